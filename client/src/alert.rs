@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::audio::Audio;
+use crate::game::Mk48Game;
+use crate::translation::Mk48Translation;
+use client_util::context::Context;
+use client_util::notification::notify;
+use core_protocol::id::{LanguageId, PlayerId};
+
+/// Important events that the player may not notice while the game is in a background tab.
+#[derive(Copy, Clone, Debug, Eq, PartialEq)]
+pub enum Alert {
+    Damaged,
+    Hostile,
+    TeamJoinRequest,
+}
+
+impl Alert {
+    const COUNT: usize = std::mem::variant_count::<Self>();
+
+    fn audio(self) -> Audio {
+        match self {
+            Self::Damaged => Audio::AlarmFast,
+            Self::Hostile => Audio::AlarmSlow,
+            Self::TeamJoinRequest => Audio::Horn,
+        }
+    }
+
+    fn message(self, t: LanguageId) -> &'static str {
+        match self {
+            Self::Damaged => t.alert_damaged(),
+            Self::Hostile => t.alert_hostile(),
+            Self::TeamJoinRequest => t.alert_team_join_request(),
+        }
+    }
+
+    /// Notifications with the same tag replace each other.
+    fn tag(self) -> &'static str {
+        match self {
+            Self::Damaged => "damaged",
+            Self::Hostile => "hostile",
+            Self::TeamJoinRequest => "team_join_request",
+        }
+    }
+}
+
+/// Alerts the player, if they opted in, of important events while the game is in a background
+/// tab, with a sound and a browser notification.
+pub struct Alerter {
+    /// When each kind of alert was last played, in seconds, indexed by `Alert as usize`.
+    last_alerted: [f32; Alert::COUNT],
+    /// Team join requests that were already alerted of.
+    joiners: Box<[PlayerId]>,
+}
+
+impl Default for Alerter {
+    fn default() -> Self {
+        Self {
+            last_alerted: [f32::NEG_INFINITY; Alert::COUNT],
+            joiners: Default::default(),
+        }
+    }
+}
+
+impl Alerter {
+    /// Each kind of alert is played at most this often, in seconds.
+    const PERIOD: f32 = 30.0;
+
+    /// Alerts the player, unless they are watching, didn't opt in, or were alerted recently.
+    pub fn alert(&mut self, alert: Alert, context: &Context<Mk48Game>) {
+        if !context.settings.background_alerts || context.visibility.is_visible() {
+            return;
+        }
+
+        let time_seconds = context.client.time_seconds;
+        let last_alerted = &mut self.last_alerted[alert as usize];
+        if time_seconds - *last_alerted < Self::PERIOD {
+            return;
+        }
+        *last_alerted = time_seconds;
+
+        context.audio.play_alert(alert.audio());
+        let t = context.common_settings.language;
+        notify("Mk48.io", alert.message(t), alert.tag());
+    }
+
+    /// Call every frame to alert the team captain of new requests to join their team.
+    pub fn update_joiners(&mut self, context: &Context<Mk48Game>) {
+        let joiners = &context.state.core.joiners;
+        if *joiners == self.joiners {
+            return;
+        }
+        if joiners.iter().any(|joiner| !self.joiners.contains(joiner)) {
+            self.alert(Alert::TeamJoinRequest, context);
+        }
+        self.joiners = joiners.clone();
+    }
+}
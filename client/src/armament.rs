@@ -9,10 +9,13 @@ use client_util::rate_limiter::RateLimiter;
 use common::angle::Angle;
 use common::contact::{Contact, ContactTrait};
 use common::entity::{Armament, EntityData, EntityId, EntityKind, EntitySubKind, EntityType};
+use common::firing_solution::{intercept, predict_path};
+use common::protocol::Salvo;
 use common_util::range::gen_radius;
-use glam::Vec2;
+use glam::{Vec2, Vec4, Vec4Swizzles};
 use rand::{thread_rng, Rng};
-use renderer2d::Particle;
+use renderer::gray_a;
+use renderer2d::{GraphicLayer, Particle};
 use std::collections::HashMap;
 
 impl Mk48Game {
@@ -30,69 +33,14 @@ impl Mk48Game {
 
         if let Some(armament_selection) = armament_selection {
             for i in 0..player_contact.data().armaments.len() {
-                let armament = &player_contact.data().armaments[i];
-
-                if armament.entity_type != armament_selection {
-                    // Wrong type; cannot fire.
-                    continue;
-                }
-
-                let armament_entity_data: &EntityData = armament.entity_type.data();
-
-                // Don't limit dredger fire rate so players with bad ping can build faster.
-                // TODO fix ping reducing fire rate for all weapons.
-                if !((player_contact.reloads()[i] && fire_rate_limiter.is_ready(i as u8))
-                    || armament_entity_data.sub_kind == EntitySubKind::Depositor)
-                {
-                    // Recently fired, shouldn't try to fire again (server will just block).
-                    continue;
-                }
-
-                let mut max_angle_diff = Angle::ZERO;
-                if let Some(turret_index) = armament.turret {
-                    if !player_contact.data().turrets[turret_index]
-                        .within_azimuth(player_contact.turrets()[turret_index])
-                    {
-                        // Out of azimuth range; cannot fire.
-                        continue;
-                    }
-                } else {
-                    max_angle_diff += Angle::from_degrees(30.0)
-                }
-
-                let transform = *player_contact.transform()
-                    + player_contact
-                        .data()
-                        .armament_transform(player_contact.turrets(), i);
-
-                let armament_direction_target = Angle::from(mouse_position - transform.position);
-
-                let mut angle_diff = (armament_direction_target - transform.direction).abs();
-                if armament.vertical
-                    || armament_entity_data.kind == EntityKind::Aircraft
-                    || armament_entity_data.sub_kind == EntitySubKind::Depositor
-                    || armament_entity_data.sub_kind == EntitySubKind::DepthCharge
-                    || armament_entity_data.sub_kind == EntitySubKind::Mine
-                {
-                    // Vertically-launched armaments can fire in any horizontal direction.
-                    // Aircraft can quickly assume any direction.
-                    // Depositors, depth charges, and mines are not constrained by direction.
-                    angle_diff = Angle::ZERO;
-                }
-
-                max_angle_diff += match armament_entity_data.sub_kind {
-                    EntitySubKind::Shell => Angle::from_degrees(30.0),
-                    EntitySubKind::Rocket => Angle::from_degrees(45.0),
-                    EntitySubKind::RocketTorpedo => Angle::from_degrees(75.0),
-                    EntitySubKind::Torpedo if armament_entity_data.sensors.sonar.range > 0.0 => {
-                        Angle::from_degrees(150.0)
-                    }
-                    _ => Angle::from_degrees(90.0),
-                };
-
-                if !angle_limit || angle_diff < max_angle_diff {
-                    let distance_squared = mouse_position.distance_squared(transform.position);
-                    let score = (angle_diff.to_degrees().powi(2) + distance_squared).sqrt();
+                if let Some(score) = Self::score_armament(
+                    fire_rate_limiter,
+                    player_contact,
+                    angle_limit,
+                    mouse_position,
+                    armament_selection,
+                    i,
+                ) {
                     // Bias towards earlier firing solutions to avoid flickering left vs. right
                     // when steering straight.
                     if best_armament.map(|(_, s)| score + 2.5 < s).unwrap_or(true) {
@@ -105,6 +53,115 @@ impl Mk48Game {
         best_armament.map(|(idx, _)| idx)
     }
 
+    /// Finds every armament of the selected type, in the selected group, that could fire at the
+    /// mouse position, in a salvo.
+    pub fn find_salvo(
+        fire_rate_limiter: &FireRateLimiter,
+        player_contact: &Contact,
+        mouse_position: Vec2,
+        armament_selection: Option<EntityType>,
+        weapon_group: WeaponGroup,
+    ) -> Option<Salvo> {
+        let armament_selection = armament_selection?;
+        let armaments = &player_contact.data().armaments;
+        let armament_indices: Vec<u8> = (0..armaments.len())
+            .filter(|&i| {
+                weapon_group.contains(&armaments[i])
+                    && Self::score_armament(
+                        fire_rate_limiter,
+                        player_contact,
+                        true,
+                        mouse_position,
+                        armament_selection,
+                        i,
+                    )
+                    .is_some()
+            })
+            .map(|i| i as u8)
+            .take(Salvo::MAX_ARMAMENTS)
+            .collect();
+
+        (!armament_indices.is_empty())
+            .then(|| Salvo::new(armament_selection.data().sub_kind, armament_indices))
+    }
+
+    /// Scores the `i`th armament (lower is better), or returns None if it cannot fire.
+    fn score_armament(
+        fire_rate_limiter: &FireRateLimiter,
+        player_contact: &Contact,
+        angle_limit: bool,
+        mouse_position: Vec2,
+        armament_selection: EntityType,
+        i: usize,
+    ) -> Option<f32> {
+        let armament = &player_contact.data().armaments[i];
+
+        if armament.entity_type != armament_selection {
+            // Wrong type; cannot fire.
+            return None;
+        }
+
+        let armament_entity_data: &EntityData = armament.entity_type.data();
+
+        // Don't limit dredger fire rate so players with bad ping can build faster.
+        // TODO fix ping reducing fire rate for all weapons.
+        if !((player_contact.reloads()[i] && fire_rate_limiter.is_ready(i as u8))
+            || armament_entity_data.sub_kind == EntitySubKind::Depositor)
+        {
+            // Recently fired, shouldn't try to fire again (server will just block).
+            return None;
+        }
+
+        let mut max_angle_diff = Angle::ZERO;
+        if let Some(turret_index) = armament.turret {
+            if !player_contact.data().turrets[turret_index]
+                .within_azimuth(player_contact.turrets()[turret_index])
+            {
+                // Out of azimuth range; cannot fire.
+                return None;
+            }
+        } else {
+            max_angle_diff += Angle::from_degrees(30.0)
+        }
+
+        let transform = *player_contact.transform()
+            + player_contact
+                .data()
+                .armament_transform(player_contact.turrets(), i);
+
+        let armament_direction_target = Angle::from(mouse_position - transform.position);
+
+        let mut angle_diff = (armament_direction_target - transform.direction).abs();
+        if armament.vertical
+            || armament_entity_data.kind == EntityKind::Aircraft
+            || armament_entity_data.sub_kind == EntitySubKind::Depositor
+            || armament_entity_data.sub_kind == EntitySubKind::DepthCharge
+            || armament_entity_data.sub_kind == EntitySubKind::Mine
+        {
+            // Vertically-launched armaments can fire in any horizontal direction.
+            // Aircraft can quickly assume any direction.
+            // Depositors, depth charges, and mines are not constrained by direction.
+            angle_diff = Angle::ZERO;
+        }
+
+        max_angle_diff += match armament_entity_data.sub_kind {
+            EntitySubKind::Shell => Angle::from_degrees(30.0),
+            EntitySubKind::Rocket => Angle::from_degrees(45.0),
+            EntitySubKind::RocketTorpedo => Angle::from_degrees(75.0),
+            EntitySubKind::Torpedo if armament_entity_data.sensors.sonar.range > 0.0 => {
+                Angle::from_degrees(150.0)
+            }
+            _ => Angle::from_degrees(90.0),
+        };
+
+        if !angle_limit || angle_diff < max_angle_diff {
+            let distance_squared = mouse_position.distance_squared(transform.position);
+            Some((angle_diff.to_degrees().powi(2) + distance_squared).sqrt())
+        } else {
+            None
+        }
+    }
+
     /// This approximates the server-based automatic anti aircraft gunfire, in the form
     /// of tracer particles and audio (return value is appropriate volume).
     pub fn simulate_anti_aircraft(
@@ -181,6 +238,94 @@ impl Mk48Game {
 
         volume
     }
+
+    /// Draws the predicted path of an armament, and, if the mouse is over a hostile contact, a
+    /// marker where to aim to intercept it (grayed out if the intercept is out of range).
+    #[allow(clippy::too_many_arguments)]
+    pub fn draw_firing_solution(
+        boat: &Contact,
+        index: usize,
+        contacts: &HashMap<EntityId, InterpolatedContact>,
+        core_state: &CoreState,
+        mouse_position: Vec2,
+        thickness: f32,
+        color: Vec4,
+        graphics: &mut GraphicLayer,
+    ) {
+        let data = boat.data();
+        let armament = &data.armaments[index];
+        let armament_data = armament.entity_type.data();
+        let launch = *boat.transform() + data.armament_transform(boat.turrets(), index);
+
+        let path = predict_path(armament_data, launch, armament.vertical, mouse_position, 32);
+        for pair in path.windows(2) {
+            let (start, end) = (pair[0], pair[1]);
+            let mut color = color;
+            if end.seeking {
+                // Homing weapons may deviate from here on.
+                color.w *= 0.5;
+            }
+            // Thicken the line towards the apex of ballistic arcs, like a shadow would.
+            graphics.draw_line(
+                start.position,
+                end.position,
+                thickness * (1.0 + 2.0 * end.arc),
+                color,
+            );
+        }
+
+        if path.is_empty() {
+            return;
+        }
+
+        // Hostile boat or aircraft under the mouse.
+        let target = contacts
+            .values()
+            .map(|InterpolatedContact { view, .. }| view)
+            .filter(|contact| {
+                contact.id() != boat.id()
+                    && matches!(
+                        contact.entity_type().map(|t| t.data().kind),
+                        Some(EntityKind::Boat | EntityKind::Aircraft)
+                    )
+                    && !core_state.are_friendly(boat.player_id(), contact.player_id())
+            })
+            .map(|contact| {
+                (
+                    contact,
+                    contact.transform().position.distance(mouse_position),
+                )
+            })
+            .filter(|(contact, distance)| *distance <= contact.data().radius.max(10.0))
+            .min_by(|(_, a), (_, b)| a.total_cmp(b))
+            .map(|(contact, _)| contact);
+
+        if let Some(target) = target {
+            if let Some(solution) = intercept(
+                armament_data,
+                &launch,
+                target.data(),
+                *target.transform(),
+                *target.guidance(),
+            ) {
+                let color = if solution.in_range {
+                    color
+                } else {
+                    gray_a(128, 100)
+                };
+                let radius = (target.data().width * 0.75).max(thickness * 4.0);
+
+                graphics.draw_line(
+                    target.transform().position,
+                    solution.position,
+                    thickness,
+                    color.xyz().extend(color.w * 0.5),
+                );
+                graphics.draw_circle(solution.position, radius, thickness * 2.0, color);
+                graphics.draw_filled_circle(solution.position, thickness * 2.0, color);
+            }
+        }
+    }
 }
 
 /// This is useful for avoiding firing the same weapon twice, which reduces fire rate in a high
@@ -226,6 +371,38 @@ impl FireRateLimiter {
     }
 }
 
+/// Which of the selected type of armament are fired in a salvo.
+#[derive(Copy, Clone, Debug, Default, Eq, PartialEq)]
+pub enum WeaponGroup {
+    /// Every armament that can bear on the target.
+    #[default]
+    All,
+    /// Armaments mounted forward of midships.
+    Forward,
+    /// Armaments mounted aft of midships.
+    Aft,
+}
+
+impl WeaponGroup {
+    /// Returns the next group, for cycling through groups.
+    pub fn next(self) -> Self {
+        match self {
+            Self::All => Self::Forward,
+            Self::Forward => Self::Aft,
+            Self::Aft => Self::All,
+        }
+    }
+
+    /// Returns true if `armament` belongs to the group.
+    pub fn contains(self, armament: &Armament) -> bool {
+        match self {
+            Self::All => true,
+            Self::Forward => armament.position_forward >= 0.0,
+            Self::Aft => armament.position_forward < 0.0,
+        }
+    }
+}
+
 pub struct Group {
     pub entity_type: EntityType,
     pub total: u8,
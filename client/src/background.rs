@@ -390,8 +390,11 @@ pub struct Mk48OverlayLayer {
     shader: Shader,
     u_above: f32,
     u_area: f32,
+    /// Animated towards the actual world radius, to avoid lurching.
     u_border: f32,
+    u_pulse: f32,
     u_restrict: f32,
+    u_target: f32,
     u_visual: f32,
 }
 
@@ -409,7 +412,9 @@ impl DefaultRender for Mk48OverlayLayer {
             u_above: 0.0,
             u_area: 0.0,
             u_border: 1000.0,
+            u_pulse: 0.0,
             u_restrict: 0.0,
+            u_target: 1000.0,
             u_visual: 0.0,
         }
     }
@@ -421,11 +426,21 @@ impl Mk48OverlayLayer {
         visual_range: f32,
         visual_restriction: f32,
         world_radius: f32,
+        world_target_radius: f32,
         area: Option<(f32, bool)>,
+        time_seconds: f32,
+        elapsed_seconds: f32,
     ) {
         self.u_visual = visual_range;
         self.u_restrict = visual_restriction;
-        self.u_border = world_radius;
+        self.u_border += (world_radius - self.u_border) * (elapsed_seconds * 4.0).min(1.0);
+        self.u_target = world_target_radius;
+        // Pulse the line where the border is shrinking to.
+        self.u_pulse = if world_target_radius < world_radius {
+            0.35 + 0.25 * (time_seconds * 4.0).sin()
+        } else {
+            0.0
+        };
         self.u_above = area
             .as_ref()
             .map(|(_, above)| if *above { 1.0 } else { -1.0 })
@@ -444,6 +459,7 @@ impl RenderLayer<&Camera2d> for Mk48OverlayLayer {
                 vec3(self.u_above, self.u_area, self.u_border),
             );
             shader.uniform("uRestrict_uVisual", vec2(self.u_restrict, self.u_visual));
+            shader.uniform("uTarget_uPulse", vec2(self.u_target, self.u_pulse));
 
             self.inner.render(renderer, (shader, camera, None));
         }
@@ -1,7 +1,8 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-use crate::armament::{group_armaments, FireRateLimiter, Group};
+use crate::alert::{Alert, Alerter};
+use crate::armament::{group_armaments, FireRateLimiter, Group, WeaponGroup};
 use crate::audio::Audio;
 use crate::background::{Mk48BackgroundLayer, Mk48OverlayLayer};
 use crate::camera::Mk48Camera;
@@ -12,6 +13,7 @@ use crate::settings::{Mk48Settings, ShadowSetting};
 use crate::sortable_sprite::SortableSprite;
 use crate::sprite::SpriteLayer;
 use crate::state::Mk48State;
+use crate::telemetry::{incoming_weapons, ReloadTracker, Telemetry};
 use crate::trail::TrailLayer;
 use crate::ui::{
     InstructionStatus, UiEvent, UiProps, UiState, UiStatus, UiStatusPlaying, UiStatusRespawning,
@@ -29,7 +31,7 @@ use common::angle::Angle;
 use common::contact::{Contact, ContactTrait};
 use common::entity::{EntityData, EntityId, EntityKind, EntitySubKind, EntityType};
 use common::guidance::Guidance;
-use common::protocol::{Command, Control, Fire, Hint, Pay, Spawn, Update, Upgrade};
+use common::protocol::{Command, Control, Fire, Hint, Hire, Pay, Spawn, Update, Upgrade};
 use common::ticks::Ticks;
 use common::transform::Transform;
 use common::velocity::Velocity;
@@ -81,6 +83,12 @@ pub struct Mk48Game {
     pub fire_rate_limiter: FireRateLimiter,
     /// FPS counter
     pub fps_counter: FpsMonitor,
+    /// Estimates reload progress for combat telemetry.
+    reload_tracker: ReloadTracker,
+    /// Alerts the player of important events while the game is in a background tab.
+    alerter: Alerter,
+    /// Waypoints of the next missile to be fired.
+    waypoints: Vec<Vec2>,
     ui_state: UiState,
 }
 
@@ -119,6 +127,12 @@ impl std::ops::Deref for Mk48Params {
 const REVERSE_ANGLE: f32 = PI * 3.0 / 8.0;
 pub const SURFACE_KEY: Key = Key::R;
 pub const ACTIVE_KEY: Key = Key::Z;
+pub const SALVO_KEY: Key = Key::F;
+pub const POINT_DEFENSE_KEY: Key = Key::V;
+pub const FLIGHT_PROFILE_KEY: Key = Key::G;
+pub const WAYPOINT_KEY: Key = Key::Q;
+pub const WEAPON_GROUP_KEY: Key = Key::B;
+pub const HOVER_KEY: Key = Key::H;
 
 impl Mk48Game {
     // Don't reverse early on, when the player doesn't have a great idea of their orientation.
@@ -206,6 +220,9 @@ impl GameClient for Mk48Game {
             peek_update_sound_counter: 0,
             fire_rate_limiter: FireRateLimiter::new(),
             fps_counter: FpsMonitor::new(1.0),
+            reload_tracker: ReloadTracker::default(),
+            alerter: Alerter::default(),
+            waypoints: Vec::new(),
             ui_state: UiState::default(),
         })
     }
@@ -219,6 +236,20 @@ impl GameClient for Mk48Game {
         let updated: HashMap<EntityId, &Contact> =
             update.contacts.iter().map(|c| (c.id(), c)).collect();
 
+        let time_seconds = context.client.time_seconds;
+        context
+            .state
+            .game
+            .jitter_buffer
+            .record_arrival(time_seconds);
+
+        if update.alerts.damaged {
+            self.alerter.alert(Alert::Damaged, context);
+        }
+        if update.alerts.hostile {
+            self.alerter.alert(Alert::Hostile, context);
+        }
+
         for (id, &contact) in updated.iter() {
             if let Some(InterpolatedContact { model, .. }) = context.state.game.contacts.get(id) {
                 if Some(*id) == context.state.game.entity_id {
@@ -240,11 +271,12 @@ impl GameClient for Mk48Game {
 
                 // Mutable borrow after immutable borrows.
                 let network_contact = context.state.game.contacts.get_mut(id).unwrap();
-                network_contact.model = contact.clone();
+                network_contact.push_snapshot(contact.clone(), time_seconds);
 
-                // Compensate for the fact that the data is a little old (second parameter is rough
-                // estimate of latency)
-                network_contact.model.simulate(0.1);
+                // Other contacts are interpolated from snapshots in tick.
+                if Some(*id) == context.state.game.entity_id {
+                    network_contact.snap_model(&context.state.game.terrain);
+                }
             } else {
                 if play_sounds {
                     self.play_new_contact_audio(
@@ -261,11 +293,10 @@ impl GameClient for Mk48Game {
                     self.first_zoom = true;
                     self.interpolated_altitude.reset();
                 }
-                context
-                    .state
-                    .game
-                    .contacts
-                    .insert(contact.id(), InterpolatedContact::new(contact.clone()));
+                context.state.game.contacts.insert(
+                    contact.id(),
+                    InterpolatedContact::new(contact.clone(), time_seconds),
+                );
             }
         }
 
@@ -412,6 +443,37 @@ impl GameClient for Mk48Game {
                     ACTIVE_KEY => {
                         self.set_active(!self.ui_state.active, &*context);
                     }
+                    POINT_DEFENSE_KEY => {
+                        if entity_type.data().has_point_defense() {
+                            self.ui_state.point_defense = self.ui_state.point_defense.next();
+                        }
+                    }
+                    FLIGHT_PROFILE_KEY => {
+                        if entity_type.data().has_missiles() {
+                            self.ui_state.flight_profile = self.ui_state.flight_profile.next();
+                        }
+                    }
+                    WAYPOINT_KEY => {
+                        if entity_type.data().has_missiles() {
+                            if self.waypoints.len() >= Fire::MAX_WAYPOINTS {
+                                // Start over.
+                                self.waypoints.clear();
+                            } else if let Some(view_position) = context.mouse.view_position {
+                                self.waypoints
+                                    .push(self.camera.to_world_position(view_position));
+                            }
+                        }
+                    }
+                    WEAPON_GROUP_KEY => {
+                        if entity_type.data().armaments.len() > 1 {
+                            self.ui_state.weapon_group = self.ui_state.weapon_group.next();
+                        }
+                    }
+                    HOVER_KEY => {
+                        if entity_type.data().has_helicopters() {
+                            self.ui_state.hover = !self.ui_state.hover;
+                        }
+                    }
                     Key::Tab => {
                         self.ui_state.armament = groups
                             .get(
@@ -457,6 +519,8 @@ impl GameClient for Mk48Game {
         // Allow more sounds to be played in peek.
         self.peek_update_sound_counter = 0;
 
+        self.alerter.update_joiners(context);
+
         // The distance from player's boat to the closest visible member of each team, for the purpose of sorting and
         // filtering.
         let mut team_proximity: HashMap<TeamId, f32> = HashMap::new();
@@ -493,6 +557,12 @@ impl GameClient for Mk48Game {
         } else {
             None
         };
+        context.state.game.jitter_buffer.update(elapsed_seconds);
+        let render_time = context
+            .state
+            .game
+            .jitter_buffer
+            .render_time(context.client.time_seconds);
         // A subset of game logic.
         for interp in &mut context.state.game.contacts.values_mut() {
             if interp
@@ -525,7 +595,12 @@ impl GameClient for Mk48Game {
 
             interp.update_error_bound(elapsed_seconds, debug_latency_entity_id);
             interp.generate_particles(layer);
-            interp.interpolate(elapsed_seconds, context.state.game.entity_id);
+            interp.interpolate(
+                elapsed_seconds,
+                context.state.game.entity_id,
+                render_time,
+                &context.state.game.terrain,
+            );
         }
 
         // May have changed due to the above.
@@ -581,7 +656,10 @@ impl GameClient for Mk48Game {
             visual_range,
             visual_restriction,
             context.state.game.world_radius,
+            context.state.game.world_target_radius,
             area,
+            context.client.time_seconds,
+            elapsed_seconds,
         );
 
         let mut anti_aircraft_volume = 0.0;
@@ -935,6 +1013,17 @@ impl GameClient for Mk48Game {
                                 {
                                     let armament = &data.armaments[i];
                                     if armament.entity_type != EntityType::Depositor {
+                                        Self::draw_firing_solution(
+                                            contact,
+                                            i,
+                                            &context.state.game.contacts,
+                                            &context.state.core,
+                                            mouse_pos,
+                                            hud_thickness,
+                                            hud_color,
+                                            &mut layer.graphics,
+                                        );
+
                                         let transform = *contact.transform();
                                         let direction = contact.transform().direction;
                                         let color = hud_color;
@@ -1296,6 +1385,57 @@ impl GameClient for Mk48Game {
             }
         }
 
+        // Rings containing players with bounties.
+        if !context.settings.cinematic {
+            for bounty in context.state.game.bounties.iter() {
+                let bounty_color = rgba(255, 75, 75, 150);
+                layer.graphics.draw_circle(
+                    bounty.position,
+                    bounty.radius,
+                    0.005 * zoom,
+                    bounty_color,
+                );
+
+                let alias = context
+                    .state
+                    .core
+                    .player_or_bot(bounty.player_id)
+                    .map(|player| player.alias.as_str().to_owned())
+                    .unwrap_or_default();
+                layer.text.draw(
+                    &format!("☠ {} ({})", alias, bounty.value),
+                    bounty.position + Vec2::new(0.0, bounty.radius + 0.035 * zoom),
+                    0.035 * zoom,
+                    [255, 75, 75, 255],
+                );
+            }
+        }
+
+        // Route of the next missile to be fired.
+        if !self.waypoints.is_empty() {
+            if let Some(player_contact) = context.state.game.player_contact() {
+                let route_color = rgba(255, 255, 255, 100);
+                let thickness = 0.0025 * zoom;
+                let aim = context
+                    .mouse
+                    .view_position
+                    .map(|view_position| self.camera.to_world_position(view_position));
+
+                let mut previous = player_contact.transform().position;
+                for &waypoint in self.waypoints.iter().chain(aim.iter()) {
+                    layer
+                        .graphics
+                        .draw_line(previous, waypoint, thickness, route_color);
+                    previous = waypoint;
+                }
+                for &waypoint in self.waypoints.iter() {
+                    layer
+                        .graphics
+                        .draw_circle(waypoint, 0.01 * zoom, thickness, route_color);
+                }
+            }
+        }
+
         // Play anti-aircraft sfx.
         if anti_aircraft_volume > 0.0 && !context.audio.is_playing(Audio::Aa) {
             context
@@ -1372,6 +1512,11 @@ impl GameClient for Mk48Game {
         // Send command later, when lifetimes allow.
         let mut control: Option<Command> = None;
 
+        self.reload_tracker.update(
+            context.state.game.player_contact(),
+            context.client.time_seconds,
+        );
+
         let player_contact = Self::maybe_contact_mut(
             &mut context.state.game.contacts,
             context.state.game.entity_id,
@@ -1484,6 +1629,11 @@ impl GameClient for Mk48Game {
                 altitude: player_contact.altitude(),
                 submerge: self.ui_state.submerge,
                 active: self.ui_state.active,
+                point_defense: self.ui_state.point_defense,
+                flight_profile: self.ui_state.flight_profile,
+                waypoints: self.waypoints.len(),
+                weapon_group: self.ui_state.weapon_group,
+                hover: self.ui_state.hover,
                 instruction_status: if player_contact.data().level <= 3 {
                     InstructionStatus {
                         touch: context.mouse.touch_screen,
@@ -1496,6 +1646,23 @@ impl GameClient for Mk48Game {
                 armament: self.ui_state.armament,
                 armament_consumption: player_contact.reloads().iter().map(|b| *b).collect(),
                 team_proximity,
+                telemetry: if context.settings.telemetry_shown {
+                    let incoming = incoming_weapons(
+                        player_contact,
+                        context.state.game.contacts.values().map(|c| &c.view),
+                        |player_id| context.state.core.is_friendly(player_id),
+                    );
+                    Telemetry::new(
+                        player_contact,
+                        self.ui_state.active,
+                        &self.reload_tracker,
+                        context.client.time_seconds,
+                        incoming,
+                    )
+                } else {
+                    None
+                },
+                border_warning: context.state.game.border_warning,
             });
 
             if self.control_rate_limiter.update_ready(elapsed_seconds) {
@@ -1511,6 +1678,8 @@ impl GameClient for Mk48Game {
                     submerge: self.ui_state.submerge,
                     aim_target,
                     active: self.ui_state.active,
+                    point_defense: self.ui_state.point_defense,
+                    hover: self.ui_state.hover,
                     pay: context.keyboard.is_down(Key::C).then_some(Pay),
                     fire: if left_click
                         || context
@@ -1529,9 +1698,33 @@ impl GameClient for Mk48Game {
                         .map(|i| {
                             self.fire_rate_limiter.fired(i as u8);
 
-                            Fire {
-                                armament_index: i as u8,
+                            let armament = &player_contact.data().armaments[i];
+                            if armament.entity_type.data().sub_kind == EntitySubKind::Missile {
+                                Fire {
+                                    armament_index: i as u8,
+                                    profile: self.ui_state.flight_profile,
+                                    waypoints: std::mem::take(&mut self.waypoints),
+                                }
+                            } else {
+                                Fire::new(i as u8)
+                            }
+                        })
+                    } else {
+                        None
+                    },
+                    salvo: if context.keyboard.is_down(SALVO_KEY) {
+                        Self::find_salvo(
+                            &self.fire_rate_limiter,
+                            player_contact,
+                            aim_target.unwrap_or_default(),
+                            self.ui_state.armament,
+                            self.ui_state.weapon_group,
+                        )
+                        .map(|salvo| {
+                            for &i in &salvo.armament_indices {
+                                self.fire_rate_limiter.fired(i);
                             }
+                            salvo
                         })
                     } else {
                         None
@@ -1541,7 +1734,7 @@ impl GameClient for Mk48Game {
 
                 // Some things are not idempotent.
                 fn is_significant(control: &Control) -> bool {
-                    control.fire.is_some() || control.pay.is_some()
+                    control.fire.is_some() || control.salvo.is_some() || control.pay.is_some()
                 }
 
                 if Some(&current_control) != self.last_control.as_ref()
@@ -1594,9 +1787,21 @@ impl GameClient for Mk48Game {
             UiEvent::Armament(armament) => {
                 self.ui_state.armament = armament;
             }
+            UiEvent::PointDefense(point_defense) => {
+                self.ui_state.point_defense = point_defense;
+            }
+            UiEvent::FlightProfile(flight_profile) => {
+                self.ui_state.flight_profile = flight_profile;
+            }
             UiEvent::GraphicsSettingsChanged => {
                 self.render_chain = Self::create_render_chain(context).unwrap();
             }
+            UiEvent::Hire => {
+                context.send_to_game(Command::Hire(Hire));
+            }
+            UiEvent::Hover(hover) => {
+                self.ui_state.hover = hover;
+            }
             UiEvent::OverrideRespawn => {
                 self.respawn_overridden = true;
             }
@@ -1614,6 +1819,9 @@ impl GameClient for Mk48Game {
                 context.audio.play(Audio::Upgrade);
                 context.send_to_game(Command::Upgrade(Upgrade { entity_type }));
             }
+            UiEvent::WeaponGroup(weapon_group) => {
+                self.ui_state.weapon_group = weapon_group;
+            }
         }
     }
 }
@@ -4,19 +4,21 @@
 use crate::animation::Animation;
 use crate::audio::Audio;
 use crate::game::{Mk48Game, Mk48Layer};
+use crate::jitter_buffer::JitterBuffer;
 use crate::particle::Mk48Particle;
 use client_util::audio::AudioPlayer;
 use client_util::context::Context;
 use common::contact::{Contact, ContactTrait};
 use common::entity::EntityId;
 use common::entity::{EntityData, EntityKind, EntitySubKind};
+use common::terrain::Terrain;
 use common::ticks::Ticks;
 use common_util::angle::Angle;
 use common_util::range::map_ranges;
 use glam::Vec2;
 use js_hooks::console_log;
 use rand::{thread_rng, Rng};
-use std::collections::HashMap;
+use std::collections::{HashMap, VecDeque};
 
 /// A contact that may be locally controlled by simulated elsewhere (by the server).
 pub struct InterpolatedContact {
@@ -30,20 +32,77 @@ pub struct InterpolatedContact {
     /// Idle ticks, i.e. how many updates since last seen. If exceeds entity_type.data().keep_alive(),
     /// assume entity went away.
     pub idle: Ticks,
+    /// Recent server updates and their arrival times, oldest first. Model is interpolated between
+    /// them (see [`JitterBuffer`]).
+    snapshots: VecDeque<(f32, Contact)>,
 }
 
 impl InterpolatedContact {
+    /// Rough estimate of latency, to compensate for the fact that the data is a little old.
+    const LATENCY_COMPENSATION: f32 = 0.1;
+
     /// Initializes an interpolated contact.
-    pub(crate) fn new(contact: Contact) -> Self {
+    pub(crate) fn new(contact: Contact, time_seconds: f32) -> Self {
+        let mut snapshots = VecDeque::with_capacity(JitterBuffer::SNAPSHOTS + 1);
+        snapshots.push_back((time_seconds, contact.clone()));
+
         // When a new contact appears, its model and view are identical.
         Self {
             model: contact.clone(),
             view: contact,
             error: 0.0,
             idle: Ticks::ZERO,
+            snapshots,
+        }
+    }
+
+    /// Buffers a server update that arrived at a particular time.
+    pub fn push_snapshot(&mut self, contact: Contact, time_seconds: f32) {
+        self.snapshots.push_back((time_seconds, contact));
+        while self.snapshots.len() > JitterBuffer::SNAPSHOTS {
+            self.snapshots.pop_front();
+        }
+    }
+
+    /// Snaps model to the newest snapshot. Used for the player's own boat, which shouldn't be
+    /// delayed by the jitter buffer lest controls feel sluggish.
+    pub fn snap_model(&mut self, terrain: &Terrain) {
+        if let Some((_, newest)) = self.snapshots.back() {
+            self.model = newest.clone();
+            self.model.simulate(Self::LATENCY_COMPENSATION, terrain);
         }
     }
 
+    /// Computes model by interpolating between the snapshots on either side of `render_time`, or
+    /// extrapolating (within limits) from the newest snapshot if the buffer has run dry.
+    fn buffered_model(&self, render_time: f32, terrain: &Terrain) -> Option<Contact> {
+        let model = match self.snapshots.iter().position(|&(t, _)| t > render_time) {
+            // Render time precedes all snapshots, e.g. contact just appeared.
+            Some(0) => self.snapshots[0].1.clone(),
+            Some(next) => {
+                let (t0, c0) = &self.snapshots[next - 1];
+                let (t1, c1) = &self.snapshots[next];
+                let mut model = c0.clone();
+                let fraction = (render_time - t0) / (t1 - t0).max(0.001);
+                model.interpolate_towards(c1, true, fraction, 0.0);
+                model
+            }
+            None => {
+                let (t, newest) = self.snapshots.back()?;
+                let mut model = newest.clone();
+                // Entities sent less often may be extrapolated for longer.
+                let limit = JitterBuffer::MAX_EXTRAPOLATION
+                    + model
+                        .entity_type()
+                        .map(|e| e.data().kind.keep_alive().start().to_secs())
+                        .unwrap_or(0.0);
+                model.simulate((render_time - t).min(limit), terrain);
+                model
+            }
+        };
+        Some(model)
+    }
+
     /// Updates measure of discrepancy between model and view, known as "error."
     pub fn update_error_bound(
         &mut self,
@@ -171,17 +230,33 @@ impl InterpolatedContact {
         }
     }
 
-    /// Performs interpolation. Takes the entity id of the player's boat.
-    pub fn interpolate(&mut self, elapsed_seconds: f32, player_entity_id: Option<EntityId>) {
+    /// Performs interpolation. Takes the entity id of the player's boat, the (delayed) time at
+    /// which other contacts should be rendered, and the terrain (which affects some movement).
+    pub fn interpolate(
+        &mut self,
+        elapsed_seconds: f32,
+        player_entity_id: Option<EntityId>,
+        render_time: f32,
+        terrain: &Terrain,
+    ) {
+        let is_player = Some(self.model.id()) == player_entity_id;
+        if !is_player {
+            if let Some(model) = self.buffered_model(render_time, terrain) {
+                self.model = model;
+            }
+        }
+
         // Don't interpolate view's guidance if this is the player's boat, so that it doesn't jerk around.
         self.view.interpolate_towards(
             &self.model,
-            Some(self.model.id()) != player_entity_id,
+            !is_player,
             elapsed_seconds * self.error,
             elapsed_seconds,
         );
-        self.model.simulate(elapsed_seconds);
-        self.view.simulate(elapsed_seconds);
+        if is_player {
+            self.model.simulate(elapsed_seconds, terrain);
+        }
+        self.view.simulate(elapsed_seconds, terrain);
     }
 }
 
@@ -329,3 +404,65 @@ impl Mk48Game {
         }
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use crate::interpolated_contact::InterpolatedContact;
+    use crate::jitter_buffer::JitterBuffer;
+    use common::altitude::Altitude;
+    use common::contact::{Contact, ContactTrait};
+    use common::guidance::Guidance;
+    use common::terrain::Terrain;
+    use common::ticks::Ticks;
+    use common::transform::Transform;
+    use common::velocity::Velocity;
+    use common_util::angle::Angle;
+    use glam::Vec2;
+    use std::num::NonZeroU32;
+
+    /// A contact heading east at 10 meters per second, at `x` meters from the origin.
+    fn contact(x: f32) -> Contact {
+        Contact::new(
+            Altitude::ZERO,
+            Ticks::ZERO,
+            None,
+            Guidance::new(),
+            NonZeroU32::new(1).unwrap(),
+            None,
+            None,
+            Transform {
+                position: Vec2::new(x, 0.0),
+                direction: Angle::ZERO,
+                velocity: Velocity::from_mps(10.0),
+            },
+            None,
+        )
+    }
+
+    #[test]
+    fn buffered_model() {
+        let terrain = Terrain::new();
+        let mut interpolated = InterpolatedContact::new(contact(0.0), 0.0);
+        interpolated.push_snapshot(contact(1.0), 0.1);
+
+        let x = |render_time: f32| {
+            interpolated
+                .buffered_model(render_time, &terrain)
+                .unwrap()
+                .transform()
+                .position
+                .x
+        };
+
+        // Before the oldest snapshot.
+        assert_eq!(x(-1.0), 0.0);
+        // Between snapshots, without any extrapolation.
+        assert!((x(0.05) - 0.5).abs() < 0.01, "{}", x(0.05));
+        assert!((x(0.1) - 1.0).abs() < 0.01, "{}", x(0.1));
+        // Buffer ran dry.
+        assert!((x(0.2) - 2.0).abs() < 0.01, "{}", x(0.2));
+        // Within limits.
+        let limit = 1.0 + 10.0 * JitterBuffer::MAX_EXTRAPOLATION;
+        assert!((x(10.0) - limit).abs() < 0.01, "{}", x(10.0));
+    }
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+/// Measures the jitter of update inter-arrival times, and derives an interpolation delay that is
+/// just long enough for contacts to (usually) have a snapshot on either side of the render time.
+pub struct JitterBuffer {
+    /// Time the last update arrived, if any.
+    last_arrival: Option<f32>,
+    /// Moving average of time between updates.
+    interval: f32,
+    /// Moving average of deviation of time between updates from `interval`.
+    jitter: f32,
+    /// Current delay, which gradually approaches the target delay.
+    delay: f32,
+}
+
+impl Default for JitterBuffer {
+    fn default() -> Self {
+        Self {
+            last_arrival: None,
+            interval: Self::NOMINAL_INTERVAL,
+            jitter: 0.0,
+            delay: Self::NOMINAL_INTERVAL,
+        }
+    }
+}
+
+impl JitterBuffer {
+    /// Server tick period.
+    const NOMINAL_INTERVAL: f32 = 0.1;
+    const MIN_DELAY: f32 = 0.05;
+    const MAX_DELAY: f32 = 0.5;
+    /// How many standard-ish deviations of jitter to absorb.
+    const JITTER_FACTOR: f32 = 2.5;
+    /// Longer gaps are assumed to be the tab being hidden, not the network.
+    const MAX_INTERVAL: f32 = 1.0;
+
+    /// How many snapshots each contact keeps.
+    pub const SNAPSHOTS: usize = 4;
+    /// How far past its newest snapshot a contact may be extrapolated.
+    pub const MAX_EXTRAPOLATION: f32 = 0.25;
+
+    /// Call when an update arrives.
+    pub fn record_arrival(&mut self, time_seconds: f32) {
+        if let Some(last_arrival) = self.last_arrival.replace(time_seconds) {
+            let interval = time_seconds - last_arrival;
+            if !(0.0..Self::MAX_INTERVAL).contains(&interval) {
+                return;
+            }
+            self.interval += (interval - self.interval) * 0.1;
+            self.jitter += ((interval - self.interval).abs() - self.jitter) * 0.1;
+        }
+    }
+
+    /// Call every frame to adapt the delay. Widens quickly, to stop stutter, but shrinks slowly,
+    /// to avoid oscillating.
+    pub fn update(&mut self, elapsed_seconds: f32) {
+        let target = self.target_delay();
+        let rate = if target > self.delay { 0.5 } else { 0.05 };
+        self.delay += (target - self.delay).clamp(-rate * elapsed_seconds, rate * elapsed_seconds);
+    }
+
+    fn target_delay(&self) -> f32 {
+        (self.interval + self.jitter * Self::JITTER_FACTOR).clamp(Self::MIN_DELAY, Self::MAX_DELAY)
+    }
+
+    /// The time, in the past, at which buffered contacts should be rendered.
+    pub fn render_time(&self, time_seconds: f32) -> f32 {
+        time_seconds - self.delay
+    }
+
+    /// Current interpolation delay in seconds.
+    pub fn delay(&self) -> f32 {
+        self.delay
+    }
+
+    /// Current measure of jitter in seconds.
+    pub fn jitter(&self) -> f32 {
+        self.jitter
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::jitter_buffer::JitterBuffer;
+
+    fn simulate(intervals: impl Iterator<Item = f32>) -> JitterBuffer {
+        let mut buffer = JitterBuffer::default();
+        let mut time = 0.0;
+        for interval in intervals {
+            time += interval;
+            buffer.record_arrival(time);
+            buffer.update(interval);
+        }
+        buffer
+    }
+
+    #[test]
+    fn steady() {
+        let buffer = simulate(std::iter::repeat(0.1).take(1000));
+        assert!(buffer.jitter() < 0.001, "{}", buffer.jitter());
+        assert!((buffer.delay() - 0.1).abs() < 0.01, "{}", buffer.delay());
+    }
+
+    #[test]
+    fn jittery() {
+        let buffer = simulate([0.02, 0.18].into_iter().cycle().take(1000));
+        assert!(buffer.jitter() > 0.05, "{}", buffer.jitter());
+        assert!(buffer.delay() > 0.25, "{}", buffer.delay());
+        assert!(buffer.delay() <= JitterBuffer::MAX_DELAY);
+    }
+
+    #[test]
+    fn hidden_tab() {
+        let buffer = simulate(
+            std::iter::repeat(0.1)
+                .take(500)
+                .chain(std::iter::once(30.0))
+                .chain(std::iter::repeat(0.1).take(10)),
+        );
+        assert!(buffer.jitter() < 0.001, "{}", buffer.jitter());
+    }
+}
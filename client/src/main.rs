@@ -10,10 +10,12 @@
 #![feature(option_result_contains)]
 #![feature(mixed_integer_ops)]
 #![feature(iter_intersperse)]
+#![feature(variant_count)]
 
 use crate::game::Mk48Game;
 use crate::ui::{Mk48Route, Mk48Ui};
 
+mod alert;
 mod animation;
 mod armament;
 mod audio;
@@ -22,12 +24,14 @@ mod camera;
 mod game;
 mod interpolated;
 mod interpolated_contact;
+mod jitter_buffer;
 mod licenses;
 mod particle;
 mod settings;
 mod sortable_sprite;
 mod sprite;
 mod state;
+mod telemetry;
 mod tessellation;
 mod trail;
 mod translation;
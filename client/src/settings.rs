@@ -8,14 +8,18 @@ use std::str::FromStr;
 
 /// Settings can be set via Javascript (see util/settings.js and page/Settings.svelte).
 #[derive(Clone, Default, PartialEq, Settings)]
+#[setting(sync)]
 pub struct Mk48Settings {
     pub animations: bool,
+    /// Alert the player of attacks, etc. while the game is in a background tab.
+    pub background_alerts: bool,
     #[setting(no_store)]
     pub cinematic: bool,
     pub circle_hud: bool,
     pub dynamic_waves: bool,
     pub fps_shown: bool,
     pub shadows: ShadowSetting,
+    pub telemetry_shown: bool,
 }
 
 #[derive(Copy, Clone, Debug, PartialEq)]
@@ -3,13 +3,15 @@
 
 use crate::animation::Animation;
 use crate::interpolated_contact::InterpolatedContact;
+use crate::jitter_buffer::JitterBuffer;
 use client_util::apply::Apply;
 use common::contact::Contact;
 use common::death_reason::DeathReason;
 use common::entity::EntityId;
-use common::protocol::Update;
+use common::protocol::{Bounty, Update};
 use common::terrain::Terrain;
 use std::collections::HashMap;
+use std::sync::Arc;
 
 /// State associated with game server connection. Reset when connection is reset.
 pub struct Mk48State {
@@ -17,9 +19,17 @@ pub struct Mk48State {
     pub contacts: HashMap<EntityId, InterpolatedContact>,
     pub death_reason: Option<DeathReason>,
     pub entity_id: Option<EntityId>,
+    /// Adapts the interpolation delay of contacts to network conditions.
+    pub jitter_buffer: JitterBuffer,
     pub score: u32,
     pub terrain: Terrain,
     pub world_radius: f32,
+    /// Radius the world border is growing or shrinking towards.
+    pub world_target_radius: f32,
+    /// Seconds until the shrinking world border reaches the player's boat, if soon.
+    pub border_warning: Option<f32>,
+    /// Approximate positions of players with bounties.
+    pub bounties: Arc<[Bounty]>,
     terrain_reset: bool,
 }
 
@@ -30,10 +40,14 @@ impl Default for Mk48State {
             contacts: HashMap::new(),
             death_reason: None,
             entity_id: None,
+            jitter_buffer: JitterBuffer::default(),
             score: 0,
             terrain: Terrain::default(),
             // Keep border off splash screen by assuming radius.
             world_radius: 10000.0,
+            world_target_radius: 10000.0,
+            border_warning: None,
+            bounties: Vec::new().into(),
             terrain_reset: false,
         }
     }
@@ -71,6 +85,9 @@ impl Apply<Update> for Mk48State {
         self.terrain.apply_update(&update.terrain);
 
         self.world_radius = update.world_radius;
+        self.world_target_radius = update.world_target_radius;
+        self.border_warning = update.border_warning;
+        self.bounties = update.bounties;
         self.score = update.score;
     }
 
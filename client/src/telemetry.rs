@@ -0,0 +1,273 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use common::contact::{Contact, ContactTrait};
+use common::entity::{EntityData, EntityKind, EntityType};
+use common::velocity::Velocity;
+use common_util::range::map_ranges;
+use core_protocol::id::PlayerId;
+use std::ops::Range;
+
+/// Combat telemetry of the player's boat, computed client-side.
+#[derive(Clone, PartialEq)]
+pub struct Telemetry {
+    pub speed: Velocity,
+    /// Above this speed, the boat is much louder to passive sonar.
+    pub cavitation_speed: Velocity,
+    /// Effective sensor ranges, which are zero if the sensor is unusable at the current altitude.
+    pub visual: f32,
+    pub radar: f32,
+    pub sonar: f32,
+    /// Whether radar and sonar are active, as opposed to passive.
+    pub active: bool,
+    /// Estimated distance within which a stationary enemy would detect the boat by passive sonar.
+    pub sonar_detection: f32,
+    /// Per armament, reload progress from 0 to 1, or `None` if unknown (limited armaments only
+    /// reload once their weapon is gone).
+    pub reloads: Box<[Option<f32>]>,
+    /// Estimated seconds until all (unlimited) armaments are reloaded.
+    pub reload_all: f32,
+    /// Number of hostile weapons on a collision course.
+    pub incoming: u8,
+    /// Seconds until the soonest incoming weapon arrives.
+    pub impact: Option<f32>,
+}
+
+impl Telemetry {
+    pub fn new(
+        player: &Contact,
+        active: bool,
+        reload_tracker: &ReloadTracker,
+        time_seconds: f32,
+        incoming: (u8, Option<f32>),
+    ) -> Option<Self> {
+        let entity_type = player.entity_type()?;
+        let data: &'static EntityData = entity_type.data();
+        let altitude = player.altitude();
+        let speed = player.transform().velocity;
+
+        // Same as the server, radar and visual don't work well under water, and sonar doesn't work
+        // in the air.
+        let visual_radar_efficacy = map_ranges(altitude.to_norm(), -0.35..0.0, 0.0..1.0, true);
+        let sonar = if altitude.is_airborne() {
+            0.0
+        } else {
+            data.sensors.sonar.range
+        };
+
+        // Assume the enemy has the best passive sonar of any boat of the same level.
+        let listener_range = EntityType::iter()
+            .map(EntityType::data)
+            .filter(|d| d.kind == EntityKind::Boat && d.level == data.level)
+            .map(|d| d.sensors.sonar.range)
+            .fold(0.0, f32::max);
+        let sonar_detection = if altitude.is_airborne() {
+            0.0
+        } else {
+            data.passive_sonar_range(
+                data.sonar_noise(speed, altitude, active),
+                listener_range,
+                0.0,
+            )
+        };
+
+        let (reloads, reload_all) = reload_tracker.progress(entity_type, time_seconds);
+
+        Some(Self {
+            speed,
+            cavitation_speed: data.cavitation_speed(altitude),
+            visual: data.sensors.visual.range * visual_radar_efficacy,
+            radar: data.sensors.radar.range * visual_radar_efficacy,
+            sonar,
+            active,
+            sonar_detection,
+            reloads,
+            reload_all,
+            incoming: incoming.0,
+            impact: incoming.1,
+        })
+    }
+
+    /// Whether the boat is making extra noise by cavitating.
+    pub fn cavitating(&self) -> bool {
+        self.speed.abs() > self.cavitation_speed
+    }
+}
+
+/// Counts hostile weapons whose heading intersects the player's boat, and the soonest time to
+/// impact.
+pub fn incoming_weapons<'a>(
+    player: &Contact,
+    contacts: impl Iterator<Item = &'a Contact>,
+    is_friendly: impl Fn(Option<PlayerId>) -> bool,
+) -> (u8, Option<f32>) {
+    let player_data = match player.entity_type() {
+        Some(entity_type) => entity_type.data(),
+        None => return (0, None),
+    };
+    let player_position = player.transform().position;
+
+    let mut incoming = 0u8;
+    let mut impact: Option<f32> = None;
+    for contact in contacts {
+        let data = match contact.entity_type() {
+            Some(entity_type) if entity_type.data().kind == EntityKind::Weapon => {
+                entity_type.data()
+            }
+            _ => continue,
+        };
+        if is_friendly(contact.player_id()) {
+            continue;
+        }
+
+        let speed = contact.transform().velocity.to_mps();
+        if speed <= 1.0 {
+            // Mines and the like aren't headed anywhere.
+            continue;
+        }
+
+        let heading = contact.transform().direction.to_vec();
+        let offset = player_position - contact.transform().position;
+        let along_track = offset.dot(heading);
+        if along_track <= 0.0 || along_track > data.range {
+            continue;
+        }
+        let cross_track = (offset - heading * along_track).length();
+        if cross_track > player_data.length * 0.5 + data.width {
+            continue;
+        }
+
+        incoming = incoming.saturating_add(1);
+        let seconds = along_track / speed;
+        impact = Some(impact.map_or(seconds, |i| i.min(seconds)));
+    }
+    (incoming, impact)
+}
+
+/// Estimates the reload progress of the player's armaments. The server only sends whether each
+/// armament is ready, so track when each was consumed, and mirror how the server reloads ranges
+/// of similar armaments one at a time.
+#[derive(Default)]
+pub struct ReloadTracker {
+    entity_type: Option<EntityType>,
+    /// Whether each armament was ready as of the last update.
+    ready: Vec<bool>,
+    /// When each armament was last consumed.
+    consumed_at: Vec<f32>,
+    /// Per range of similar armaments, when the current reload started.
+    reloading_since: Vec<f32>,
+}
+
+impl ReloadTracker {
+    /// Call every frame with the player's boat (if any).
+    pub fn update(&mut self, player: Option<&Contact>, time_seconds: f32) {
+        let player = match player.filter(|p| p.reloads_known()) {
+            Some(player) => player,
+            None => {
+                *self = Self::default();
+                return;
+            }
+        };
+
+        let entity_type = player.entity_type();
+        if entity_type != self.entity_type {
+            // Spawned or upgraded.
+            *self = Self {
+                entity_type,
+                ..Self::default()
+            };
+        }
+        let data = match entity_type {
+            Some(entity_type) => entity_type.data(),
+            None => return,
+        };
+
+        let reloads = player.reloads();
+        let count = data.armaments.len().min(reloads.len());
+        self.ready.resize(count, true);
+        self.consumed_at.resize(count, time_seconds);
+
+        for (range_index, range) in similar_ranges(data).enumerate() {
+            if self.reloading_since.len() <= range_index {
+                self.reloading_since.push(time_seconds);
+            }
+            let was_idle = range.clone().all(|i| i >= count || self.ready[i]);
+
+            for i in range.start..range.end.min(count) {
+                let ready = reloads[i];
+                if self.ready[i] && !ready {
+                    self.consumed_at[i] = time_seconds;
+                    if was_idle {
+                        self.reloading_since[range_index] = time_seconds;
+                    }
+                } else if !self.ready[i] && ready {
+                    // The next armament in the range starts reloading.
+                    self.reloading_since[range_index] = time_seconds;
+                }
+                self.ready[i] = ready;
+            }
+        }
+    }
+
+    /// Returns reload progress of each armament and the estimated seconds until all are ready.
+    fn progress(&self, entity_type: EntityType, time_seconds: f32) -> (Box<[Option<f32>]>, f32) {
+        let data = entity_type.data();
+        let mut progress = vec![Some(1.0); data.armaments.len()];
+        let mut reload_all = 0f32;
+
+        if self.entity_type != Some(entity_type) {
+            return (progress.into(), reload_all);
+        }
+
+        for (range_index, range) in similar_ranges(data).enumerate() {
+            let armament_data = data.armaments[range.start].entity_type.data();
+            let mut queue: Vec<usize> = range
+                .filter(|&i| i < self.ready.len() && !self.ready[i])
+                .collect();
+
+            if armament_data.limited {
+                for i in queue {
+                    progress[i] = None;
+                }
+                continue;
+            }
+
+            // Consumed first, reloaded first.
+            queue.sort_by(|&a, &b| self.consumed_at[a].total_cmp(&self.consumed_at[b]));
+
+            let reload = armament_data.reload.to_secs();
+            let since = self
+                .reloading_since
+                .get(range_index)
+                .copied()
+                .unwrap_or(time_seconds);
+            let head = ((time_seconds - since) / reload).clamp(0.0, 0.99);
+            for (n, &i) in queue.iter().enumerate() {
+                progress[i] = Some(if n == 0 { head } else { 0.0 });
+            }
+            if !queue.is_empty() {
+                reload_all = reload_all.max((queue.len() as f32 - head) * reload);
+            }
+        }
+
+        (progress.into(), reload_all)
+    }
+}
+
+/// Ranges of consecutive similar armaments, which the server reloads one at a time.
+fn similar_ranges(data: &EntityData) -> impl Iterator<Item = Range<usize>> + '_ {
+    let armaments = &data.armaments;
+    let mut start = 0;
+    std::iter::from_fn(move || {
+        if start >= armaments.len() {
+            return None;
+        }
+        let end = armaments[start..]
+            .iter()
+            .position(|a| !a.is_similar_to(&armaments[start]))
+            .map_or(armaments.len(), |n| start + n);
+        let range = start..end;
+        start = end;
+        Some(range)
+    })
+}
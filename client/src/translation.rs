@@ -1,9 +1,14 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-use crate::game::{ACTIVE_KEY, SURFACE_KEY};
+use crate::armament::WeaponGroup;
+use crate::game::{
+    ACTIVE_KEY, FLIGHT_PROFILE_KEY, HOVER_KEY, POINT_DEFENSE_KEY, SALVO_KEY, SURFACE_KEY,
+    WAYPOINT_KEY, WEAPON_GROUP_KEY,
+};
 use common::death_reason::DeathReason;
 use common::entity::{EntityKind, EntitySubKind, EntityType};
+use common::protocol::{FlightProfile, PointDefense};
 use core_protocol::id::LanguageId;
 use core_protocol::id::LanguageId::*;
 use core_protocol::name::PlayerAlias;
@@ -12,6 +17,13 @@ use yew_frontend::frontend::RewardedAd;
 use yew_frontend::s;
 
 pub trait Mk48Translation: Sized {
+    s!(alert_damaged);
+    s!(alert_hostile);
+    s!(alert_team_join_request);
+    s!(background_alerts_label);
+
+    fn border_warning(self, seconds: u32) -> String;
+
     fn death_reason(self, death_reason: &DeathReason) -> String;
     fn death_reason_boat(self, alias: PlayerAlias) -> String {
         self.death_reason_collision(&alias)
@@ -143,11 +155,33 @@ pub trait Mk48Translation: Sized {
     s!(instruction_zoom_mouse);
     s!(instruction_zoom_touch);
 
+    fn point_defense_label(self, point_defense: PointDefense) -> &'static str;
+    fn point_defense_hint(self) -> String;
+
+    fn flight_profile_label(self, flight_profile: FlightProfile) -> &'static str;
+    fn flight_profile_hint(self) -> String;
+    fn weapon_group_label(self, weapon_group: WeaponGroup) -> &'static str;
+    fn weapon_group_hint(self) -> String;
+    s!(hover_label);
+    fn hover_hint(self) -> String;
+
+    s!(escort_hire_label);
+    s!(escort_hire_hint);
+
     s!(sensor_active_label);
     fn sensor_active_hint(self, sensors: &str) -> String;
     s!(sensor_radar_label);
     s!(sensor_sonar_label);
 
+    s!(telemetry_active_label);
+    fn telemetry_impact(self, seconds: f32) -> String;
+    fn telemetry_incoming(self, count: u8) -> String;
+    s!(telemetry_passive_label);
+    fn telemetry_reload_all(self, seconds: f32) -> String;
+    fn telemetry_sonar_detection(self, meters: f32) -> String;
+    fn telemetry_speed(self, knots: f32, cavitation_knots: f32) -> String;
+    s!(telemetry_visual_label);
+
     s!(ship_surface_label);
     fn ship_surface_hint(self) -> String;
 
@@ -205,6 +239,91 @@ impl Mk48Translation for LanguageId {
     }
     */
 
+    fn alert_damaged(self) -> &'static str {
+        match self {
+            Arabic => "سفينتك تتعرض للهجوم!",
+            Bork => "Your bork is under borktack!",
+            English => "Your ship is under attack!",
+            French => "Votre navire est attaqué !",
+            German => "Dein Schiff wird angegriffen!",
+            Hindi => "आपके जहाज़ पर हमला हो रहा है!",
+            Italian => "La tua nave è sotto attacco!",
+            Japanese => "あなたの船が攻撃を受けています！",
+            Russian => "Ваш корабль атакован!",
+            SimplifiedChinese => "你的船正在遭受攻击！",
+            Spanish => "¡Tu barco está siendo atacado!",
+            Vietnamese => "Tàu của bạn đang bị tấn công!",
+        }
+    }
+
+    fn alert_hostile(self) -> &'static str {
+        match self {
+            Arabic => "سفينة معادية تقترب.",
+            Bork => "A borkstile bork is borking closer.",
+            English => "A hostile ship is approaching.",
+            French => "Un navire hostile approche.",
+            German => "Ein feindliches Schiff nähert sich.",
+            Hindi => "एक शत्रु जहाज़ पास आ रहा है।",
+            Italian => "Una nave ostile si sta avvicinando.",
+            Japanese => "敵艦が接近しています。",
+            Russian => "Приближается вражеский корабль.",
+            SimplifiedChinese => "一艘敌舰正在接近。",
+            Spanish => "Un barco hostil se acerca.",
+            Vietnamese => "Một tàu địch đang tiến đến.",
+        }
+    }
+
+    fn alert_team_join_request(self) -> &'static str {
+        match self {
+            Arabic => "لاعب يريد الانضمام إلى أسطولك.",
+            Bork => "A borker wants to bork your fleet.",
+            English => "A player wants to join your fleet.",
+            French => "Un joueur veut rejoindre votre flotte.",
+            German => "Ein Spieler möchte deiner Flotte beitreten.",
+            Hindi => "एक खिलाड़ी आपके बेड़े में शामिल होना चाहता है।",
+            Italian => "Un giocatore vuole unirsi alla tua flotta.",
+            Japanese => "プレイヤーがあなたの艦隊への参加を希望しています。",
+            Russian => "Игрок хочет вступить в ваш флот.",
+            SimplifiedChinese => "有玩家想加入你的舰队。",
+            Spanish => "Un jugador quiere unirse a tu flota.",
+            Vietnamese => "Một người chơi muốn gia nhập hạm đội của bạn.",
+        }
+    }
+
+    fn background_alerts_label(self) -> &'static str {
+        match self {
+            Arabic => "تنبيهات في الخلفية",
+            Bork => "Borkground Alerts",
+            English => "Background Alerts",
+            French => "Alertes en arrière-plan",
+            German => "Hintergrund-Warnungen",
+            Hindi => "पृष्ठभूमि अलर्ट",
+            Italian => "Avvisi in background",
+            Japanese => "バックグラウンド通知",
+            Russian => "Фоновые оповещения",
+            SimplifiedChinese => "后台提醒",
+            Spanish => "Alertas en segundo plano",
+            Vietnamese => "Cảnh báo nền",
+        }
+    }
+
+    fn border_warning(self, seconds: u32) -> String {
+        match self {
+            Arabic => format!("الحدود تتقلص! ستصل إليك خلال {seconds} ثانية"),
+            Bork => format!("Borkder is borking! Bork in {seconds}s"),
+            English => format!("Border shrinking! Reaches you in {seconds}s"),
+            French => format!("La frontière se rétrécit ! Elle vous atteint dans {seconds}s"),
+            German => format!("Die Grenze schrumpft! Sie erreicht dich in {seconds}s"),
+            Hindi => format!("सीमा सिकुड़ रही है! {seconds} सेकंड में आप तक पहुंचेगी"),
+            Italian => format!("Il confine si restringe! Ti raggiunge tra {seconds}s"),
+            Japanese => format!("国境が縮小中! {seconds}秒で到達"),
+            Russian => format!("Граница сужается! Достигнет вас через {seconds} с"),
+            SimplifiedChinese => format!("边境正在缩小！{seconds}秒后到达"),
+            Spanish => format!("¡La frontera se reduce! Te alcanza en {seconds}s"),
+            Vietnamese => format!("Biên giới đang thu hẹp! Đến chỗ bạn sau {seconds}giây"),
+        }
+    }
+
     fn death_reason(self, death_reason: &DeathReason) -> String {
         match death_reason {
             &DeathReason::Boat(alias) => self.death_reason_boat(alias),
@@ -1136,6 +1255,272 @@ impl Mk48Translation for LanguageId {
         }
     }
 
+    fn point_defense_label(self, point_defense: PointDefense) -> &'static str {
+        match point_defense {
+            PointDefense::Off => match self {
+                Arabic => "الدفاع النقطي: متوقف",
+                Bork => "Bork defense: off",
+                English => "Point defense: off",
+                French => "Défense rapprochée : arrêt",
+                German => "Nahbereichsabwehr: aus",
+                Hindi => "बिंदु रक्षा: बंद",
+                Italian => "Difesa ravvicinata: spenta",
+                Japanese => "近接防御: オフ",
+                Russian => "ПВО ближнего рубежа: выкл.",
+                SimplifiedChinese => "近防系统：关闭",
+                Spanish => "Defensa de punto: apagada",
+                Vietnamese => "Phòng thủ tầm gần: tắt",
+            },
+            PointDefense::SelfDefense => match self {
+                Arabic => "الدفاع النقطي: ذاتي",
+                Bork => "Bork defense: self",
+                English => "Point defense: self",
+                French => "Défense rapprochée : navire",
+                German => "Nahbereichsabwehr: eigenes Schiff",
+                Hindi => "बिंदु रक्षा: स्वयं",
+                Italian => "Difesa ravvicinata: nave",
+                Japanese => "近接防御: 自艦",
+                Russian => "ПВО ближнего рубежа: свой корабль",
+                SimplifiedChinese => "近防系统：自卫",
+                Spanish => "Defensa de punto: propia",
+                Vietnamese => "Phòng thủ tầm gần: bản thân",
+            },
+            PointDefense::AreaDefense => match self {
+                Arabic => "الدفاع النقطي: الفريق",
+                Bork => "Bork defense: pack",
+                English => "Point defense: area",
+                French => "Défense rapprochée : zone",
+                German => "Nahbereichsabwehr: Gebiet",
+                Hindi => "बिंदु रक्षा: क्षेत्र",
+                Italian => "Difesa ravvicinata: area",
+                Japanese => "近接防御: 艦隊",
+                Russian => "ПВО ближнего рубежа: зона",
+                SimplifiedChinese => "近防系统：区域",
+                Spanish => "Defensa de punto: área",
+                Vietnamese => "Phòng thủ tầm gần: khu vực",
+            },
+        }
+    }
+
+    fn point_defense_hint(self) -> String {
+        let key = POINT_DEFENSE_KEY;
+        match self {
+            Arabic => format!("({key}) يسقط الدفاع النقطي تلقائيًا الصواريخ والطائرات القادمة، لحماية سفينتك أو فريقك"),
+            Bork => format!("({key}) Bork defense automatically borks incoming missiles and aircraft, protecting bork's ship or pack"),
+            English => format!("({key}) Point defense automatically shoots down incoming missiles and aircraft, protecting your ship or your team"),
+            French => format!("({key}) La défense rapprochée abat automatiquement les missiles et avions ennemis, pour protéger votre navire ou votre équipe"),
+            German => format!("({key}) Die Nahbereichsabwehr schießt automatisch anfliegende Raketen und Flugzeuge ab und schützt dein Schiff oder dein Team"),
+            Hindi => format!("({key}) बिंदु रक्षा आने वाली मिसाइलों और विमानों को स्वचालित रूप से मार गिराती है, आपके जहाज या आपकी टीम की रक्षा करती है"),
+            Italian => format!("({key}) La difesa ravvicinata abbatte automaticamente missili e aerei in arrivo, proteggendo la tua nave o la tua squadra"),
+            Japanese => format!("({key}) 近接防御は飛来するミサイルや航空機を自動的に撃墜し、自艦または味方を守ります"),
+            Russian => format!("({key}) ПВО ближнего рубежа автоматически сбивает приближающиеся ракеты и самолёты, защищая ваш корабль или команду"),
+            SimplifiedChinese => format!("({key}) 近防系统会自动击落来袭的导弹和飞机，保护你的舰船或你的队伍"),
+            Spanish => format!("({key}) La defensa de punto derriba automáticamente misiles y aeronaves enemigos, protegiendo tu barco o tu equipo"),
+            Vietnamese => format!("({key}) Phòng thủ tầm gần tự động bắn hạ tên lửa và máy bay đang lao tới, bảo vệ tàu hoặc đội của bạn"),
+        }
+    }
+
+    fn flight_profile_label(self, flight_profile: FlightProfile) -> &'static str {
+        match flight_profile {
+            FlightProfile::Direct => match self {
+                Arabic => "مسار الصاروخ: مباشر",
+                Bork => "Bork flight: straight",
+                English => "Missile profile: direct",
+                French => "Profil de missile : direct",
+                German => "Flugprofil: direkt",
+                Hindi => "मिसाइल प्रोफ़ाइल: सीधा",
+                Italian => "Profilo missile: diretto",
+                Japanese => "ミサイル飛行: 直進",
+                Russian => "Профиль ракеты: прямой",
+                SimplifiedChinese => "导弹弹道：直飞",
+                Spanish => "Perfil de misil: directo",
+                Vietnamese => "Quỹ đạo tên lửa: trực tiếp",
+            },
+            FlightProfile::SeaSkimming => match self {
+                Arabic => "مسار الصاروخ: فوق سطح البحر",
+                Bork => "Bork flight: splashy",
+                English => "Missile profile: sea-skimming",
+                French => "Profil de missile : rase-mottes",
+                German => "Flugprofil: Tiefflug",
+                Hindi => "मिसाइल प्रोफ़ाइल: समुद्र-सतह",
+                Italian => "Profilo missile: radente",
+                Japanese => "ミサイル飛行: シースキミング",
+                Russian => "Профиль ракеты: над морем",
+                SimplifiedChinese => "导弹弹道：掠海",
+                Spanish => "Perfil de misil: rasante",
+                Vietnamese => "Quỹ đạo tên lửa: bay sát mặt biển",
+            },
+            FlightProfile::HighAltitude => match self {
+                Arabic => "مسار الصاروخ: ارتفاع عالٍ",
+                Bork => "Bork flight: zoomies",
+                English => "Missile profile: high altitude",
+                French => "Profil de missile : haute altitude",
+                German => "Flugprofil: große Höhe",
+                Hindi => "मिसाइल प्रोफ़ाइल: ऊँचाई",
+                Italian => "Profilo missile: alta quota",
+                Japanese => "ミサイル飛行: 高高度",
+                Russian => "Профиль ракеты: высотный",
+                SimplifiedChinese => "导弹弹道：高空",
+                Spanish => "Perfil de misil: gran altitud",
+                Vietnamese => "Quỹ đạo tên lửa: độ cao lớn",
+            },
+        }
+    }
+
+    fn flight_profile_hint(self) -> String {
+        let key = FLIGHT_PROFILE_KEY;
+        let waypoint_key = WAYPOINT_KEY;
+        match self {
+            Arabic => format!("({key}) تطير الصواريخ فوق سطح البحر لتفادي الرادار، أو عاليًا لمدى أطول. ({waypoint_key}) يضيف نقطة طريق عند المؤشر"),
+            Bork => format!("({key}) Bork missiles fly splashy to dodge radar, or zoomies for more range. ({waypoint_key}) adds a sniff point at the cursor"),
+            English => format!("({key}) Missiles can skim the sea to evade radar, or fly high for more range. ({waypoint_key}) adds a waypoint at the cursor"),
+            French => format!("({key}) Les missiles peuvent raser la mer pour échapper aux radars, ou voler haut pour plus de portée. ({waypoint_key}) ajoute un point de passage au curseur"),
+            German => format!("({key}) Raketen können im Tiefflug dem Radar entgehen oder hoch fliegen, um weiter zu kommen. ({waypoint_key}) setzt einen Wegpunkt am Mauszeiger"),
+            Hindi => format!("({key}) मिसाइलें रडार से बचने के लिए समुद्र के ठीक ऊपर, या अधिक दूरी के लिए ऊँची उड़ सकती हैं। ({waypoint_key}) कर्सर पर वेपॉइंट जोड़ता है"),
+            Italian => format!("({key}) I missili possono volare radenti al mare per eludere i radar, o in alta quota per una gittata maggiore. ({waypoint_key}) aggiunge un punto di passaggio sul cursore"),
+            Japanese => format!("({key}) ミサイルは海面すれすれを飛んでレーダーを回避したり、高高度を飛んで射程を延ばしたりできます。({waypoint_key}) でカーソル位置に経由地点を追加"),
+            Russian => format!("({key}) Ракеты могут лететь над самой водой, уклоняясь от радаров, или на большой высоте для увеличения дальности. ({waypoint_key}) добавляет путевую точку у курсора"),
+            SimplifiedChinese => format!("({key}) 导弹可以掠海飞行以躲避雷达，或高空飞行以增加射程。({waypoint_key}) 在光标处添加航路点"),
+            Spanish => format!("({key}) Los misiles pueden volar rasantes para evadir el radar, o a gran altitud para más alcance. ({waypoint_key}) añade un punto de ruta en el cursor"),
+            Vietnamese => format!("({key}) Tên lửa có thể bay sát mặt biển để tránh radar, hoặc bay cao để tăng tầm bắn. ({waypoint_key}) thêm điểm dẫn đường tại con trỏ"),
+        }
+    }
+
+    fn weapon_group_label(self, weapon_group: WeaponGroup) -> &'static str {
+        match weapon_group {
+            WeaponGroup::All => match self {
+                Arabic => "الرشقة: كل الأسلحة",
+                Bork => "Bork volley: all",
+                English => "Salvo: all weapons",
+                French => "Salve : toutes les armes",
+                German => "Salve: alle Waffen",
+                Hindi => "सैल्वो: सभी हथियार",
+                Italian => "Salva: tutte le armi",
+                Japanese => "斉射: 全武装",
+                Russian => "Залп: всё оружие",
+                SimplifiedChinese => "齐射：全部武器",
+                Spanish => "Salva: todas las armas",
+                Vietnamese => "Loạt bắn: tất cả vũ khí",
+            },
+            WeaponGroup::Forward => match self {
+                Arabic => "الرشقة: الأسلحة الأمامية",
+                Bork => "Bork volley: snout",
+                English => "Salvo: forward weapons",
+                French => "Salve : armes avant",
+                German => "Salve: vordere Waffen",
+                Hindi => "सैल्वो: आगे के हथियार",
+                Italian => "Salva: armi prodiere",
+                Japanese => "斉射: 前部武装",
+                Russian => "Залп: носовое оружие",
+                SimplifiedChinese => "齐射：前部武器",
+                Spanish => "Salva: armas de proa",
+                Vietnamese => "Loạt bắn: vũ khí mũi tàu",
+            },
+            WeaponGroup::Aft => match self {
+                Arabic => "الرشقة: الأسلحة الخلفية",
+                Bork => "Bork volley: tail",
+                English => "Salvo: aft weapons",
+                French => "Salve : armes arrière",
+                German => "Salve: hintere Waffen",
+                Hindi => "सैल्वो: पीछे के हथियार",
+                Italian => "Salva: armi poppiere",
+                Japanese => "斉射: 後部武装",
+                Russian => "Залп: кормовое оружие",
+                SimplifiedChinese => "齐射：后部武器",
+                Spanish => "Salva: armas de popa",
+                Vietnamese => "Loạt bắn: vũ khí đuôi tàu",
+            },
+        }
+    }
+
+    fn weapon_group_hint(self) -> String {
+        let key = WEAPON_GROUP_KEY;
+        let salvo_key = SALVO_KEY;
+        match self {
+            Arabic => format!("({key}) يختار الأسلحة التي تُطلق معًا. ({salvo_key}) يطلق كل الأسلحة الجاهزة من النوع المحدد في المجموعة"),
+            Bork => format!("({key}) Picks which bork weapons fire together. ({salvo_key}) fires every ready weapon of the selected type in the pack"),
+            English => format!("({key}) Chooses which weapons fire together. ({salvo_key}) fires every ready weapon of the selected type in the group"),
+            French => format!("({key}) Choisit les armes qui tirent ensemble. ({salvo_key}) tire toutes les armes prêtes du type sélectionné dans le groupe"),
+            German => format!("({key}) Wählt, welche Waffen gemeinsam feuern. ({salvo_key}) feuert alle bereiten Waffen des gewählten Typs in der Gruppe ab"),
+            Hindi => format!("({key}) चुनता है कि कौन से हथियार एक साथ चलेंगे। ({salvo_key}) समूह में चुने गए प्रकार के सभी तैयार हथियार चलाता है"),
+            Italian => format!("({key}) Sceglie quali armi sparano insieme. ({salvo_key}) spara tutte le armi pronte del tipo selezionato nel gruppo"),
+            Japanese => format!("({key}) 同時に発射する武装を選択します。({salvo_key}) でグループ内の選択中の種類の発射可能な武装をすべて発射"),
+            Russian => format!("({key}) Выбирает, какое оружие стреляет вместе. ({salvo_key}) стреляет из всего готового оружия выбранного типа в группе"),
+            SimplifiedChinese => format!("({key}) 选择一起开火的武器。({salvo_key}) 发射组内所选类型的所有就绪武器"),
+            Spanish => format!("({key}) Elige qué armas disparan juntas. ({salvo_key}) dispara todas las armas listas del tipo seleccionado en el grupo"),
+            Vietnamese => format!("({key}) Chọn những vũ khí cùng khai hỏa. ({salvo_key}) bắn tất cả vũ khí sẵn sàng thuộc loại đã chọn trong nhóm"),
+        }
+    }
+
+    fn hover_label(self) -> &'static str {
+        match self {
+            Arabic => "تحويم",
+            Bork => "Bork hover",
+            English => "Hover",
+            French => "Vol stationnaire",
+            German => "Schweben",
+            Hindi => "मंडराना",
+            Italian => "Volo stazionario",
+            Japanese => "ホバリング",
+            Russian => "Зависание",
+            SimplifiedChinese => "悬停",
+            Spanish => "Vuelo estacionario",
+            Vietnamese => "Bay lơ lửng",
+        }
+    }
+
+    fn hover_hint(self) -> String {
+        let key = HOVER_KEY;
+        match self {
+            Arabic => format!("({key}) تتوقف المروحيات وتحوم في مكانها، وتُنزل السونار المتدلي للبحث عن الغواصات"),
+            Bork => format!("({key}) Whirly borks stop and hover, dangling their sniffer to find sneaky subs"),
+            English => format!("({key}) Helicopters stop and hover in place, lowering their dipping sonar to find submarines"),
+            French => format!("({key}) Les hélicoptères s'arrêtent en vol stationnaire et plongent leur sonar pour trouver les sous-marins"),
+            German => format!("({key}) Hubschrauber halten an und schweben auf der Stelle, um mit ihrem Tauchsonar U-Boote aufzuspüren"),
+            Hindi => format!("({key}) हेलीकॉप्टर रुककर एक जगह मंडराते हैं और पनडुब्बियों को खोजने के लिए अपना डिपिंग सोनार नीचे करते हैं"),
+            Italian => format!("({key}) Gli elicotteri si fermano in volo stazionario e calano il sonar a immersione per trovare i sottomarini"),
+            Japanese => format!("({key}) ヘリコプターがその場でホバリングし、吊下式ソナーを降ろして潜水艦を探します"),
+            Russian => format!("({key}) Вертолёты зависают на месте и опускают гидроакустическую станцию для поиска подводных лодок"),
+            SimplifiedChinese => format!("({key}) 直升机停下并原地悬停，放下吊放声呐搜索潜艇"),
+            Spanish => format!("({key}) Los helicópteros se detienen en vuelo estacionario y bajan su sonar de inmersión para buscar submarinos"),
+            Vietnamese => format!("({key}) Trực thăng dừng lại và bay lơ lửng tại chỗ, thả sonar nhúng để tìm tàu ngầm"),
+        }
+    }
+
+    fn escort_hire_label(self) -> &'static str {
+        match self {
+            Arabic => "استأجر مرافقًا",
+            Bork => "Hire bork buddy",
+            English => "Hire escort",
+            French => "Engager une escorte",
+            German => "Geleitschiff anheuern",
+            Hindi => "अनुरक्षक किराए पर लें",
+            Italian => "Ingaggia una scorta",
+            Japanese => "護衛を雇う",
+            Russian => "Нанять эскорт",
+            SimplifiedChinese => "雇佣护航舰",
+            Spanish => "Contratar escolta",
+            Vietnamese => "Thuê tàu hộ tống",
+        }
+    }
+
+    fn escort_hire_hint(self) -> &'static str {
+        match self {
+            Arabic => "أنفق نقاطًا لاستئجار سفينة صغيرة تتبعك في تشكيل وتهاجم هدفك وتدافع عنك، حتى تغرق أو تغادر",
+            Bork => "Spend points on a smol boat that follows bork, borks at bork's target, and defends bork, until bork sinks or leaves",
+            English => "Spend score to hire a small ship that follows you in formation, engages your target, and defends you, until you sink or leave",
+            French => "Dépensez des points pour engager un petit navire qui vous suit en formation, attaque votre cible et vous défend, jusqu'à ce que vous couliez ou partiez",
+            German => "Gib Punkte aus, um ein kleines Schiff anzuheuern, das dir in Formation folgt, dein Ziel angreift und dich verteidigt, bis du sinkst oder gehst",
+            Hindi => "अंक खर्च करके एक छोटा जहाज़ किराए पर लें जो संरचना में आपके पीछे चलता है, आपके लक्ष्य पर हमला करता है और आपकी रक्षा करता है, जब तक आप डूब नहीं जाते या चले नहीं जाते",
+            Italian => "Spendi punti per ingaggiare una piccola nave che ti segue in formazione, attacca il tuo bersaglio e ti difende, finché non affondi o esci",
+            Japanese => "スコアを使って小型艦を雇います。撃沈されるか退出するまで、編隊を組んで追従し、目標を攻撃し、あなたを守ります",
+            Russian => "Потратьте очки, чтобы нанять небольшой корабль, который следует за вами в строю, атакует вашу цель и защищает вас, пока вы не утонете или не выйдете",
+            SimplifiedChinese => "花费分数雇佣一艘小型舰船，它会以编队跟随你、攻击你的目标并保护你，直到你被击沉或离开",
+            Spanish => "Gasta puntos para contratar un barco pequeño que te sigue en formación, ataca a tu objetivo y te defiende, hasta que te hundas o salgas",
+            Vietnamese => "Dùng điểm để thuê một tàu nhỏ đi theo bạn trong đội hình, tấn công mục tiêu của bạn và bảo vệ bạn, cho đến khi bạn bị chìm hoặc rời đi",
+        }
+    }
+
     fn sensor_active_label(self) -> &'static str {
         match self {
             Arabic => "أجهزة استشعار نشطة",
@@ -1205,6 +1590,142 @@ impl Mk48Translation for LanguageId {
         }
     }
 
+    fn telemetry_active_label(self) -> &'static str {
+        match self {
+            Arabic => "نشط",
+            Bork => "brrr",
+            English => "active",
+            French => "actif",
+            German => "aktiv",
+            Hindi => "सक्रिय",
+            Italian => "attivo",
+            Japanese => "アクティブ",
+            Russian => "активный",
+            SimplifiedChinese => "主动",
+            Spanish => "activo",
+            Vietnamese => "chủ động",
+        }
+    }
+
+    fn telemetry_impact(self, seconds: f32) -> String {
+        match self {
+            Arabic => format!("، الاصطدام خلال {seconds:.1} ث"),
+            Bork => format!(", bonk in {seconds:.1}s"),
+            English => format!(", impact in {seconds:.1}s"),
+            French => format!(", impact dans {seconds:.1}s"),
+            German => format!(", Einschlag in {seconds:.1}s"),
+            Hindi => format!(", {seconds:.1} सेकंड में प्रहार"),
+            Italian => format!(", impatto tra {seconds:.1}s"),
+            Japanese => format!("、{seconds:.1}秒後に着弾"),
+            Russian => format!(", попадание через {seconds:.1} с"),
+            SimplifiedChinese => format!("，{seconds:.1}秒后命中"),
+            Spanish => format!(", impacto en {seconds:.1}s"),
+            Vietnamese => format!(", va chạm sau {seconds:.1}giây"),
+        }
+    }
+
+    fn telemetry_incoming(self, count: u8) -> String {
+        match self {
+            Arabic => format!("{count} قادم"),
+            Bork => format!("{count} bonks incoming"),
+            English => format!("{count} incoming"),
+            French => format!("{count} en approche"),
+            German => format!("{count} im Anflug"),
+            Hindi => format!("{count} आ रहे हैं"),
+            Italian => format!("{count} in arrivo"),
+            Japanese => format!("{count}発接近中"),
+            Russian => format!("{count} на подлёте"),
+            SimplifiedChinese => format!("{count}枚来袭"),
+            Spanish => format!("{count} entrantes"),
+            Vietnamese => format!("{count} đang lao tới"),
+        }
+    }
+
+    fn telemetry_passive_label(self) -> &'static str {
+        match self {
+            Arabic => "سلبي",
+            Bork => "shhh",
+            English => "passive",
+            French => "passif",
+            German => "passiv",
+            Hindi => "निष्क्रिय",
+            Italian => "passivo",
+            Japanese => "パッシブ",
+            Russian => "пассивный",
+            SimplifiedChinese => "被动",
+            Spanish => "pasivo",
+            Vietnamese => "thụ động",
+        }
+    }
+
+    fn telemetry_reload_all(self, seconds: f32) -> String {
+        match self {
+            Arabic => format!("إعادة تعبئة كاملة خلال {seconds:.0} ث"),
+            Bork => format!("All borks ready in {seconds:.0}s"),
+            English => format!("Fully reloaded in {seconds:.0}s"),
+            French => format!("Entièrement rechargé dans {seconds:.0}s"),
+            German => format!("Vollständig nachgeladen in {seconds:.0}s"),
+            Hindi => format!("{seconds:.0} सेकंड में पूरी तरह रीलोड"),
+            Italian => format!("Completamente ricaricato tra {seconds:.0}s"),
+            Japanese => format!("{seconds:.0}秒で全装填"),
+            Russian => format!("Полная перезарядка через {seconds:.0} с"),
+            SimplifiedChinese => format!("{seconds:.0}秒后全部装填"),
+            Spanish => format!("Recarga completa en {seconds:.0}s"),
+            Vietnamese => format!("Nạp đầy sau {seconds:.0}giây"),
+        }
+    }
+
+    fn telemetry_sonar_detection(self, meters: f32) -> String {
+        match self {
+            Arabic => format!("الكشف بالسونار السلبي ~{meters:.0} م"),
+            Bork => format!("Sniffable from ~{meters:.0}m"),
+            English => format!("Passive sonar detection ~{meters:.0}m"),
+            French => format!("Détection par sonar passif ~{meters:.0}m"),
+            German => format!("Passive Sonarerfassung ~{meters:.0}m"),
+            Hindi => format!("निष्क्रिय सोनार पहचान ~{meters:.0}मी"),
+            Italian => format!("Rilevamento sonar passivo ~{meters:.0}m"),
+            Japanese => format!("パッシブソナー探知 ~{meters:.0}m"),
+            Russian => format!("Обнаружение пассивным сонаром ~{meters:.0} м"),
+            SimplifiedChinese => format!("被动声纳探测距离 ~{meters:.0}米"),
+            Spanish => format!("Detección por sonar pasivo ~{meters:.0}m"),
+            Vietnamese => format!("Bị sonar thụ động phát hiện ~{meters:.0}m"),
+        }
+    }
+
+    fn telemetry_speed(self, knots: f32, cavitation_knots: f32) -> String {
+        match self {
+            Arabic => format!("{knots:.1} عقدة / {cavitation_knots:.1} عقدة تكهف"),
+            Bork => format!("{knots:.1}kn / {cavitation_knots:.1}kn bubbly"),
+            English => format!("{knots:.1}kn / {cavitation_knots:.1}kn cavitation"),
+            French => format!("{knots:.1}nd / {cavitation_knots:.1}nd cavitation"),
+            German => format!("{knots:.1}kn / {cavitation_knots:.1}kn Kavitation"),
+            Hindi => format!("{knots:.1}नॉट / {cavitation_knots:.1}नॉट कैविटेशन"),
+            Italian => format!("{knots:.1}nd / {cavitation_knots:.1}nd cavitazione"),
+            Japanese => format!("{knots:.1}ノット / キャビテーション{cavitation_knots:.1}ノット"),
+            Russian => format!("{knots:.1} уз / {cavitation_knots:.1} уз кавитация"),
+            SimplifiedChinese => format!("{knots:.1}节 / 空化{cavitation_knots:.1}节"),
+            Spanish => format!("{knots:.1}nd / {cavitation_knots:.1}nd cavitación"),
+            Vietnamese => format!("{knots:.1}hl / {cavitation_knots:.1}hl tạo bọt"),
+        }
+    }
+
+    fn telemetry_visual_label(self) -> &'static str {
+        match self {
+            Arabic => "بصري",
+            Bork => "Eyes",
+            English => "Visual",
+            French => "Visuel",
+            German => "Sicht",
+            Hindi => "दृश्य",
+            Italian => "Visivo",
+            Japanese => "目視",
+            Russian => "Визуально",
+            SimplifiedChinese => "目视",
+            Spanish => "Visual",
+            Vietnamese => "Quan sát",
+        }
+    }
+
     fn ship_surface_label(self) -> &'static str {
         match self {
             Arabic => "سطح",
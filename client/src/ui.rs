@@ -1,7 +1,9 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::armament::WeaponGroup;
 use crate::game::Mk48Game;
+use crate::telemetry::Telemetry;
 use crate::translation::Mk48Translation;
 use crate::ui::about_dialog::AboutDialog;
 use crate::ui::changelog_dialog::ChangelogDialog;
@@ -21,6 +23,7 @@ use common::altitude::Altitude;
 use common::angle::Angle;
 use common::death_reason::DeathReason;
 use common::entity::EntityType;
+use common::protocol::{FlightProfile, PointDefense};
 use common::velocity::Velocity;
 use core_protocol::id::{LanguageId, TeamId};
 use core_protocol::name::PlayerAlias;
@@ -145,6 +148,7 @@ pub fn mk48_ui(props: &PropertiesWrapper<UiProps>) -> Html {
                             status={playing.clone()}
                             score={props.score}
                             fps={gctw.settings_cache.fps_shown.then_some(props.fps)}
+                            network={gctw.settings_cache.fps_shown.then_some(props.network)}
                         />
                     </Positioner>
                     <UpgradeOverlay
@@ -156,6 +160,7 @@ pub fn mk48_ui(props: &PropertiesWrapper<UiProps>) -> Html {
                         position={Position::BottomLeft{margin}}
                         style="max-width:25%;"
                         status={playing.clone()}
+                        score={props.score}
                     />
                     <Positioner id="sidebar" position={Position::CenterRight{margin}} flex={Flex::Column}>
                         <InvitationIcon/>
@@ -257,6 +262,13 @@ pub struct UiState {
     pub active: bool,
     pub submerge: bool,
     pub armament: Option<EntityType>,
+    pub point_defense: PointDefense,
+    /// Flight profile of missiles to be fired.
+    pub flight_profile: FlightProfile,
+    /// Which weapons are fired in a salvo.
+    pub weapon_group: WeaponGroup,
+    /// Helicopters hover in place.
+    pub hover: bool,
 }
 
 impl Default for UiState {
@@ -265,6 +277,10 @@ impl Default for UiState {
             active: true,
             submerge: false,
             armament: None,
+            point_defense: PointDefense::default(),
+            flight_profile: FlightProfile::default(),
+            weapon_group: WeaponGroup::default(),
+            hover: false,
         }
     }
 }
@@ -273,7 +289,13 @@ pub enum UiEvent {
     /// Sensors active.
     Active(bool),
     Armament(Option<EntityType>),
+    FlightProfile(FlightProfile),
     GraphicsSettingsChanged,
+    /// Hire an escort.
+    Hire,
+    /// Helicopters hover in place.
+    Hover(bool),
+    PointDefense(PointDefense),
     /// Go from respawning to spawning.
     #[allow(unused)]
     OverrideRespawn,
@@ -284,11 +306,14 @@ pub enum UiEvent {
     },
     Submerge(bool),
     Upgrade(EntityType),
+    WeaponGroup(WeaponGroup),
 }
 
 #[derive(PartialEq, Clone, Default)]
 pub struct UiProps {
     pub fps: f32,
+    /// Interpolation delay and jitter, in seconds.
+    pub network: (f32, f32),
     pub score: u32,
     pub status: UiStatus,
 }
@@ -312,10 +337,20 @@ pub struct UiStatusPlaying {
     pub submerge: bool,
     /// Active sensors.
     pub active: bool,
+    pub point_defense: PointDefense,
+    pub flight_profile: FlightProfile,
+    /// Number of waypoints of the next missile to be fired.
+    pub waypoints: usize,
+    pub weapon_group: WeaponGroup,
+    pub hover: bool,
     pub instruction_status: InstructionStatus,
     pub armament: Option<EntityType>,
     pub armament_consumption: Box<[bool]>,
     pub team_proximity: HashMap<TeamId, f32>,
+    /// Present if enabled in settings.
+    pub telemetry: Option<Telemetry>,
+    /// Seconds until the shrinking world border reaches the boat, if soon.
+    pub border_warning: Option<f32>,
 }
 
 #[derive(PartialEq, Clone)]
@@ -327,6 +362,10 @@ impl Mk48Game {
     pub(crate) fn update_ui_props(&self, context: &mut Context<Self>, status: UiStatus) {
         let props = UiProps {
             fps: self.fps_counter.last_sample().unwrap_or(0.0),
+            network: (
+                context.state.game.jitter_buffer.delay(),
+                context.state.game.jitter_buffer.jitter(),
+            ),
             score: context.state.game.score,
             status,
         };
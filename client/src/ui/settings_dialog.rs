@@ -2,9 +2,11 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::settings::{Mk48Settings, ShadowSetting};
+use crate::translation::Mk48Translation;
 use crate::ui::UiEvent;
 use crate::Mk48Game;
 use client_util::browser_storage::BrowserStorages;
+use client_util::notification::request_notification_permission;
 use client_util::setting::CommonSettings;
 use core_protocol::dto::ServerDto;
 use core_protocol::id::ServerId;
@@ -71,6 +73,28 @@ pub fn settings_dialog() -> Html {
         )
     });
 
+    let background_alerts = gctw.settings_cache.background_alerts;
+    let on_toggle_background_alerts = gctw.change_settings_callback.reform(move |_| {
+        if !background_alerts {
+            // Must be in response to input.
+            request_notification_permission();
+        }
+        Box::new(
+            move |settings: &mut Mk48Settings, browser_storages: &mut BrowserStorages| {
+                settings.set_background_alerts(!background_alerts, browser_storages);
+            },
+        )
+    });
+
+    let telemetry_shown = gctw.settings_cache.telemetry_shown;
+    let on_toggle_telemetry = gctw.change_settings_callback.reform(move |_| {
+        Box::new(
+            move |settings: &mut Mk48Settings, browser_storages: &mut BrowserStorages| {
+                settings.set_telemetry_shown(!telemetry_shown, browser_storages);
+            },
+        )
+    });
+
     let animations = gctw.settings_cache.animations;
     let on_toggle_animations = {
         let graphics_callback = graphics_callback.clone();
@@ -184,11 +208,21 @@ pub fn settings_dialog() -> Html {
                 {"High Contrast"}
             </label>
 
+            <label class={label_style.clone()}>
+                <input type="checkbox" checked={background_alerts} oninput={on_toggle_background_alerts}/>
+                {t.background_alerts_label()}
+            </label>
+
             <label class={label_style.clone()}>
                 <input type="checkbox" checked={fps_shown} oninput={on_toggle_fps}/>
                 {"FPS Counter"}
             </label>
 
+            <label class={label_style.clone()}>
+                <input type="checkbox" checked={telemetry_shown} oninput={on_toggle_telemetry}/>
+                {"Combat Telemetry"}
+            </label>
+
             <label class={label_style.clone()}>
                 <input type="checkbox" checked={chat_dialog_shown} oninput={on_toggle_chat}/>
                 {"Radio"}
@@ -1,13 +1,15 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-use crate::armament::{group_armaments, Group};
+use crate::armament::{group_armaments, Group, WeaponGroup};
 use crate::translation::Mk48Translation;
 use crate::ui::sprite::Sprite;
 use crate::ui::{UiEvent, UiStatusPlaying};
 use crate::Mk48Game;
 use common::altitude::Altitude;
 use common::entity::{EntityData, EntitySubKind, EntityType};
+use common::protocol::{FlightProfile, PointDefense};
+use common::util::level_to_score;
 use core_protocol::id::LanguageId;
 use stylist::yew::styled_component;
 use stylist::{css, StyleSource};
@@ -24,6 +26,7 @@ pub struct ShipControlsProps {
     #[prop_or(None)]
     pub style: Option<AttrValue>,
     pub status: UiStatusPlaying,
+    pub score: u32,
 }
 
 #[styled_component(ShipControls)]
@@ -55,7 +58,7 @@ pub fn ship_controls(props: &ShipControlsProps) -> Html {
 
     let consumption_style = css!(
         r#"
-        float: right;
+        float: inline-end;
 		color: white;
     "#
     );
@@ -97,6 +100,11 @@ pub fn ship_controls(props: &ShipControlsProps) -> Html {
             }
             {surface_button(t, props.status.entity_type, props.status.submerge, &button_style, &button_selected_style, &ui_event_callback)}
             {active_sensor_button(t, props.status.entity_type, props.status.active, props.status.altitude, &button_style, &button_selected_style, &ui_event_callback)}
+            {point_defense_button(t, props.status.entity_type, props.status.point_defense, &button_style, &button_selected_style, &ui_event_callback)}
+            {flight_profile_button(t, props.status.entity_type, props.status.flight_profile, props.status.waypoints, &button_style, &button_selected_style, &ui_event_callback)}
+            {weapon_group_button(t, props.status.entity_type, props.status.weapon_group, &button_style, &button_selected_style, &ui_event_callback)}
+            {hover_button(t, props.status.entity_type, props.status.hover, &button_style, &button_selected_style, &ui_event_callback)}
+            {hire_button(t, props.status.entity_type, props.score, &button_style, &consumed_style, &ui_event_callback)}
         </Section>
     }
 }
@@ -155,3 +163,122 @@ fn active_sensor_button(
         }
     }
 }
+
+fn point_defense_button(
+    t: LanguageId,
+    entity_type: EntityType,
+    point_defense: PointDefense,
+    button_style: &StyleSource,
+    button_selected_style: &StyleSource,
+    ui_event_callback: &Callback<UiEvent>,
+) -> Html {
+    if !entity_type.data().has_point_defense() {
+        Html::default()
+    } else {
+        let onclick = ui_event_callback
+            .reform(move |_: MouseEvent| UiEvent::PointDefense(point_defense.next()));
+        let engaged = point_defense != PointDefense::Off;
+
+        html! {
+            <div class={classes!(button_style.clone(), engaged.then(|| button_selected_style.clone()))} {onclick} title={t.point_defense_hint()}>
+                {t.point_defense_label(point_defense)}
+            </div>
+        }
+    }
+}
+
+fn flight_profile_button(
+    t: LanguageId,
+    entity_type: EntityType,
+    flight_profile: FlightProfile,
+    waypoints: usize,
+    button_style: &StyleSource,
+    button_selected_style: &StyleSource,
+    ui_event_callback: &Callback<UiEvent>,
+) -> Html {
+    if !entity_type.data().has_missiles() {
+        Html::default()
+    } else {
+        let onclick = ui_event_callback
+            .reform(move |_: MouseEvent| UiEvent::FlightProfile(flight_profile.next()));
+        let routed = flight_profile != FlightProfile::Direct || waypoints > 0;
+
+        html! {
+            <div class={classes!(button_style.clone(), routed.then(|| button_selected_style.clone()))} {onclick} title={t.flight_profile_hint()}>
+                {t.flight_profile_label(flight_profile)}
+                if waypoints > 0 {
+                    {format!(" (+{waypoints})")}
+                }
+            </div>
+        }
+    }
+}
+
+fn weapon_group_button(
+    t: LanguageId,
+    entity_type: EntityType,
+    weapon_group: WeaponGroup,
+    button_style: &StyleSource,
+    button_selected_style: &StyleSource,
+    ui_event_callback: &Callback<UiEvent>,
+) -> Html {
+    if entity_type.data().armaments.len() < 2 {
+        Html::default()
+    } else {
+        let onclick = ui_event_callback
+            .reform(move |_: MouseEvent| UiEvent::WeaponGroup(weapon_group.next()));
+        let partial = weapon_group != WeaponGroup::All;
+
+        html! {
+            <div class={classes!(button_style.clone(), partial.then(|| button_selected_style.clone()))} {onclick} title={t.weapon_group_hint()}>
+                {t.weapon_group_label(weapon_group)}
+            </div>
+        }
+    }
+}
+
+fn hover_button(
+    t: LanguageId,
+    entity_type: EntityType,
+    hover: bool,
+    button_style: &StyleSource,
+    button_selected_style: &StyleSource,
+    ui_event_callback: &Callback<UiEvent>,
+) -> Html {
+    if !entity_type.data().has_helicopters() {
+        Html::default()
+    } else {
+        let onclick = ui_event_callback.reform(move |_: MouseEvent| UiEvent::Hover(!hover));
+
+        html! {
+            <div class={classes!(button_style.clone(), hover.then(|| button_selected_style.clone()))} {onclick} title={t.hover_hint()}>
+                {t.hover_label()}
+            </div>
+        }
+    }
+}
+
+fn hire_button(
+    t: LanguageId,
+    entity_type: EntityType,
+    score: u32,
+    button_style: &StyleSource,
+    consumed_style: &StyleSource,
+    ui_event_callback: &Callback<UiEvent>,
+) -> Html {
+    let data: &'static EntityData = entity_type.data();
+    if data.max_escorts() == 0 {
+        Html::default()
+    } else {
+        let cost = data.escort_cost();
+        // Hiring can't cost levels.
+        let affordable = score >= level_to_score(data.level) + cost;
+        let onclick = ui_event_callback.reform(move |_: MouseEvent| UiEvent::Hire);
+
+        html! {
+            <div class={classes!(button_style.clone(), (!affordable).then(|| consumed_style.clone()))} {onclick} title={t.escort_hire_hint()}>
+                {format!("{} ({cost})", t.escort_hire_label())}
+            </div>
+        }
+    }
+}
@@ -22,7 +22,7 @@ pub fn ships_dialog() -> Html {
     let table_style = css!(
         r#"
         border-spacing: 1em;
-		text-align: left;
+		text-align: start;
 		width: 100%;
 		"#
     );
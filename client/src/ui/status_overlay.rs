@@ -1,9 +1,12 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::telemetry::Telemetry;
+use crate::translation::Mk48Translation;
 use crate::ui::UiStatusPlaying;
 use common::entity::EntityData;
 use common::util::level_to_score;
+use core_protocol::id::LanguageId;
 use glam::Vec2;
 use yew::{function_component, html, Html, Properties};
 use yew_frontend::component::meter::Meter;
@@ -13,6 +16,8 @@ use yew_frontend::translation::{use_translation, Translation};
 pub struct StatusProps {
     pub score: u32,
     pub fps: Option<f32>,
+    /// Interpolation delay and jitter, in seconds (for debugging).
+    pub network: Option<(f32, f32)>,
     pub status: UiStatusPlaying,
 }
 
@@ -44,7 +49,19 @@ pub fn status_overlay(props: &StatusProps) -> Html {
                     {" "}
                     {format!("{:\u{00A0}>5.1}\u{00A0}fps", fps)}
                 }
+                if let Some((delay, jitter)) = props.network {
+                    {" "}
+                    {format!("{:\u{00A0}>3.0}ms\u{00A0}±{:.0}ms", delay * 1000.0, jitter * 1000.0)}
+                }
             </h2>
+            if let Some(seconds) = status.border_warning {
+                <div style="margin-bottom: 0.25rem; color: #e74c3c; font-weight: bold;">
+                    {t.border_warning(seconds.ceil() as u32)}
+                </div>
+            }
+            if let Some(telemetry) = status.telemetry.as_ref() {
+                {telemetry_panel(telemetry, t)}
+            }
             if next_level <= EntityData::MAX_BOAT_LEVEL {
                 <Meter value={progress}>{t.upgrade_to_level_progress((progress * 100.0) as u8, next_level as u32)}</Meter>
             }
@@ -52,6 +69,69 @@ pub fn status_overlay(props: &StatusProps) -> Html {
     }
 }
 
+fn telemetry_panel(telemetry: &Telemetry, t: LanguageId) -> Html {
+    const WARNING: &str = "color: #e74c3c;";
+
+    fn fmt_sensor(name: &str, range: f32) -> String {
+        if range > 0.0 {
+            format!("{}\u{00A0}{:.0}m", name, range)
+        } else {
+            String::new()
+        }
+    }
+
+    let mode = if telemetry.active {
+        t.telemetry_active_label()
+    } else {
+        t.telemetry_passive_label()
+    };
+    let sensors = [
+        fmt_sensor(t.telemetry_visual_label(), telemetry.visual),
+        fmt_sensor(t.sensor_radar_label(), telemetry.radar),
+        fmt_sensor(t.sensor_sonar_label(), telemetry.sonar),
+    ]
+    .into_iter()
+    .filter(|s| !s.is_empty())
+    .collect::<Vec<_>>()
+    .join(" ");
+
+    html! {
+        <div style="margin-bottom: 0.25rem; font-family: monospace, sans-serif; font-size: 0.8rem;">
+            <div style={telemetry.cavitating().then_some(WARNING)}>
+                {t.telemetry_speed(telemetry.speed.abs().to_knots(), telemetry.cavitation_speed.to_knots())}
+            </div>
+            <div>{format!("{} ({})", sensors, mode)}</div>
+            if telemetry.sonar_detection > 0.0 {
+                <div>{t.telemetry_sonar_detection(telemetry.sonar_detection)}</div>
+            }
+            <div style="display: flex; gap: 2px; margin: 0.2rem 0;">
+                {telemetry.reloads.iter().map(|&progress| {
+                    let style = match progress {
+                        Some(progress) => format!(
+                            "flex: 1; height: 0.4rem; background: linear-gradient(90deg, #0084b1 {0}%, #3f3333 {0}%);",
+                            (progress * 100.0).round()
+                        ),
+                        // Unknown.
+                        None => "flex: 1; height: 0.4rem; background-color: #777777;".to_owned(),
+                    };
+                    html!{<div {style}/>}
+                }).collect::<Html>()}
+            </div>
+            if telemetry.reload_all > 0.0 {
+                <div>{t.telemetry_reload_all(telemetry.reload_all.ceil())}</div>
+            }
+            if telemetry.incoming > 0 {
+                <div style={format!("{} font-weight: bold;", WARNING)}>
+                    {t.telemetry_incoming(telemetry.incoming)}
+                    if let Some(impact) = telemetry.impact {
+                        {t.telemetry_impact(impact)}
+                    }
+                </div>
+            }
+        </div>
+    }
+}
+
 fn fmt_position(position: Vec2) -> String {
     fn fmt_coordinate(coordinate: f32, positive: char, negative: char) -> String {
         format!(
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Caches the client, so repeat visits (and installs to the home screen) load without downloading
+// it again. Registered as sw.js?v=<client hash>, so each version of the client gets its own cache,
+// which is filled completely before the version is used.
+
+const VERSION = new URL(self.location).searchParams.get('v') || 'unversioned';
+const PREFIX = 'mk48-';
+const CACHE = PREFIX + VERSION;
+
+// The client bundle, sprites, and audio. Everything else (e.g. status.json and /ws) always goes
+// to the network.
+const ASSETS = [
+    '/',
+    '/client.js',
+    '/client_bg.wasm',
+    '/favicon.png',
+    '/logo-712.png',
+    '/manifest.json',
+    '/sprites_audio.mp3',
+    '/sprites_css.png',
+    '/sprites_normal_webgl.png',
+    '/sprites_webgl.png',
+    '/textures.png',
+];
+
+self.addEventListener('install', event => {
+    event.waitUntil((async () => {
+        const cache = await caches.open(CACHE);
+        // Revalidate the HTTP cache, which may hold files of the previous version. If any file
+        // fails, so does the install, and the previous version stays in use.
+        await cache.addAll(ASSETS.map(asset => new Request(asset, {cache: 'no-cache'})));
+        if (!self.registration.active) {
+            // Nothing to replace, so no need to wait for the page to ask.
+            await self.skipWaiting();
+        }
+    })());
+});
+
+self.addEventListener('activate', event => {
+    event.waitUntil((async () => {
+        for (const name of await caches.keys()) {
+            if (name.startsWith(PREFIX) && name !== CACHE) {
+                await caches.delete(name);
+            }
+        }
+        await self.clients.claim();
+    })());
+});
+
+// Sent by the page when the player accepts the "new version available" prompt.
+self.addEventListener('message', event => {
+    if (event.data === 'skipWaiting') {
+        self.skipWaiting();
+    }
+});
+
+self.addEventListener('fetch', event => {
+    const request = event.request;
+    if (request.method !== 'GET') {
+        return;
+    }
+    const url = new URL(request.url);
+    if (url.origin !== self.location.origin) {
+        return;
+    }
+
+    // All pages (e.g. /invite/<id>/) are the same index.html. Other requests for it (e.g. checking
+    // whether the server is reachable) go to the network.
+    const navigate = request.mode === 'navigate';
+    const key = navigate && !url.pathname.includes('.') ? '/' : url.pathname;
+    if (!ASSETS.includes(key) || (key === '/' && !navigate)) {
+        return;
+    }
+
+    event.respondWith((async () => {
+        const cache = await caches.open(CACHE);
+        const cached = await cache.match(key);
+        return cached || fetch(request);
+    })());
+});
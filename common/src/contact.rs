@@ -5,6 +5,7 @@ use crate::altitude::Altitude;
 use crate::angle::Angle;
 use crate::entity::*;
 use crate::guidance::Guidance;
+use crate::terrain::Terrain;
 use crate::ticks::Ticks;
 use crate::transform::Transform;
 use crate::util::make_mut_slice;
@@ -116,10 +117,11 @@ impl Contact {
 
     /// Simulate delta_seconds passing, by updating guidance and kinematics. This is an approximation
     /// of how the corresponding entity behaves on the server.
-    pub fn simulate(&mut self, delta_seconds: f32) {
+    pub fn simulate(&mut self, delta_seconds: f32, terrain: &Terrain) {
         if let Some(entity_type) = self.entity_type() {
+            let data = entity_type.data();
             let guidance = *self.guidance();
-            let max_speed = match entity_type.data().sub_kind {
+            let max_speed = match data.sub_kind {
                 // Wait until risen to surface.
                 EntitySubKind::Missile
                 | EntitySubKind::Rocket
@@ -129,15 +131,18 @@ impl Contact {
                 {
                     EntityData::SURFACING_PROJECTILE_SPEED_LIMIT
                 }
+                // Slowed by crossing terrain.
+                EntitySubKind::Hovercraft => {
+                    let terrain_altitude = terrain
+                        .sample(self.transform().position)
+                        .unwrap_or(Altitude::MIN);
+                    data.speed.to_mps() * data.terrain_speed_factor(terrain_altitude)
+                }
                 _ => f32::INFINITY,
             };
 
-            self.transform_mut().apply_guidance(
-                entity_type.data(),
-                guidance,
-                max_speed,
-                delta_seconds,
-            );
+            self.transform_mut()
+                .apply_guidance(data, guidance, max_speed, delta_seconds);
         }
         self.transform_mut().do_kinematics(delta_seconds);
     }
@@ -448,6 +453,37 @@ impl<'a> Serialize for ContactSerializer<'a> {
     }
 }
 
+/// A contact, encoded once with bincode, so that it may be shared by many clients' updates without
+/// being encoded again.
+#[derive(Clone, Debug)]
+pub struct EncodedContact(Arc<(Contact, Box<[u8]>)>);
+
+impl EncodedContact {
+    pub fn new(contact: Contact) -> Self {
+        let bytes = bincode::serialize(&contact).unwrap().into_boxed_slice();
+        Self(Arc::new((contact, bytes)))
+    }
+
+    pub fn contact(&self) -> &Contact {
+        &self.0 .0
+    }
+}
+
+impl Serialize for EncodedContact {
+    fn serialize<S>(&self, serializer: S) -> Result<S::Ok, S::Error>
+    where
+        S: Serializer,
+    {
+        if serializer.is_human_readable() {
+            // Cached bytes are only valid for bincode (e.g. not json).
+            self.contact().serialize(serializer)
+        } else {
+            // Bincode writes bytes of a tuple back to back, reproducing the cached encoding.
+            ByteSerializer::new(&self.0 .1).serialize(serializer)
+        }
+    }
+}
+
 /// Serializes a slice of bytes without length (known size).
 struct ByteSerializer<'a> {
     items: &'a [u8],
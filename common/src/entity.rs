@@ -24,6 +24,7 @@ pub use turret::Turret;
 
 #[cfg(test)]
 mod tests {
+    use crate::altitude::Altitude;
     use crate::entity::{EntityKind, EntityType};
 
     #[test]
@@ -39,4 +40,26 @@ mod tests {
             println!("{:?} sensor range is {}", typ, range);
         }
     }
+
+    #[test]
+    fn passive_sonar_range() {
+        let listener_range = 1000.0;
+        let listener_speed = 5.0;
+        for typ in EntityType::iter() {
+            let data = typ.data();
+            if data.kind != EntityKind::Boat {
+                continue;
+            }
+            let noise = data.sonar_noise(data.speed, Altitude::ZERO, false);
+            let range = data.passive_sonar_range(noise, listener_range, listener_speed);
+
+            // Same as the passive sonar ratio in get_player_complete.
+            let ratio = |distance: f32| {
+                distance.powi(2) * data.inv_size * listener_range.powi(-2) / noise
+                    * (20.0 + listener_speed)
+            };
+            assert!(ratio(range * 0.99) < 1.0, "{:?}", typ);
+            assert!(ratio(range * 1.01) > 1.0, "{:?}", typ);
+        }
+    }
 }
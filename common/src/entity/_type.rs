@@ -75,6 +75,16 @@ impl EntityType {
         .flatten()
     }
 
+    /// escort_options returns an iterator that visits all entity types that this boat may hire as
+    /// escorts and allows a random choice to be made.
+    pub fn escort_options(self) -> impl Iterator<Item = Self> + IteratorRandom {
+        let level = self.data().escort_level();
+        Self::iter().filter(move |t| {
+            let data = t.data();
+            data.kind == EntityKind::Boat && data.level == level && !data.npc
+        })
+    }
+
     /// iterates all loot types entity should drop. Takes score before death.
     pub fn loot(self, score: u32, score_to_coins: bool) -> impl Iterator<Item = Self> + 'static {
         let data: &EntityData = self.data();
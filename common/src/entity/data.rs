@@ -1,11 +1,13 @@
 use crate::altitude::Altitude;
 use crate::entity::{Armament, EntityKind, EntitySubKind, Exhaust, Sensors, Turret};
+use crate::terrain::{GRASS_LEVEL, SAND_LEVEL};
 use crate::ticks;
 use crate::ticks::Ticks;
 use crate::transform::Transform;
+use crate::util::level_to_score;
 use crate::velocity::Velocity;
 use common_util::angle::Angle;
-use common_util::range::map_ranges_fast;
+use common_util::range::{map_ranges, map_ranges_fast};
 use glam::Vec2;
 use std::ops::Range;
 
@@ -82,6 +84,69 @@ impl EntityData {
         self.radii().end
     }
 
+    /// Whether any turrets are point defense turrets.
+    pub fn has_point_defense(&self) -> bool {
+        self.turrets.iter().any(|t| t.is_point_defense())
+    }
+
+    /// Maximum number of escorts a boat of this level may hire at once.
+    pub fn max_escorts(&self) -> usize {
+        match self.level {
+            0..=3 => 0,
+            4..=6 => 1,
+            _ => 2,
+        }
+    }
+
+    /// Level of the escorts a boat of this level hires.
+    pub fn escort_level(&self) -> u8 {
+        (self.level / 3).max(1)
+    }
+
+    /// Score it costs to hire one escort.
+    pub fn escort_cost(&self) -> u32 {
+        level_to_score(self.escort_level() + 1)
+    }
+
+    /// Terrain at or above this altitude is impassable to this entity (when not submerged).
+    /// Hovercraft can cross beaches and low-lying islands.
+    pub fn impassable_altitude(&self) -> Altitude {
+        if self.sub_kind == EntitySubKind::Hovercraft {
+            GRASS_LEVEL
+        } else {
+            SAND_LEVEL
+        }
+    }
+
+    /// Returns the multiplier of max speed over terrain of the given altitude, for entities that can
+    /// cross terrain. Hovercraft slow down as the terrain gets higher.
+    pub fn terrain_speed_factor(&self, terrain_altitude: Altitude) -> f32 {
+        if terrain_altitude < SAND_LEVEL || terrain_altitude >= self.impassable_altitude() {
+            1.0
+        } else {
+            map_ranges(
+                terrain_altitude.0 as f32,
+                SAND_LEVEL.0 as f32..GRASS_LEVEL.0 as f32,
+                0.6..0.3,
+                true,
+            )
+        }
+    }
+
+    /// Whether any armaments are missiles, which can be fired with a flight profile.
+    pub fn has_missiles(&self) -> bool {
+        self.armaments
+            .iter()
+            .any(|a| a.entity_type.data().sub_kind == EntitySubKind::Missile)
+    }
+
+    /// Whether any armaments are helicopters, which can be ordered to hover.
+    pub fn has_helicopters(&self) -> bool {
+        self.armaments
+            .iter()
+            .any(|a| a.entity_type.data().sub_kind == EntitySubKind::Heli)
+    }
+
     /// max_health returns the the minimum damage to kill a boat, panicking if the corresponding
     /// entity does not have health.
     pub fn max_health(&self) -> Ticks {
@@ -114,6 +179,34 @@ impl EntityData {
         )
     }
 
+    /// Returns the noise an entity of this type makes, for the purpose of passive sonar, given its
+    /// speed and altitude, and whether it (a boat) has its sensors active.
+    pub fn sonar_noise(&self, speed: Velocity, altitude: Altitude, active: bool) -> f32 {
+        let mut noise = 2f32.max(speed.abs().to_mps() - self.cavitation_speed(altitude).to_mps());
+
+        if matches!(
+            self.kind,
+            EntityKind::Boat | EntityKind::Weapon | EntityKind::Decoy
+        ) {
+            noise *= 2.0;
+
+            if self.kind != EntityKind::Boat {
+                noise += 100.0;
+            } else if active && self.sensors.sonar.range > 0.0 {
+                // Active sonar gives away entity's position.
+                noise += 20.0;
+            }
+        }
+        noise
+    }
+
+    /// Returns the distance within which an entity of this type, making a given amount of noise,
+    /// is detected by a listener's passive sonar, given the listener's sonar range and speed.
+    pub fn passive_sonar_range(&self, noise: f32, listener_range: f32, listener_speed: f32) -> f32 {
+        // Making noise of your own reduces the performance of passive sonar.
+        listener_range * (noise / (self.inv_size * (20.0 + listener_speed))).sqrt()
+    }
+
     /// armament_transform returns the entity-relative transform of a given armament.
     pub fn armament_transform(&self, turret_angles: &[Angle], index: usize) -> Transform {
         let armament = &self.armaments[index];
@@ -150,12 +243,14 @@ impl EntityData {
         transform
     }
 
-    /// update_turret_aim brings turret_angles delta_seconds closer to position_target.
+    /// update_turret_aim brings turret_angles delta_seconds closer to position_target, or
+    /// point_defense_target for point defense turrets that are engaging.
     pub fn update_turret_aim(
         &self,
         boat_transform: Transform,
         turret_angles: &mut [Angle],
         position_target: Option<Vec2>,
+        point_defense_target: Option<Vec2>,
         delta_seconds: f32,
     ) {
         for (i, a) in turret_angles.iter_mut().enumerate() {
@@ -163,6 +258,11 @@ impl EntityData {
             let amount = Angle::from_radians(
                 (delta_seconds * turret.speed.to_radians()).clamp(0.0, std::f32::consts::PI),
             );
+            let position_target = if turret.is_point_defense() {
+                point_defense_target.or(position_target)
+            } else {
+                position_target
+            };
             let mut direction_target = turret.angle;
             if let Some(target) = position_target {
                 let turret_global_transform = boat_transform
@@ -186,3 +286,44 @@ impl EntityData {
         }
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use crate::altitude::Altitude;
+    use crate::entity::EntityType;
+    use crate::terrain::{GRASS_LEVEL, SAND_LEVEL};
+
+    #[test]
+    fn impassable_altitude() {
+        let hovercraft = EntityType::Zubr.data();
+        assert!(SAND_LEVEL < hovercraft.impassable_altitude());
+        assert!(GRASS_LEVEL >= hovercraft.impassable_altitude());
+
+        let boat = EntityType::FairmileD.data();
+        assert!(SAND_LEVEL >= boat.impassable_altitude());
+        assert!(SAND_LEVEL - Altitude::UNIT < boat.impassable_altitude());
+    }
+
+    #[test]
+    fn terrain_speed_factor() {
+        let hovercraft = EntityType::Zubr.data();
+        assert_eq!(
+            hovercraft.terrain_speed_factor(SAND_LEVEL - Altitude::UNIT),
+            1.0
+        );
+        assert!((hovercraft.terrain_speed_factor(SAND_LEVEL) - 0.6).abs() < 0.01);
+
+        // Slows down more and more, approaching 0.3 just below the grass.
+        let mut previous = 1.0;
+        for altitude in SAND_LEVEL.0..GRASS_LEVEL.0 {
+            let factor = hovercraft.terrain_speed_factor(Altitude(altitude));
+            assert!(factor < previous, "{} >= {}", factor, previous);
+            previous = factor;
+        }
+        assert!((previous - 0.3).abs() < 0.03, "{}", previous);
+
+        // Boats never cross terrain, so they aren't slowed by it.
+        let boat = EntityType::FairmileD.data();
+        assert_eq!(boat.terrain_speed_factor(SAND_LEVEL - Altitude::UNIT), 1.0);
+    }
+}
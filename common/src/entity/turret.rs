@@ -1,4 +1,4 @@
-use crate::entity::EntityType;
+use crate::entity::{EntitySubKind, EntityType};
 use common_util::angle::Angle;
 use glam::Vec2;
 
@@ -16,10 +16,35 @@ pub struct Turret {
 }
 
 impl Turret {
+    /// Guns of at most this caliber, in meters, fire rapidly enough to shoot down missiles.
+    const CIWS_MAX_CALIBER: f32 = 0.03;
+
     pub fn position(&self) -> Vec2 {
         Vec2::new(self.position_forward, self.position_side)
     }
 
+    /// Whether the turret automatically engages incoming missiles and aircraft.
+    pub fn is_point_defense(&self) -> bool {
+        self.entity_type
+            .map_or(false, |t| t.data().sub_kind == EntitySubKind::Sam)
+            || self.is_ciws()
+    }
+
+    /// Whether the turret is a close-in weapon system, a rapid-firing gun that engages incoming
+    /// missiles and aircraft at short range.
+    pub fn is_ciws(&self) -> bool {
+        self.entity_type.map_or(false, |t| {
+            let data = t.data();
+            data.sub_kind == EntitySubKind::Gun
+                && !data.armaments.is_empty()
+                && data.armaments.iter().all(|armament| {
+                    let armament_data = armament.entity_type.data();
+                    armament_data.sub_kind == EntitySubKind::Shell
+                        && armament_data.width <= Self::CIWS_MAX_CALIBER
+                })
+        })
+    }
+
     /// within_azimuth returns whether the given boat-relative angle is within the azimuth (horizontal
     /// angle) limits, if any.
     pub fn within_azimuth(&self, curr: Angle) -> bool {
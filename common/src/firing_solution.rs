@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::angle::Angle;
+use crate::entity::{EntityData, EntityKind, EntitySubKind};
+use crate::guidance::Guidance;
+use crate::transform::Transform;
+use glam::Vec2;
+
+/// Seconds per simulation step, same as a server tick.
+const STEP_SECONDS: f32 = 0.1;
+/// Don't predict targets further into the future than this, as their guidance will change anyway.
+const MAX_PREDICTION_SECONDS: f32 = 60.0;
+
+/// How a weapon travels after being fired.
+#[derive(Copy, Clone, Debug, Eq, PartialEq)]
+pub enum Trajectory {
+    /// Turns towards the aim target, and then runs straight.
+    Straight,
+    /// Like `Straight`, until its seeker acquires a target.
+    Homing,
+    /// Starts with all its velocity, and can't turn.
+    Ballistic,
+}
+
+impl Trajectory {
+    /// Returns `None` if the weapon doesn't travel towards the aim target (e.g. mines, aircraft).
+    pub fn of(data: &EntityData) -> Option<Self> {
+        if data.kind != EntityKind::Weapon || data.speed.to_mps() <= 0.0 {
+            return None;
+        }
+        Some(match data.sub_kind {
+            EntitySubKind::Depositor | EntitySubKind::DepthCharge | EntitySubKind::Mine => {
+                return None
+            }
+            EntitySubKind::Shell => Self::Ballistic,
+            // Same as the server, rockets never home.
+            EntitySubKind::Rocket => Self::Straight,
+            _ if data.sensors.any() => Self::Homing,
+            _ => Self::Straight,
+        })
+    }
+}
+
+/// A point on a predicted weapon path.
+#[derive(Copy, Clone, Debug, PartialEq)]
+pub struct PathPoint {
+    pub position: Vec2,
+    /// Seconds since launch.
+    pub seconds: f32,
+    /// Height of a ballistic arc, from 0 to 1 (always 0 for other trajectories). The server
+    /// simulates shells in 2D, so this is only a visual aid.
+    pub arc: f32,
+    /// Whether a homing weapon's seeker would be active.
+    pub seeking: bool,
+}
+
+/// Predicts the path of a weapon of type `data`, launched with `launch` (see
+/// `EntityData::armament_transform`), and aimed at `aim`, until it runs out of range. Returns at
+/// most `max_points` evenly spaced points, or nothing if the weapon doesn't have a `Trajectory`.
+pub fn predict_path(
+    data: &EntityData,
+    launch: Transform,
+    vertical: bool,
+    aim: Vec2,
+    max_points: usize,
+) -> Vec<PathPoint> {
+    let trajectory = match Trajectory::of(data) {
+        Some(trajectory) => trajectory,
+        None => return Vec::new(),
+    };
+
+    let guidance = Guidance {
+        direction_target: Angle::from(aim - launch.position),
+        velocity_target: data.speed,
+    };
+    let mut transform = launch;
+    if vertical {
+        // Vertically-launched armaments can be launched in any horizontal direction.
+        transform.direction = guidance.direction_target;
+    }
+
+    let lifespan = data.lifespan.to_secs();
+    let spacing = data.range / max_points.max(1) as f32;
+    let mut points = Vec::with_capacity(max_points);
+    let mut seconds = 0.0;
+    let mut traveled = 0.0;
+    let mut next_point = 0.0;
+
+    while seconds <= lifespan && traveled <= data.range && points.len() < max_points {
+        if traveled >= next_point {
+            let fraction = (seconds / lifespan).min(1.0);
+            points.push(PathPoint {
+                position: transform.position,
+                seconds,
+                arc: if trajectory == Trajectory::Ballistic {
+                    4.0 * fraction * (1.0 - fraction)
+                } else {
+                    0.0
+                },
+                // Same as the server, seekers activate after one second.
+                seeking: trajectory == Trajectory::Homing && seconds > 1.0,
+            });
+            next_point += spacing;
+        }
+
+        let before = transform.position;
+        transform.apply_guidance(data, guidance, f32::INFINITY, STEP_SECONDS);
+        transform.do_kinematics(STEP_SECONDS);
+        traveled += transform.position.distance(before);
+        seconds += STEP_SECONDS;
+    }
+
+    points
+}
+
+/// Seconds for a weapon of type `data` to travel `distance` in a straight line, given its speed at
+/// launch, accelerating the same way as `Transform::apply_guidance`. Returns infinity if the weapon
+/// can't move.
+pub fn time_to_travel(data: &EntityData, launch_speed: f32, distance: f32) -> f32 {
+    let max_speed = data.speed.to_mps();
+    if max_speed <= 0.0 {
+        return f32::INFINITY;
+    }
+    let initial_speed = launch_speed.clamp(0.0, max_speed);
+    let acceleration = 1.0 / 3.0 * max_speed.clamp(15.0, 500.0);
+
+    // Distance covered until max speed is reached.
+    let accelerating_distance = (max_speed.powi(2) - initial_speed.powi(2)) / (2.0 * acceleration);
+
+    if distance <= accelerating_distance {
+        // Solve distance = initial_speed * t + acceleration * t^2 / 2.
+        ((initial_speed.powi(2) + 2.0 * acceleration * distance).sqrt() - initial_speed)
+            / acceleration
+    } else {
+        (max_speed - initial_speed) / acceleration + (distance - accelerating_distance) / max_speed
+    }
+}
+
+/// Predicts where a target of type `data` will be after `seconds`, assuming it keeps following its
+/// current guidance.
+pub fn predict_position(
+    data: &EntityData,
+    mut transform: Transform,
+    guidance: Guidance,
+    seconds: f32,
+) -> Vec2 {
+    let mut remaining = seconds.clamp(0.0, MAX_PREDICTION_SECONDS);
+    while remaining > 0.0 {
+        let step = remaining.min(STEP_SECONDS);
+        transform.apply_guidance(data, guidance, f32::INFINITY, step);
+        transform.do_kinematics(step);
+        remaining -= step;
+    }
+    transform.position
+}
+
+/// Where to aim a weapon to hit a moving target.
+#[derive(Copy, Clone, Debug, PartialEq)]
+pub struct Intercept {
+    /// Predicted position of the target when the weapon arrives.
+    pub position: Vec2,
+    /// Seconds from launch until the weapon arrives.
+    pub seconds: f32,
+    /// Whether the intercept is within the weapon's range.
+    pub in_range: bool,
+}
+
+/// Computes the point at which a weapon of type `weapon`, launched with `launch`, would intercept
+/// a target of type `target_data` following `target_guidance`. Doesn't account for time spent
+/// turning towards the target, or for homing. Returns `None` if the weapon doesn't have a
+/// `Trajectory` or can't catch up with the target.
+pub fn intercept(
+    weapon: &EntityData,
+    launch: &Transform,
+    target_data: &EntityData,
+    target_transform: Transform,
+    target_guidance: Guidance,
+) -> Option<Intercept> {
+    Trajectory::of(weapon)?;
+
+    let launch_speed = launch.velocity.to_mps();
+    let time_to =
+        |position: Vec2| time_to_travel(weapon, launch_speed, position.distance(launch.position));
+
+    // Fixed point iteration, which converges as long as the weapon is faster than the target.
+    let mut position = target_transform.position;
+    let mut seconds = time_to(position);
+    for _ in 0..16 {
+        if seconds > MAX_PREDICTION_SECONDS {
+            return None;
+        }
+        position = predict_position(target_data, target_transform, target_guidance, seconds);
+        let next = time_to(position);
+        let converged = (next - seconds).abs() < STEP_SECONDS * 0.1;
+        seconds = next;
+        if converged {
+            return Some(Intercept {
+                position,
+                seconds,
+                in_range: position.distance(launch.position) <= weapon.range
+                    && seconds <= weapon.lifespan.to_secs(),
+            });
+        }
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::angle::Angle;
+    use crate::entity::EntityType;
+    use crate::firing_solution::{
+        intercept, predict_path, predict_position, time_to_travel, Trajectory,
+    };
+    use crate::guidance::Guidance;
+    use crate::transform::Transform;
+    use crate::velocity::Velocity;
+    use glam::Vec2;
+
+    #[test]
+    fn trajectories() {
+        assert_eq!(
+            Trajectory::of(EntityType::Mark18.data()),
+            Some(Trajectory::Straight)
+        );
+        assert_eq!(
+            Trajectory::of(EntityType::_82R.data()),
+            Some(Trajectory::Homing)
+        );
+        assert_eq!(
+            Trajectory::of(EntityType::_76X636MmR.data()),
+            Some(Trajectory::Ballistic)
+        );
+        assert_eq!(Trajectory::of(EntityType::Mark9.data()), None);
+    }
+
+    #[test]
+    fn time_to_travel_matches_simulation() {
+        for entity_type in [EntityType::Mark18, EntityType::Barak8, EntityType::Asroc] {
+            let data = entity_type.data();
+            let guidance = Guidance {
+                direction_target: Angle::ZERO,
+                velocity_target: data.speed,
+            };
+            let mut transform = Transform {
+                velocity: Velocity::from_mps(1.0),
+                ..Transform::new()
+            };
+            let mut seconds = 0.0;
+            while transform.position.x < data.range * 0.5 {
+                transform.apply_guidance(data, guidance, f32::INFINITY, 0.01);
+                transform.do_kinematics(0.01);
+                seconds += 0.01;
+            }
+            let predicted = time_to_travel(data, 1.0, transform.position.x);
+            assert!(
+                (predicted - seconds).abs() < seconds * 0.05 + 0.1,
+                "{:?} {} {}",
+                entity_type,
+                predicted,
+                seconds
+            );
+        }
+    }
+
+    #[test]
+    fn intercept_moving_target() {
+        let weapon = EntityType::Mark18.data();
+        let target = EntityType::Fletcher.data();
+        let launch = Transform {
+            velocity: Velocity::from_mps(1.0),
+            ..Transform::new()
+        };
+        let target_transform = Transform {
+            position: Vec2::new(300.0, 200.0),
+            direction: Angle::from_degrees(90.0),
+            velocity: Velocity::from_mps(3.0),
+        };
+        let target_guidance = Guidance {
+            direction_target: Angle::from_degrees(90.0),
+            velocity_target: Velocity::from_mps(3.0),
+        };
+
+        let solution = intercept(weapon, &launch, target, target_transform, target_guidance)
+            .expect("should intercept");
+        assert!(solution.in_range);
+        assert!(solution.position.y > target_transform.position.y);
+
+        // The target arrives where the weapon arrives, when the weapon arrives.
+        let target_position =
+            predict_position(target, target_transform, target_guidance, solution.seconds);
+        assert!(target_position.distance(solution.position) < 2.0);
+
+        // A distant target is out of range.
+        let far = Transform {
+            position: Vec2::new(weapon.range * 2.0, 0.0),
+            ..target_transform
+        };
+        let solution = intercept(weapon, &launch, target, far, target_guidance);
+        assert!(solution.map_or(true, |s| !s.in_range));
+    }
+
+    #[test]
+    fn path() {
+        let data = EntityType::_76X636MmR.data();
+        let launch = Transform {
+            velocity: data.speed,
+            ..Transform::new()
+        };
+        let path = predict_path(data, launch, false, Vec2::new(100.0, 0.0), 32);
+        assert!(!path.is_empty() && path.len() <= 32);
+        assert!(path.iter().all(|p| p.position.x <= data.range + 100.0));
+        assert!(path.iter().any(|p| p.arc > 0.5));
+    }
+}
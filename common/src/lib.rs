@@ -19,6 +19,7 @@ pub mod complete;
 pub mod contact;
 pub mod death_reason;
 pub mod entity;
+pub mod firing_solution;
 pub mod guidance;
 pub mod protocol;
 pub mod terrain;
@@ -1,30 +1,64 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::angle::Angle;
 use crate::contact::Contact;
 use crate::death_reason::DeathReason;
 use crate::entity::*;
 use crate::guidance::Guidance;
 use crate::terrain::{ChunkId, SerializedChunk};
+use crate::ticks::Ticks;
+use core_protocol::id::PlayerId;
 use glam::Vec2;
 use serde::{Deserialize, Serialize};
+use std::sync::Arc;
 
-/// Server to client update.
+/// Server to client update. The server may use a different, but identically serialized, type
+/// of contact (see `EncodedContact`).
 #[cfg_attr(feature = "server", derive(actix::Message))]
 #[cfg_attr(feature = "server", rtype(result = "()"))]
 #[derive(Debug, Serialize, Deserialize)]
-pub struct Update {
+pub struct Update<C = Contact> {
     /// All currently visible contacts.
-    pub contacts: Vec<Contact>,
+    pub contacts: Vec<C>,
     /// Why the player died, if they died, otherwise None.
     pub death_reason: Option<DeathReason>,
     /// Player's current score.
     pub score: u32,
     /// Current world border radius.
     pub world_radius: f32,
+    /// Radius the world border is growing or shrinking towards.
+    pub world_target_radius: f32,
+    /// Seconds until the shrinking world border reaches the player's boat, if soon.
+    pub border_warning: Option<f32>,
+    /// Players with a bounty on them, shown to everyone.
+    pub bounties: Arc<[Bounty]>,
+    /// Important events since the previous update.
+    pub alerts: Alerts,
     pub terrain: Box<TerrainUpdate>,
 }
 
+/// Events that the player may want to know about, even while not watching the game (e.g. it is in
+/// a background tab).
+#[derive(Copy, Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+pub struct Alerts {
+    /// The player's boat took damage.
+    pub damaged: bool,
+    /// A hostile boat appeared among the player's contacts.
+    pub hostile: bool,
+}
+
+/// The approximate position of a player with a bounty.
+#[derive(Clone, Debug, PartialEq, Serialize, Deserialize)]
+pub struct Bounty {
+    pub player_id: PlayerId,
+    /// Score collected by whoever sinks the player.
+    pub value: u32,
+    /// Center of a ring that contains the player's boat (as of when it was last refreshed).
+    pub position: Vec2,
+    pub radius: f32,
+}
+
 /// Updates for terrain chunks.
 pub type TerrainUpdate = [(ChunkId, SerializedChunk)];
 
@@ -36,6 +70,7 @@ pub enum Command {
     Control(Control),
     Spawn(Spawn),
     Upgrade(Upgrade),
+    Hire(Hire),
 }
 
 /// Generic command to control one's ship.
@@ -51,6 +86,12 @@ pub struct Control {
     pub active: bool,
     /// Fire weapon a weapon.
     pub fire: Option<Fire>,
+    /// Fire a group of weapons.
+    pub salvo: Option<Salvo>,
+    /// Point defense engagement mode.
+    pub point_defense: PointDefense,
+    /// Helicopters hover in place, instead of following the aim target.
+    pub hover: bool,
     /// Pay one coin.
     pub pay: Option<Pay>,
     /// Optional hints.
@@ -62,6 +103,145 @@ pub struct Control {
 pub struct Fire {
     /// The index of the weapon to fire/use, relative to `EntityData.armaments`.
     pub armament_index: u8,
+    /// How a missile flies to its target (ignored for other weapons).
+    pub profile: FlightProfile,
+    /// Points a missile flies through, in order, before heading for the aim target.
+    pub waypoints: Vec<Vec2>,
+}
+
+impl Fire {
+    /// Maximum number of waypoints.
+    pub const MAX_WAYPOINTS: usize = 2;
+
+    /// Fires a weapon directly at the aim target.
+    pub fn new(armament_index: u8) -> Self {
+        Self {
+            armament_index,
+            profile: FlightProfile::default(),
+            waypoints: Vec::new(),
+        }
+    }
+}
+
+/// How a missile flies to its target.
+#[derive(Copy, Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+pub enum FlightProfile {
+    /// Fly straight to the target at the usual altitude.
+    #[default]
+    Direct,
+    /// Fly just above the waves, which is harder to detect by radar but burns more fuel.
+    SeaSkimming,
+    /// Cruise high above everything, where thin air extends range, then dive onto the target.
+    HighAltitude,
+}
+
+impl FlightProfile {
+    /// Returns the next profile, for cycling through profiles.
+    pub fn next(self) -> Self {
+        match self {
+            Self::Direct => Self::SeaSkimming,
+            Self::SeaSkimming => Self::HighAltitude,
+            Self::HighAltitude => Self::Direct,
+        }
+    }
+
+    /// Fraction of lifespan burned at launch (climbing to altitude, or flying low in dense air).
+    pub fn fuel_cost(self) -> f32 {
+        match self {
+            Self::Direct => 0.0,
+            Self::SeaSkimming => 0.25,
+            Self::HighAltitude => 0.1,
+        }
+    }
+
+    /// Rate at which lifespan is burned in flight, relative to normal.
+    pub fn fuel_rate(self) -> f32 {
+        match self {
+            Self::HighAltitude => 0.7,
+            _ => 1.0,
+        }
+    }
+
+    /// Multiplier of range, combining fuel cost and rate.
+    pub fn range_factor(self) -> f32 {
+        (1.0 - self.fuel_cost()) / self.fuel_rate()
+    }
+}
+
+/// What point defense turrets automatically engage.
+#[derive(Copy, Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+pub enum PointDefense {
+    /// Don't engage anything.
+    Off,
+    /// Engage missiles and aircraft threatening one's own boat.
+    #[default]
+    SelfDefense,
+    /// Also engage missiles and aircraft threatening nearby teammates.
+    AreaDefense,
+}
+
+impl PointDefense {
+    /// Returns the next mode, for cycling through modes.
+    pub fn next(self) -> Self {
+        match self {
+            Self::Off => Self::SelfDefense,
+            Self::SelfDefense => Self::AreaDefense,
+            Self::AreaDefense => Self::Off,
+        }
+    }
+}
+
+/// Fire a group of weapons, in one command.
+#[derive(Clone, PartialEq, Serialize, Deserialize, Debug)]
+pub struct Salvo {
+    /// The indices of the weapons to fire, relative to `EntityData.armaments`.
+    pub armament_indices: Vec<u8>,
+    /// Total angle over which the weapons are fanned out (evenly, in order of `armament_indices`).
+    pub spread: Angle,
+    /// Delay between firing consecutive weapons.
+    pub interval: Ticks,
+}
+
+impl Salvo {
+    /// Maximum number of weapons in one salvo.
+    pub const MAX_ARMAMENTS: usize = 16;
+    /// Maximum delay between firing consecutive weapons.
+    pub const MAX_INTERVAL: Ticks = Ticks::from_whole_secs(1);
+
+    /// Maximum total spread.
+    pub fn max_spread() -> Angle {
+        Angle::from_degrees(60.0)
+    }
+
+    /// Returns a salvo of weapons of a particular sub kind, with a suitable spread and interval.
+    pub fn new(sub_kind: EntitySubKind, armament_indices: Vec<u8>) -> Self {
+        let count = armament_indices.len().saturating_sub(1) as f32;
+        let (spread, interval) = match sub_kind {
+            // Fan out to cover a target's possible evasive maneuvers.
+            EntitySubKind::Torpedo => (Angle::from_degrees(3.0) * count, Ticks::from_repr(1)),
+            // Ripple fire to avoid weapons colliding with each other.
+            EntitySubKind::Missile | EntitySubKind::Rocket | EntitySubKind::RocketTorpedo => {
+                (Angle::ZERO, Ticks::from_repr(2))
+            }
+            // Broadside.
+            _ => (Angle::ZERO, Ticks::ZERO),
+        };
+        Self {
+            armament_indices,
+            spread: spread.min(Self::max_spread()),
+            interval,
+        }
+    }
+
+    /// Returns the angle offset of the `i`th weapon.
+    pub fn offset(&self, i: usize) -> Angle {
+        let count = self.armament_indices.len();
+        if count < 2 {
+            Angle::ZERO
+        } else {
+            self.spread * (i as f32 / (count - 1) as f32 - 0.5)
+        }
+    }
 }
 
 /// Provide hints to optimize experience.
@@ -95,11 +275,16 @@ pub struct Upgrade {
     pub entity_type: EntityType,
 }
 
+/// Hire an escort, of a type chosen by the server, that follows and defends one's ship. Costs
+/// `EntityData::escort_cost` of score.
+#[derive(Clone, Serialize, Deserialize, Debug)]
+pub struct Hire;
+
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::altitude::Altitude;
-    use crate::contact::ReloadsStorage;
+    use crate::contact::{EncodedContact, ReloadsStorage};
     use crate::entity::EntityId;
     use crate::guidance::Guidance;
     use crate::ticks::Ticks;
@@ -112,59 +297,63 @@ mod tests {
     use rand::prelude::*;
     use std::num::NonZeroU32;
 
+    fn random_contact(mut rng: impl Rng) -> Contact {
+        let entity_type: Option<EntityType> = rng
+            .gen_bool(0.5)
+            .then(|| EntityType::iter().choose(&mut rng).unwrap());
+        let is_boat = entity_type.map_or(false, |t| t.data().kind == EntityKind::Boat);
+
+        Contact::new(
+            Altitude::from_u8(rng.gen()),
+            Ticks::from_secs(rng.gen::<f32>() * 10.0),
+            entity_type,
+            Guidance {
+                direction_target: rng.gen(),
+                velocity_target: Velocity::from_mps(rng.gen::<f32>() * 3.0),
+            },
+            EntityId::new(rng.gen_range(1..u32::MAX)).unwrap(),
+            rng.gen_bool(0.5)
+                .then(|| PlayerId(NonZeroU32::new(rng.gen_range(1..u32::MAX)).unwrap())),
+            (is_boat && rng.gen_bool(0.5)).then(|| {
+                let mut arr = BitArray::<ReloadsStorage>::ZERO;
+                for (_, mut r) in entity_type
+                    .unwrap()
+                    .data()
+                    .armaments
+                    .iter()
+                    .zip(arr.iter_mut())
+                {
+                    *r = rng.gen();
+                }
+                arr
+            }),
+            Transform {
+                position: vec2(
+                    rng.gen::<f32>() * 1000.0 - 500.0,
+                    rng.gen::<f32>() * 1000.0 - 500.0,
+                ),
+                velocity: Velocity::from_mps(rng.gen::<f32>() * 3.0),
+                direction: rng.gen(),
+            },
+            is_boat.then(|| {
+                entity_type
+                    .unwrap()
+                    .data()
+                    .turrets
+                    .iter()
+                    .map(|_| rng.gen())
+                    .collect()
+            }),
+        )
+    }
+
     #[test]
     fn serialize() {
         EntityType::from_str(EntityType::Barrel.as_str()).unwrap();
 
         let mut rng = thread_rng();
         for _ in 0..10000 {
-            let entity_type: Option<EntityType> = rng
-                .gen_bool(0.5)
-                .then(|| EntityType::iter().choose(&mut rng).unwrap());
-            let is_boat = entity_type.map_or(false, |t| t.data().kind == EntityKind::Boat);
-
-            let c = Contact::new(
-                Altitude::from_u8(rng.gen()),
-                Ticks::from_secs(rng.gen::<f32>() * 10.0),
-                entity_type,
-                Guidance {
-                    direction_target: rng.gen(),
-                    velocity_target: Velocity::from_mps(rng.gen::<f32>() * 3.0),
-                },
-                EntityId::new(rng.gen_range(1..u32::MAX)).unwrap(),
-                rng.gen_bool(0.5)
-                    .then(|| PlayerId(NonZeroU32::new(rng.gen_range(1..u32::MAX)).unwrap())),
-                (is_boat && rng.gen_bool(0.5)).then(|| {
-                    let mut arr = BitArray::<ReloadsStorage>::ZERO;
-                    for (_, mut r) in entity_type
-                        .unwrap()
-                        .data()
-                        .armaments
-                        .iter()
-                        .zip(arr.iter_mut())
-                    {
-                        *r = rng.gen();
-                    }
-                    arr
-                }),
-                Transform {
-                    position: vec2(
-                        rng.gen::<f32>() * 1000.0 - 500.0,
-                        rng.gen::<f32>() * 1000.0 - 500.0,
-                    ),
-                    velocity: Velocity::from_mps(rng.gen::<f32>() * 3.0),
-                    direction: rng.gen(),
-                },
-                is_boat.then(|| {
-                    entity_type
-                        .unwrap()
-                        .data()
-                        .turrets
-                        .iter()
-                        .map(|_| rng.gen())
-                        .collect()
-                }),
-            );
+            let c = random_contact(&mut rng);
 
             let options = DefaultOptions::new()
                 .with_fixint_encoding()
@@ -189,4 +378,49 @@ mod tests {
             }
         }
     }
+
+    fn update<C>(contacts: Vec<C>) -> Update<C> {
+        Update {
+            contacts,
+            death_reason: None,
+            score: 42,
+            world_radius: 1000.0,
+            world_target_radius: 1200.0,
+            border_warning: None,
+            bounties: Vec::new().into(),
+            alerts: Alerts::default(),
+            terrain: Vec::new().into(),
+        }
+    }
+
+    #[test]
+    fn encoded_contact() {
+        let mut rng = thread_rng();
+        let contacts: Vec<Contact> = (0..1000).map(|_| random_contact(&mut rng)).collect();
+        let encoded: Vec<EncodedContact> =
+            contacts.iter().cloned().map(EncodedContact::new).collect();
+
+        let bytes = bincode::serialize(&update(contacts.clone())).unwrap();
+        assert_eq!(bytes, bincode::serialize(&update(encoded.clone())).unwrap());
+        assert_eq!(
+            bincode::deserialize::<Update>(&bytes).unwrap().contacts,
+            contacts
+        );
+
+        assert_eq!(
+            serde_json::to_string(&update(contacts)).unwrap(),
+            serde_json::to_string(&update(encoded)).unwrap()
+        );
+    }
+
+    #[test]
+    fn salvo_offsets() {
+        let salvo = Salvo::new(EntitySubKind::Torpedo, vec![0, 1, 2]);
+        assert_eq!(salvo.offset(0), -salvo.offset(2));
+        assert_eq!(salvo.offset(1), Angle::ZERO);
+        assert!(salvo.spread > Angle::ZERO && salvo.spread <= Salvo::max_spread());
+
+        let single = Salvo::new(EntitySubKind::Torpedo, vec![3]);
+        assert_eq!(single.offset(0), Angle::ZERO);
+    }
 }
@@ -264,6 +264,11 @@ fn zero_generator(_: usize, _: usize) -> u8 {
     0
 }
 
+lazy_static! {
+    /// Stands in for chunks that are still being generated in the background.
+    static ref OCEAN_CHUNK: Chunk = Chunk::zero();
+}
+
 /// Terrain stores a bitmap representing the altitude at each pixel in a 2D grid.
 pub struct Terrain {
     chunks: [[Option<Box<Chunk>>; SIZE_CHUNKS]; SIZE_CHUNKS],
@@ -274,6 +279,11 @@ pub struct Terrain {
     /// Guards chunk generation.
     mutex: Mutex<()>,
     generator: Generator,
+    /// If true, missing chunks are requested instead of generated, and treated as ocean until
+    /// they are inserted (see [`Terrain::with_background_generator`]).
+    background: bool,
+    /// Chunks to generate in the background.
+    requested: Mutex<ChunkSet>,
 }
 
 pub struct TerrainMutation {
@@ -360,6 +370,18 @@ impl Terrain {
             updated: ChunkSet::new(),
             mutex: Mutex::new(()),
             generator,
+            background: false,
+            requested: Mutex::new(ChunkSet::new()),
+        }
+    }
+
+    /// Allocates a Terrain whose chunks are generated in the background. Missing chunks, and
+    /// chunks due for regeneration, are requested (see [`Self::take_requested`]) and swapped in
+    /// when ready (see [`Self::insert_generated`]).
+    pub fn with_background_generator(generator: Generator) -> Self {
+        Self {
+            background: true,
+            ..Self::with_generator(generator)
         }
     }
 
@@ -369,7 +391,8 @@ impl Terrain {
         (SIZE / 2) as f32 * SCALE
     }
 
-    /// Returns a mutable reference to a chunk.
+    /// Returns a mutable reference to a chunk, generating it if necessary (even if generation
+    /// would otherwise happen in the background).
     pub fn mut_chunk(&mut self, chunk_id: ChunkId) -> &mut Chunk {
         let chunk = &mut self.chunks[chunk_id.1 as usize][chunk_id.0 as usize];
         if chunk.is_none() {
@@ -382,8 +405,7 @@ impl Terrain {
     #[inline]
     pub fn get_chunk(&self, chunk_id: ChunkId) -> &Chunk {
         unsafe {
-            let ptr: &AtomicPtr<Chunk> =
-                transmute(&self.chunks[chunk_id.1 as usize][chunk_id.0 as usize]);
+            let ptr = self.chunk_ptr(chunk_id);
             if let Some(chunk) = ptr.load(Ordering::Relaxed).as_ref() {
                 return chunk;
             }
@@ -391,20 +413,84 @@ impl Terrain {
         }
     }
 
+    unsafe fn chunk_ptr(&self, chunk_id: ChunkId) -> &AtomicPtr<Chunk> {
+        transmute(&self.chunks[chunk_id.1 as usize][chunk_id.0 as usize])
+    }
+
     #[inline(never)]
     unsafe fn get_chunk_slow(&self, ptr: &AtomicPtr<Chunk>, chunk_id: ChunkId) -> &Chunk {
+        if self.background {
+            // Conservatively treat as ocean (no collisions) until generated.
+            self.request(chunk_id);
+            return &OCEAN_CHUNK;
+        }
+
         let lock = self.mutex.lock().unwrap();
         if let Some(chunk) = ptr.load(Ordering::Acquire).as_ref() {
             return chunk;
         }
 
-        // TODO generate in parallel.
         let chunk = Box::into_raw(Chunk::new(chunk_id, self.generator));
         ptr.store(chunk, Ordering::Release);
         drop(lock);
         chunk.as_ref().unwrap()
     }
 
+    /// Returns true if the chunk is generated, or would be generated on demand.
+    pub fn is_ready(&self, chunk_id: ChunkId) -> bool {
+        !self.background || unsafe { !self.chunk_ptr(chunk_id).load(Ordering::Acquire).is_null() }
+    }
+
+    /// Returns the subset of chunks that are ready, requesting the rest.
+    pub fn ready(&self, chunks: ChunkSet) -> ChunkSet {
+        if !self.background {
+            return chunks;
+        }
+        let mut ready = ChunkSet::new();
+        for chunk_id in chunks.into_iter() {
+            if self.is_ready(chunk_id) {
+                ready.add(chunk_id);
+            } else {
+                self.request(chunk_id);
+            }
+        }
+        ready
+    }
+
+    /// Requests that a chunk be generated in the background, if it isn't ready.
+    pub fn request(&self, chunk_id: ChunkId) {
+        if !self.is_ready(chunk_id) {
+            self.requested.lock().unwrap().add(chunk_id);
+        }
+    }
+
+    /// Requests all chunks in a rect (e.g. ahead of a moving boat), so they are ready before
+    /// they are needed.
+    pub fn prefetch(&self, center: Vec2, dimensions: Vec2) {
+        if self.background {
+            self.ready(ChunkSet::new_rect(center, dimensions));
+        }
+    }
+
+    /// Takes the chunks requested since the last call, to generate in the background.
+    pub fn take_requested(&self) -> ChunkSet {
+        std::mem::take(&mut *self.requested.lock().unwrap())
+    }
+
+    /// Swaps in a chunk generated in the background or, if the chunk already exists, regenerates
+    /// it one step towards the generated chunk. Either way, clients receive the whole chunk at
+    /// once.
+    pub fn insert_generated(&mut self, chunk_id: ChunkId, generated: Box<Chunk>) {
+        let slot = &mut self.chunks[chunk_id.1 as usize][chunk_id.0 as usize];
+        if let Some(chunk) = slot.as_mut() {
+            chunk.regenerate_toward(&generated);
+        } else {
+            *slot = Some(generated);
+        }
+        slot.as_mut().unwrap().update = ChunkUpdate::Complete;
+        self.updated.add(chunk_id);
+    }
+
     /// Applies a terrain update, overwriting relevant terrain pixels.
     pub fn apply_update(&mut self, update: &TerrainUpdate) {
         for (chunk_id, serialized) in update.iter() {
@@ -630,6 +716,12 @@ impl Terrain {
     /// Returns if there is any land (meeting or exceeding threshold) in a square, centered at
     /// center. Useful for determining whether something can spawn.
     pub fn land_in_square(&self, center: Vec2, side_length: f32) -> bool {
+        // Conservatively assume land where terrain isn't generated yet.
+        let chunks = ChunkSet::new_rect(center, Vec2::splat(side_length));
+        if self.ready(chunks.clone()) != chunks {
+            return true;
+        }
+
         let lower_left = Coord::saturating_from_position(center - side_length * 0.5);
         let upper_right = Coord::saturating_from_position(center + side_length * 0.5);
 
@@ -656,6 +748,13 @@ impl Terrain {
         let f_pos = pos.floor();
         let Coord(fx, fy) = Coord::from_scaled_position(f_pos)?;
 
+        // Don't modify terrain that isn't generated yet.
+        if !self.is_ready(ChunkId::from_coord(Coord(fx, fy)))
+            || !self.is_ready(ChunkId::from_coord(Coord(cx, cy)))
+        {
+            return None;
+        }
+
         let fract = pos.sub(f_pos);
 
         // Return if actually changed underlying data.
@@ -719,10 +818,16 @@ impl Terrain {
                     if let Some(next_regen) = chunk.next_regen {
                         if now >= next_regen {
                             let chunk_id = ChunkId(cx as u16, cy as u16);
-                            chunk.regenerate(chunk_id, self.generator); // TODO parallelize
-
-                            chunk.update = ChunkUpdate::Complete;
-                            self.updated.add(chunk_id);
+                            if self.background {
+                                // Regenerated by Terrain::insert_generated.
+                                chunk.next_regen = None;
+                                self.requested.get_mut().unwrap().add(chunk_id);
+                            } else {
+                                chunk.regenerate(chunk_id, self.generator);
+
+                                chunk.update = ChunkUpdate::Complete;
+                                self.updated.add(chunk_id);
+                            }
                         }
                     }
                 }
@@ -880,10 +985,11 @@ impl Chunk {
 
     /// regenerate brings each pixel of the chunk one unit closer to original height.
     pub fn regenerate(&mut self, chunk_id: ChunkId, generator: Generator) {
-        let coord = chunk_id.as_coord();
-        let x_offset = coord.0;
-        let y_offset = coord.1;
+        self.regenerate_toward(&Self::new(chunk_id, generator));
+    }
 
+    /// Like [`Self::regenerate`], given the already-generated original chunk.
+    pub fn regenerate_toward(&mut self, original: &Self) {
         // Whether the regeneration is incomplete (some pixels are still not equal to original values).
         let mut incomplete = false;
 
@@ -891,7 +997,7 @@ impl Chunk {
             for x in 0..CHUNK_SIZE {
                 let coord = Coord(x, y);
                 let height = self.at(coord);
-                let original_height = generator(x + x_offset, y + y_offset) & 0b11110000;
+                let original_height = original.at(coord);
 
                 let new_height = match original_height.cmp(&height) {
                     std::cmp::Ordering::Less => height - 0b10000,
@@ -1180,6 +1286,13 @@ impl ChunkSet {
         *row |= 1 << (index % Self::ROW_SIZE);
     }
 
+    /// Removes a given ChunkId from this set.
+    pub fn remove(&mut self, chunk_id: ChunkId) {
+        let index = chunk_id.as_index();
+        let row = &mut self.data[index >> Self::ROW_SIZE_LOG2];
+        *row &= !(1 << (index % Self::ROW_SIZE));
+    }
+
     /// Iterates all ChunkIds in the set.
     pub fn into_iter(self) -> impl Iterator<Item = ChunkId> {
         (0..Self::DATA_SIZE * Self::ROW_SIZE)
@@ -1399,4 +1512,58 @@ mod tests {
         let chunk2 = Chunk::from_bytes(&bytes);
         assert_eq!(chunk.data, chunk2.data);
     }
+
+    #[test]
+    fn background() {
+        fn generator(x: usize, y: usize) -> u8 {
+            ((x ^ y) as u8) << 4
+        }
+
+        let mut terrain = Terrain::with_background_generator(generator);
+        let chunk_id = ChunkId(1, 2);
+        let coord = chunk_id.as_coord();
+        let mut chunks = ChunkSet::new();
+        chunks.add(chunk_id);
+
+        // Treated as ocean, and not sent to clients, until generated.
+        assert!(!terrain.is_ready(chunk_id));
+        assert_eq!(terrain.at(coord), 0);
+        assert!(terrain.ready(chunks.clone()).is_empty());
+        assert!(terrain.modify(TerrainMutation::simple(Vec2::ZERO, 60.0)).is_none());
+        assert_eq!(terrain.take_requested(), chunks);
+        assert!(terrain.take_requested().is_empty());
+
+        // Swapped in whole.
+        let generated = Chunk::new(chunk_id, generator);
+        let bytes = generated.to_bytes();
+        terrain.insert_generated(chunk_id, generated);
+        assert_eq!(terrain.ready(chunks.clone()), chunks);
+        assert!(terrain.updated.contains(chunk_id));
+        let serialized = terrain
+            .get_chunk(chunk_id)
+            .to_serialized_chunk(true, &terrain, chunk_id);
+        assert!(!serialized.is_update);
+        assert_eq!(&*serialized.bytes, bytes.as_slice());
+
+        // Regenerated in the background, also without tearing.
+        let modified = Coord(coord.0 + 1, coord.1);
+        terrain.set(modified, 0b11110000);
+        terrain.pre_update();
+        terrain.post_update();
+        terrain.mut_chunk(chunk_id).next_regen = Some(Instant::now());
+        terrain.post_update();
+        assert_eq!(terrain.at(modified), 0b11110000);
+        assert_eq!(terrain.take_requested(), chunks);
+
+        terrain.insert_generated(chunk_id, Chunk::new(chunk_id, generator));
+        assert_eq!(terrain.at(modified), 0b11100000);
+        let serialized = terrain
+            .get_chunk(chunk_id)
+            .to_serialized_chunk(true, &terrain, chunk_id);
+        assert!(!serialized.is_update);
+        assert_eq!(
+            &*serialized.bytes,
+            terrain.get_chunk(chunk_id).to_bytes().as_slice()
+        );
+    }
 }
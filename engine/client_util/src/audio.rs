@@ -37,6 +37,8 @@ struct Inner<A: Audio> {
     context: AudioContext,
     sfx_gain: GainNode,
     _music_gain: GainNode,
+    /// Alerts bypass [`Self::sfx_gain`], so they are heard even if the page is hidden.
+    alert_gain: GainNode,
     track: Option<AudioBuffer>,
     /// Audio indexed by [`Audio::index`].
     playing: Box<[Vec<AudioBufferSourceNode>]>,
@@ -56,17 +58,20 @@ struct Inner<A: Audio> {
 impl<A: Audio> Default for AudioPlayer<A> {
     fn default() -> Self {
         if let Ok(context) = web_sys::AudioContext::new() {
-            if let Some((sfx_gain, music_gain)) = web_sys::GainNode::new(&context)
+            if let Some(((sfx_gain, music_gain), alert_gain)) = web_sys::GainNode::new(&context)
                 .ok()
                 .zip(web_sys::GainNode::new(&context).ok())
+                .zip(web_sys::GainNode::new(&context).ok())
             {
                 let _ = sfx_gain.connect_with_audio_node(&context.destination());
                 let _ = music_gain.connect_with_audio_node(&context.destination());
+                let _ = alert_gain.connect_with_audio_node(&context.destination());
 
                 let inner = Rc::new(RefCell::new(Some(Inner {
                     context,
                     sfx_gain,
                     _music_gain: music_gain,
+                    alert_gain,
                     track: None,
                     playing: vec![Vec::new(); std::mem::variant_count::<A>()].into_boxed_slice(),
                     muted_by_game: false,
@@ -130,17 +135,22 @@ impl<A: Audio> AudioPlayer<A> {
 
     /// Plays a particular sound once, with a specified volume.
     pub fn play_with_volume(&self, audio: A, volume: f32) {
-        Inner::play(&self.inner, audio, volume, false);
+        Inner::play(&self.inner, audio, volume, false, false);
     }
 
     /// Plays a particular sound once, with a specified volume and delay in seconds.
     pub fn play_with_volume_and_delay(&self, audio: A, volume: f32, _delay: f32) {
-        Inner::play(&self.inner, audio, volume, false);
+        Inner::play(&self.inner, audio, volume, false, false);
     }
 
     /// Plays a particular sound in a loop.
     pub fn play_looping(&self, audio: A) {
-        Inner::play(&self.inner, audio, 1.0, true);
+        Inner::play(&self.inner, audio, 1.0, true, false);
+    }
+
+    /// Plays a particular sound once, even if the page is hidden, to alert the player.
+    pub fn play_alert(&self, audio: A) {
+        Inner::play(&self.inner, audio, 1.0, false, true);
     }
 
     pub fn is_playing(&self, audio: A) -> bool {
@@ -192,7 +202,15 @@ impl<A: Audio> AudioPlayer<A> {
 
 impl<A: Audio> Inner<A> {
     fn recalculate_volume(&self) -> f32 {
-        if self.muted_by_game || self.muted_by_visibility || self.muted_by_ad {
+        if self.muted_by_visibility {
+            0.0
+        } else {
+            self.recalculate_alert_volume()
+        }
+    }
+
+    fn recalculate_alert_volume(&self) -> f32 {
+        if self.muted_by_game || self.muted_by_ad {
             0.0
         } else {
             self.volume_setting
@@ -200,6 +218,10 @@ impl<A: Audio> Inner<A> {
     }
 
     fn update_volume(&mut self) {
+        self.alert_gain
+            .gain()
+            .set_value(self.recalculate_alert_volume());
+
         let new_volume = self.recalculate_volume();
         if new_volume != self.volume_target {
             self.volume_target = new_volume;
@@ -215,11 +237,16 @@ impl<A: Audio> Inner<A> {
         }
     }
 
-    /// Plays a particular sound, optionally in a loop. This is private, since looping is never
-    /// determined at runtime.
-    fn play(rc: &Rc<RefCell<Option<Self>>>, audio: A, volume: f32, looping: bool) {
+    /// Plays a particular sound, optionally in a loop, and optionally as an alert. This is
+    /// private, since looping is never determined at runtime.
+    fn play(rc: &Rc<RefCell<Option<Self>>>, audio: A, volume: f32, looping: bool, alert: bool) {
         if let Some(inner) = rc.borrow_mut().as_mut() {
-            if inner.recalculate_volume() == 0.0 {
+            let volume_setting = if alert {
+                inner.recalculate_alert_volume()
+            } else {
+                inner.recalculate_volume()
+            };
+            if volume_setting == 0.0 {
                 return;
             }
 
@@ -242,7 +269,11 @@ impl<A: Audio> Inner<A> {
                 gain.gain().set_value(volume);
                 let _ = source.connect_with_audio_node(&gain);
 
-                let _ = gain.connect_with_audio_node(&inner.sfx_gain);
+                let _ = gain.connect_with_audio_node(if alert {
+                    &inner.alert_gain
+                } else {
+                    &inner.sfx_gain
+                });
 
                 if looping {
                     source.set_loop(true);
@@ -11,13 +11,16 @@ use crate::mouse::MouseState;
 use crate::reconn_web_socket::ReconnWebSocket;
 use crate::setting::CommonSettings;
 use crate::visibility::VisibilityState;
-use core_protocol::dto::{LeaderboardDto, LiveboardDto, MessageDto, PlayerDto, ServerDto, TeamDto};
+use core_protocol::dto::{
+    LeaderboardDto, LiveboardDto, MessageDto, PlayerDto, ServerDto, TeamDto, TreasuryDto,
+};
 use core_protocol::id::{CohortId, InvitationId, LoginType, PeriodId, PlayerId, ServerId, TeamId};
 use core_protocol::name::PlayerAlias;
 use core_protocol::rpc::{
     ChatUpdate, ClientRequest, ClientUpdate, InvitationUpdate, LeaderboardUpdate, LiveboardUpdate,
     PlayerUpdate, Request, SystemUpdate, TeamUpdate, Update, WebSocketQuery,
 };
+use core_protocol::web_transport;
 use heapless::HistoryBuffer;
 use std::collections::HashMap;
 use std::marker::PhantomData;
@@ -76,6 +79,8 @@ pub struct CoreState {
     pub members: Box<[PlayerId]>,
     pub joiners: Box<[PlayerId]>,
     pub joins: Box<[TeamId]>,
+    /// Default if not in a team.
+    pub treasury: TreasuryDto,
     /// TODO: Deprecate `pub`
     pub leaderboards: [Box<[LeaderboardDto]>; std::mem::variant_count::<PeriodId>()],
     pub liveboard: Vec<LiveboardDto>,
@@ -282,6 +287,9 @@ impl<G: GameClient> Apply<Update<G::GameUpdate>> for ServerState<G> {
                 TeamUpdate::Joins(joins) => {
                     core.joins = joins;
                 }
+                TeamUpdate::Treasury(treasury) => {
+                    core.treasury = treasury;
+                }
                 TeamUpdate::AddedOrUpdated(added_or_updated) => {
                     for team in added_or_updated.into_vec() {
                         core.teams.insert(team.team_id, team);
@@ -331,6 +339,39 @@ impl<G: GameClient> Context<G> {
         frontend: &dyn Frontend<G::UiProps>,
     ) -> (String, Option<ServerId>) {
         let scheme = ws_protocol(frontend.get_real_encryption().unwrap_or(is_https()));
+        let (host, query, ideal_server_id) =
+            Self::compute_host_and_query(common_settings, override_server_id, frontend);
+        (
+            format!("{}://{}/ws?{}", scheme, host, query),
+            ideal_server_id,
+        )
+    }
+
+    /// Computes the URL of the WebTransport endpoint, which listens on its own port of the server
+    /// that created the session.
+    pub(crate) fn compute_web_transport_url(
+        common_settings: &CommonSettings,
+        port: u16,
+        frontend: &dyn Frontend<G::UiProps>,
+    ) -> String {
+        let (host, query, _) =
+            Self::compute_host_and_query(common_settings, common_settings.server_id, frontend);
+        let hostname = host.split(':').next().unwrap_or(&host);
+        format!(
+            "https://{}:{}{}?{}",
+            hostname,
+            port,
+            web_transport::PATH,
+            query
+        )
+    }
+
+    /// Returns the host, query string, and id of the ideal server to connect to.
+    fn compute_host_and_query(
+        common_settings: &CommonSettings,
+        override_server_id: Option<ServerId>,
+        frontend: &dyn Frontend<G::UiProps>,
+    ) -> (String, String, Option<ServerId>) {
         let ideal_server_id = override_server_id.or(frontend.get_ideal_server_id());
         let host = frontend.get_real_host().unwrap_or_else(host);
 
@@ -357,10 +398,7 @@ impl<G: GameClient> Context<G> {
 
         let web_socket_query_url = serde_urlencoded::to_string(&web_socket_query).unwrap();
 
-        (
-            format!("{}://{}/ws?{}", scheme, ideal_host, web_socket_query_url),
-            ideal_server_id,
-        )
+        (ideal_host, web_socket_query_url, ideal_server_id)
     }
 
     /// Whether the game websocket is closed or errored (not open, opening, or nonexistent).
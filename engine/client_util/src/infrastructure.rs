@@ -9,10 +9,12 @@ use crate::frontend::Frontend;
 use crate::game_client::GameClient;
 use crate::keyboard::{Key, KeyboardEvent as GameClientKeyboardEvent};
 use crate::mouse::{MouseButton, MouseEvent as GameClientMouseEvent};
+use crate::rate_limiter::RateLimiter;
 use crate::reconn_web_socket::ReconnWebSocket;
-use crate::setting::CommonSettings;
+use crate::setting::{CommonSettings, Settings};
 use crate::visibility::VisibilityEvent;
 use common_util::range::map_ranges;
+use core_protocol::dto::SettingsDto;
 use core_protocol::id::{PlayerId, ServerId, TeamId};
 use core_protocol::name::TeamName;
 use core_protocol::rpc::{
@@ -37,6 +39,9 @@ pub struct Infrastructure<G: GameClient> {
     /// screen in a gesture, used to emulate right click.
     right_touch_id: Option<i32>,
     statistic_fps_monitor: FpsMonitor,
+    /// Last known settings stored on the server, if logged in.
+    synced_settings: Option<SettingsDto>,
+    sync_settings_rate_limiter: RateLimiter,
 }
 
 impl<G: GameClient> Infrastructure<G> {
@@ -62,6 +67,8 @@ impl<G: GameClient> Infrastructure<G> {
                 left_touch_id: None,
                 right_touch_id: None,
                 statistic_fps_monitor: FpsMonitor::new(60.0),
+                synced_settings: None,
+                sync_settings_rate_limiter: RateLimiter::new(5.0),
             }),
             Err(e) => Err((
                 e,
@@ -123,12 +130,34 @@ impl<G: GameClient> Infrastructure<G> {
                             .set_session_id(Some(session_id), &mut self.context.browser_storages);
                     }
                 }
+                &Update::Client(ClientUpdate::WebTransportAvailable {
+                    port,
+                    certificate_hash,
+                }) => {
+                    // Sent after the session was created, so the URL can resume it.
+                    let url = Context::<G>::compute_web_transport_url(
+                        &self.context.common_settings,
+                        port,
+                        &*self.context.frontend,
+                    );
+                    self.context.socket.upgrade(&url, certificate_hash);
+                }
                 Update::Client(ClientUpdate::EvalSnippet(snippet)) => {
                     // Do NOT use `eval`, since it runs in the local scope and therefore
                     // prevents minification.
                     let _ = Function::new_no_args(&snippet).call0(&JsValue::NULL);
                     // TODO: send result back to server.
                 }
+                Update::Client(ClientUpdate::SettingsSynced(settings)) => {
+                    self.context
+                        .common_settings
+                        .apply_synced(settings, &mut self.context.browser_storages);
+                    self.context
+                        .settings
+                        .apply_synced(settings, &mut self.context.browser_storages);
+                    self.synced_settings = Some(settings.clone());
+                    self.sync_settings_rate_limiter.fast_track();
+                }
                 _ => {}
             }
 
@@ -144,6 +173,32 @@ impl<G: GameClient> Infrastructure<G> {
             self.context
                 .send_to_server(Request::Client(ClientRequest::TallyFps(fps)));
         }
+
+        self.sync_settings(elapsed_seconds);
+    }
+
+    /// Uploads local settings that are newer than those on the server (only if logged in).
+    fn sync_settings(&mut self, elapsed_seconds: f32) {
+        let synced_settings = match self.synced_settings.as_mut() {
+            Some(synced_settings) => synced_settings,
+            None => return,
+        };
+
+        if !self
+            .sync_settings_rate_limiter
+            .update_ready(elapsed_seconds)
+        {
+            return;
+        }
+
+        let mut fields = CommonSettings::synced(&self.context.browser_storages);
+        fields.extend(G::GameSettings::synced(&self.context.browser_storages));
+        let local = SettingsDto::new(fields);
+
+        if synced_settings.merge(&local) {
+            self.context
+                .send_to_server(Request::Client(ClientRequest::SyncSettings(local)));
+        }
     }
 
     pub fn keyboard(&mut self, event: KeyboardEvent) {
@@ -26,8 +26,10 @@ pub mod joystick;
 pub mod js_util;
 pub mod keyboard;
 pub mod mouse;
+pub mod notification;
 pub mod rate_limiter;
 pub mod reconn_web_socket;
 pub mod setting;
 pub mod visibility;
 pub mod web_socket;
+pub mod web_transport;
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use js_hooks::window;
+use wasm_bindgen::closure::Closure;
+use wasm_bindgen::{JsCast, JsValue};
+use web_sys::{Notification, NotificationOptions, NotificationPermission};
+
+/// Returns true if the browser supports notifications.
+fn notifications_supported() -> bool {
+    js_sys::Reflect::has(&window(), &JsValue::from_str("Notification")).unwrap_or(false)
+}
+
+/// Returns true if the player allowed notifications.
+pub fn notifications_permitted() -> bool {
+    notifications_supported() && Notification::permission() == NotificationPermission::Granted
+}
+
+/// Asks the player to allow notifications, unless they already decided. Must be called in
+/// response to input (e.g. a click).
+pub fn request_notification_permission() {
+    if notifications_supported() && Notification::permission() == NotificationPermission::Default {
+        let _ = Notification::request_permission();
+    }
+}
+
+/// Shows a notification, if permitted, replacing any previous notification with the same `tag`.
+/// Clicking it brings the game to the front.
+pub fn notify(title: &str, body: &str, tag: &str) {
+    if !notifications_permitted() {
+        return;
+    }
+
+    let mut options = NotificationOptions::new();
+    options.body(body).tag(tag);
+
+    if let Ok(notification) = Notification::new_with_options(title, &options) {
+        let onclick = Closure::once_into_js(move |event: JsValue| {
+            let _ = window().focus();
+            if let Some(notification) = event
+                .dyn_into::<web_sys::Event>()
+                .ok()
+                .and_then(|e| e.target())
+                .and_then(|t| t.dyn_into::<Notification>().ok())
+            {
+                notification.close();
+            }
+        });
+        notification.set_onclick(Some(onclick.unchecked_ref()));
+    }
+}
@@ -3,15 +3,72 @@
 
 use crate::apply::Apply;
 use crate::web_socket::{ProtoWebSocket, State};
+use crate::web_transport::ProtoWebTransport;
 use core_protocol::web_socket::WebSocketProtocol;
 use serde::de::DeserializeOwned;
 use serde::Serialize;
 use std::marker::PhantomData;
 
+/// Underlying connection, which may be upgraded from a WebSocket to WebTransport.
+enum Socket<I, O> {
+    WebSocket(ProtoWebSocket<I, O>),
+    WebTransport(ProtoWebTransport<I, O>),
+}
+
+impl<I, O> Socket<I, O>
+where
+    I: 'static + DeserializeOwned,
+    O: 'static + Serialize,
+{
+    fn state(&self) -> State {
+        match self {
+            Self::WebSocket(web_socket) => web_socket.state(),
+            Self::WebTransport(web_transport) => web_transport.state(),
+        }
+    }
+
+    fn has_updates(&self) -> bool {
+        match self {
+            Self::WebSocket(web_socket) => web_socket.has_updates(),
+            Self::WebTransport(web_transport) => web_transport.has_updates(),
+        }
+    }
+
+    fn receive_updates(&mut self) -> Vec<I> {
+        match self {
+            Self::WebSocket(web_socket) => web_socket.receive_updates(),
+            Self::WebTransport(web_transport) => web_transport.receive_updates(),
+        }
+    }
+
+    fn send(&mut self, msg: O) {
+        match self {
+            Self::WebSocket(web_socket) => web_socket.send(msg),
+            Self::WebTransport(web_transport) => web_transport.send(msg),
+        }
+    }
+}
+
+impl<I, O> Socket<I, O> {
+    fn close(&mut self) {
+        match self {
+            Self::WebSocket(web_socket) => web_socket.close(),
+            Self::WebTransport(web_transport) => web_transport.close(),
+        }
+    }
+}
+
 /// Reconnectable WebSocket (generic over inbound, outbound, and state).
 /// Old state is preserved after closing, but cleared when a new connection is reopened.
+///
+/// May be upgraded to WebTransport, and falls back to a WebSocket if WebTransport fails.
 pub struct ReconnWebSocket<I, O, S> {
-    inner: ProtoWebSocket<I, O>,
+    inner: Socket<I, O>,
+    protocol: WebSocketProtocol,
+    /// WebTransport that will replace `inner` once it opens.
+    upgrade: Option<ProtoWebTransport<I, O>>,
+    /// WebTransport failed before, so don't try it again.
+    web_transport_failed: bool,
     host: String,
     /// Tracks whether the socket was closed, so the state can be cleared as soon as it is reopened.
     was_closed: bool,
@@ -39,7 +96,10 @@ where
         }
 
         Self {
-            inner,
+            inner: Socket::WebSocket(inner),
+            protocol,
+            upgrade: None,
+            web_transport_failed: false,
             preamble,
             host,
             was_closed: false,
@@ -51,12 +111,12 @@ where
 
     /// Returns whether the underlying connection is closed (for any reason).
     pub fn is_closed(&self) -> bool {
-        self.inner.is_closed()
+        matches!(self.inner.state(), State::Closed | State::Error)
     }
 
     /// Returns whether the underlying connection is open.
     pub fn is_open(&self) -> bool {
-        self.inner.is_open()
+        self.inner.state() == State::Open
     }
 
     pub fn is_reconnecting(&self) -> bool {
@@ -68,7 +128,7 @@ where
     /// exhausted.
     pub fn is_terminated(&self) -> bool {
         self.inner.state() == State::Closed
-            || (self.inner.is_error() && self.tries >= Self::MAX_TRIES)
+            || (self.inner.state() == State::Error && self.tries >= Self::MAX_TRIES)
     }
 
     /// Takes the current time, and returns a collection of updates to apply to the current
@@ -76,6 +136,28 @@ where
     ///
     /// TODO: Until further notice, it is the caller's responsibility to apply the state changes.
     pub fn update(&mut self, state: &mut S, time_seconds: f32) -> Vec<I> {
+        if let Some(upgrade) = self.upgrade.as_ref() {
+            match upgrade.state() {
+                State::Opening => {}
+                State::Open => {
+                    // The server replaces the WebSocket with the WebTransport, and resends
+                    // everything, so state from the WebSocket must be cleared.
+                    let upgrade = self.upgrade.take().unwrap();
+                    if !self.is_closed() {
+                        self.inner.close();
+                    }
+                    self.inner = Socket::WebTransport(upgrade);
+                    self.was_closed = false;
+                    state.reset();
+                }
+                State::Error | State::Closed => {
+                    // Keep using the WebSocket.
+                    self.upgrade = None;
+                    self.web_transport_failed = true;
+                }
+            }
+        }
+
         if self.is_closed() {
             self.was_closed = true;
         } else if self.was_closed && self.is_open() && self.inner.has_updates() {
@@ -98,9 +180,26 @@ where
         self.preamble = Some(preamble);
     }
 
-    /// Sets the format that will be used to send subsequent messages.
+    /// Sets the format that will be used to send subsequent messages. WebTransport always uses
+    /// [`WebSocketProtocol::Binary`].
     pub fn set_protocol(&mut self, protocol: WebSocketProtocol) {
-        self.inner.set_protocol(protocol);
+        self.protocol = protocol;
+        if let Socket::WebSocket(web_socket) = &mut self.inner {
+            web_socket.set_protocol(protocol);
+        }
+    }
+
+    /// Starts connecting to the same server via WebTransport at `url`, switching to it once it
+    /// opens. Does nothing if WebTransport is unsupported, in use, or failed before.
+    pub fn upgrade(&mut self, url: &str, certificate_hash: Option<[u8; 32]>) {
+        if self.upgrade.is_none()
+            && !self.web_transport_failed
+            && self.protocol == WebSocketProtocol::Binary
+            && matches!(self.inner, Socket::WebSocket(_))
+            && ProtoWebTransport::<I, O>::is_supported()
+        {
+            self.upgrade = Some(ProtoWebTransport::new(url, certificate_hash));
+        }
     }
 
     /// Sends a message, or queues it for sending when the underlying connection is open.
@@ -117,9 +216,12 @@ where
             self.next_try = time_seconds + Self::SECONDS_PER_TRY * 0.5;
         } else if time_seconds < self.next_try {
             // Wait...
-        } else if self.inner.is_error() && self.tries < Self::MAX_TRIES {
-            // Try again.
-            self.inner = ProtoWebSocket::new(&self.host, self.inner.protocol());
+        } else if self.inner.state() == State::Error && self.tries < Self::MAX_TRIES {
+            // Try again, falling back to a WebSocket.
+            if matches!(self.inner, Socket::WebTransport(_)) {
+                self.web_transport_failed = true;
+            }
+            self.inner = Socket::WebSocket(ProtoWebSocket::new(&self.host, self.protocol));
             if let Some(p) = self.preamble.as_ref() {
                 self.inner.send(p.clone());
             }
@@ -140,5 +242,8 @@ where
 impl<I, O, S> Drop for ReconnWebSocket<I, O, S> {
     fn drop(&mut self) {
         self.inner.close();
+        if let Some(upgrade) = self.upgrade.as_mut() {
+            upgrade.close();
+        }
     }
 }
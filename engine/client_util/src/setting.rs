@@ -3,6 +3,7 @@
 
 use crate::browser_storage::BrowserStorages;
 use crate::js_util::is_mobile;
+use core_protocol::dto::{SettingDto, SettingsDto};
 use core_protocol::id::{ArenaId, CohortId, LanguageId, ServerId, SessionId};
 use core_protocol::name::PlayerAlias;
 use core_protocol::web_socket::WebSocketProtocol;
@@ -12,6 +13,14 @@ pub use engine_macros::Settings;
 pub trait Settings: Sized {
     /// Loads all settings from local storage.
     fn load(l: &BrowserStorages, default: Self) -> Self;
+
+    /// Gets settings that are synchronized with the server, enabled by `#[setting(sync)]`.
+    fn synced(_: &BrowserStorages) -> Vec<SettingDto> {
+        Vec::new()
+    }
+
+    /// Applies synchronized settings that were changed more recently than their local values.
+    fn apply_synced(&mut self, _: &SettingsDto, _: &mut BrowserStorages) {}
 }
 
 // Useful if you don't want settings.
@@ -21,6 +30,7 @@ impl Settings for () {
 
 /// Settings of the infrastructure, common to all games.
 #[derive(Clone, PartialEq, Settings)]
+#[setting(sync)]
 pub struct CommonSettings {
     /// Alias preference.
     #[setting(optional)]
@@ -31,19 +41,20 @@ pub struct CommonSettings {
     #[setting(range = "0.0..1.0", finite)]
     pub volume: f32,
     /// Last [`CohortId`].
-    #[setting(optional)]
+    #[setting(optional, no_sync)]
     pub cohort_id: Option<CohortId>,
     /// Last-used/chosen [`ServerId`].
     #[setting(optional, volatile)]
     pub server_id: Option<ServerId>,
     /// Not manually set by the player.
-    #[setting(optional)]
+    #[setting(optional, no_sync)]
     pub arena_id: Option<ArenaId>,
     /// Not manually set by the player. Not accessible via arbitrary getter/setter as doing so would
     /// pull BigUint64Array into the JS shim, breaking compatibility with old devices.
-    #[setting(optional)]
+    #[setting(optional, no_sync)]
     pub session_id: Option<SessionId>,
-    /// Whether to set antialias rendering option.
+    /// Whether to set antialias rendering option. Depends on the device, so not synced.
+    #[setting(no_sync)]
     pub antialias: bool,
     /// Websocket protocol.
     #[setting(volatile)]
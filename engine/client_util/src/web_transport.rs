@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::web_socket::State;
+use core_protocol::web_transport::{is_newer, write_frame, FrameReader, Reassembler, Sequence};
+use js_hooks::{console_error, window};
+use js_sys::{Array, Reflect, Uint8Array};
+use serde::de::DeserializeOwned;
+use serde::Serialize;
+use std::cell::RefCell;
+use std::convert::TryInto;
+use std::rc::Rc;
+use wasm_bindgen::{JsCast, JsValue};
+use wasm_bindgen_futures::{spawn_local, JsFuture};
+use web_sys::{
+    ReadableStream, ReadableStreamDefaultReader, WebTransport, WebTransportBidirectionalStream,
+    WebTransportHash, WebTransportOptions, WritableStreamDefaultWriter,
+};
+
+/// Same limit as the server.
+const MAX_MESSAGE_SIZE: usize = 32768;
+
+struct ProtoWebTransportInner<I, O> {
+    state: State,
+    /// Only available in State::Open.
+    writer: Option<WritableStreamDefaultWriter>,
+    /// Only used in State::Opening.
+    outbound_buffer: Vec<O>,
+    inbound_buffer: Vec<I>,
+    /// Newest update received, which makes older updates that arrive as datagrams obsolete.
+    newest: Option<Sequence>,
+}
+
+/// WebTransport that obeys the same protocol as [`crate::web_socket::ProtoWebSocket`], except
+/// that it always uses bincode.
+pub struct ProtoWebTransport<I, O> {
+    transport: Option<WebTransport>,
+    inner: Rc<RefCell<ProtoWebTransportInner<I, O>>>,
+}
+
+impl<I, O> ProtoWebTransport<I, O>
+where
+    I: 'static + DeserializeOwned,
+    O: 'static + Serialize,
+{
+    /// Returns true if the browser supports WebTransport.
+    pub fn is_supported() -> bool {
+        Reflect::has(&window(), &JsValue::from_str("WebTransport")).unwrap_or(false)
+    }
+
+    /// Opens a new WebTransport session. `certificate_hash` is the SHA-256 of the server's
+    /// certificate, if it is self-signed.
+    pub fn new(url: &str, certificate_hash: Option<[u8; 32]>) -> Self {
+        let inner = Rc::new(RefCell::new(ProtoWebTransportInner {
+            state: State::Opening,
+            writer: None,
+            outbound_buffer: Vec::new(),
+            inbound_buffer: Vec::new(),
+            newest: None,
+        }));
+
+        let mut options = WebTransportOptions::new();
+        if let Some(certificate_hash) = certificate_hash {
+            let mut hash = WebTransportHash::new();
+            hash.algorithm("sha-256")
+                .value(&Uint8Array::from(certificate_hash.as_slice()));
+            options.server_certificate_hashes(&Array::of1(&hash));
+        }
+
+        let transport = match WebTransport::new_with_options(url, &options) {
+            Ok(transport) => transport,
+            Err(e) => {
+                console_error!("could not open web transport: {:?}", e);
+                inner.borrow_mut().state = State::Error;
+                return Self {
+                    transport: None,
+                    inner,
+                };
+            }
+        };
+
+        let inner_copy = inner.clone();
+        let transport_copy = transport.clone();
+        spawn_local(async move {
+            let state = match Self::run(&transport_copy, &inner_copy).await {
+                // The server finished the stream.
+                Ok(()) => State::Closed,
+                Err(_) => State::Error,
+            };
+            let mut inner = inner_copy.borrow_mut();
+            if inner.state != State::Closed {
+                inner.state = state;
+            }
+            inner.writer = None;
+        });
+
+        Self {
+            transport: Some(transport),
+            inner,
+        }
+    }
+
+    /// Opens the stream, then reads from it until it ends.
+    async fn run(
+        transport: &WebTransport,
+        inner: &Rc<RefCell<ProtoWebTransportInner<I, O>>>,
+    ) -> Result<(), JsValue> {
+        JsFuture::from(transport.ready()).await?;
+        let stream: WebTransportBidirectionalStream =
+            JsFuture::from(transport.create_bidirectional_stream())
+                .await?
+                .unchecked_into();
+        let writer = stream.writable().get_writer()?;
+
+        // The server only learns of the stream once something is written to it.
+        let mut hello = Vec::new();
+        write_frame(&mut hello, &[]);
+        JsFuture::from(writer.write_with_chunk(&Uint8Array::from(hello.as_slice()))).await?;
+
+        {
+            let mut inner = inner.borrow_mut();
+            if inner.state != State::Opening {
+                // Closed in the meantime.
+                return Ok(());
+            }
+            inner.state = State::Open;
+            for outbound in std::mem::take(&mut inner.outbound_buffer) {
+                Self::do_send(&writer, outbound);
+            }
+            inner.writer = Some(writer);
+        }
+
+        let datagram_inner = inner.clone();
+        let datagrams = transport.datagrams().readable();
+        spawn_local(async move {
+            let mut reassembler = Reassembler::default();
+            let _ = read_chunks(datagrams, |datagram| {
+                if let Some((sequence, message)) = reassembler.push(datagram) {
+                    let mut inner = datagram_inner.borrow_mut();
+                    if inner
+                        .newest
+                        .map_or(true, |newest| is_newer(sequence, newest))
+                    {
+                        inner.newest = Some(sequence);
+                        Self::receive(&mut inner, &message);
+                    }
+                }
+                true
+            })
+            .await;
+        });
+
+        let mut reader = FrameReader::new(MAX_MESSAGE_SIZE + 2);
+        read_chunks(stream.readable(), |bytes| {
+            reader.extend(bytes);
+            let mut inner = inner.borrow_mut();
+            loop {
+                match reader.next_frame() {
+                    Ok(Some(frame)) if frame.len() >= 2 => {
+                        // Updates on the stream are always delivered, but still supersede older
+                        // datagrams.
+                        let sequence = Sequence::from_le_bytes(frame[0..2].try_into().unwrap());
+                        if inner
+                            .newest
+                            .map_or(true, |newest| is_newer(sequence, newest))
+                        {
+                            inner.newest = Some(sequence);
+                        }
+                        Self::receive(&mut inner, &frame[2..]);
+                    }
+                    Ok(None) => return inner.state != State::Closed,
+                    _ => {
+                        console_error!("invalid web transport frame");
+                        inner.state = State::Closed;
+                        return false;
+                    }
+                }
+            }
+        })
+        .await
+    }
+
+    fn receive(inner: &mut ProtoWebTransportInner<I, O>, message: &[u8]) {
+        match bincode::deserialize(message) {
+            Ok(update) => inner.inbound_buffer.push(update),
+            Err(e) => {
+                console_error!("error decoding web transport data: {}", e);
+                // Mark as closed without actually closing, like the WebSocket.
+                inner.state = State::Closed;
+            }
+        }
+    }
+
+    /// Gets current (cached) state.
+    pub fn state(&self) -> State {
+        self.inner.borrow().state
+    }
+
+    /// Returns whether `receive_updates` would return a non-empty `Vec`.
+    pub fn has_updates(&self) -> bool {
+        !self.inner.borrow().inbound_buffer.is_empty()
+    }
+
+    /// Gets buffered updates.
+    pub fn receive_updates(&mut self) -> Vec<I> {
+        std::mem::take(&mut self.inner.borrow_mut().inbound_buffer)
+    }
+
+    /// Send a message or buffer it if the stream is still opening.
+    pub fn send(&mut self, msg: O) {
+        let mut inner = self.inner.borrow_mut();
+        match (inner.state, inner.writer.as_ref()) {
+            (State::Opening, _) => inner.outbound_buffer.push(msg),
+            (State::Open, Some(writer)) => Self::do_send(writer, msg),
+            _ => console_error!("cannot send on closed web transport."),
+        }
+    }
+
+    /// Sends a message or drop it on error.
+    fn do_send(writer: &WritableStreamDefaultWriter, msg: O) {
+        let mut frame = Vec::new();
+        write_frame(&mut frame, &bincode::serialize(&msg).unwrap());
+        // Writes are queued in order, so there is no need to wait for each.
+        let _ = writer.write_with_chunk(&Uint8Array::from(frame.as_slice()));
+    }
+}
+
+impl<I, O> ProtoWebTransport<I, O> {
+    pub fn close(&mut self) {
+        let mut inner = self.inner.borrow_mut();
+        if matches!(inner.state, State::Opening | State::Open) {
+            inner.state = State::Closed;
+            inner.writer = None;
+            drop(inner);
+            if let Some(transport) = self.transport.as_ref() {
+                transport.close();
+            }
+        }
+    }
+}
+
+/// Calls `on_chunk` with each chunk read, until the stream ends or `on_chunk` returns false.
+async fn read_chunks(
+    readable: ReadableStream,
+    mut on_chunk: impl FnMut(&[u8]) -> bool,
+) -> Result<(), JsValue> {
+    let reader: ReadableStreamDefaultReader = readable.get_reader().unchecked_into();
+    loop {
+        let result = JsFuture::from(reader.read()).await?;
+        if Reflect::get(&result, &JsValue::from_str("done"))?
+            .as_bool()
+            .unwrap_or(true)
+        {
+            return Ok(());
+        }
+        let chunk: Uint8Array =
+            Reflect::get(&result, &JsValue::from_str("value"))?.unchecked_into();
+        if !on_chunk(&chunk.to_vec()) {
+            let _ = reader.cancel();
+            return Ok(());
+        }
+    }
+}
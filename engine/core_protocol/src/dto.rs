@@ -141,6 +141,136 @@ impl Ord for ServerDto {
     }
 }
 
+/// The Settings Data Transfer Object (DTO) is a versioned blob of settings that are synchronized
+/// between devices of a logged-in user.
+#[derive(Clone, Debug, Default, Eq, PartialEq, Serialize, Deserialize)]
+pub struct SettingsDto {
+    pub version: u16,
+    /// Sorted by name, with no duplicates.
+    pub fields: Vec<SettingDto>,
+}
+
+/// A single synchronized setting, serialized the same way as in browser storage.
+#[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize)]
+pub struct SettingDto {
+    pub name: String,
+    /// `None` if the (optional) setting was cleared.
+    pub value: Option<String>,
+    /// When the setting was last changed, for last-write-wins merging.
+    pub modified: UnixTime,
+}
+
+impl SettingsDto {
+    /// Increment when the meaning of existing fields changes. Blobs with a different version are
+    /// discarded instead of merged.
+    pub const VERSION: u16 = 1;
+
+    pub fn new(mut fields: Vec<SettingDto>) -> Self {
+        fields.sort_unstable_by(|a, b| a.name.cmp(&b.name));
+        fields.dedup_by(|a, b| a.name == b.name);
+        Self {
+            version: Self::VERSION,
+            fields,
+        }
+    }
+
+    /// Merges other settings into self, keeping whichever value of each field was written last.
+    /// Returns whether self changed.
+    pub fn merge(&mut self, other: &Self) -> bool {
+        if other.version != Self::VERSION {
+            return false;
+        }
+        if self.version != Self::VERSION {
+            *self = other.clone();
+            return true;
+        }
+
+        let mut changed = false;
+        for field in &other.fields {
+            match self.fields.binary_search_by(|f| f.name.cmp(&field.name)) {
+                Ok(index) => {
+                    let existing = &mut self.fields[index];
+                    if field.modified > existing.modified {
+                        *existing = field.clone();
+                        changed = true;
+                    }
+                }
+                Err(index) => {
+                    self.fields.insert(index, field.clone());
+                    changed = true;
+                }
+            }
+        }
+        changed
+    }
+}
+
+#[cfg(test)]
+mod settings_test {
+    use crate::dto::{SettingDto, SettingsDto};
+
+    fn field(name: &str, value: &str, modified: u64) -> SettingDto {
+        SettingDto {
+            name: name.to_owned(),
+            value: Some(value.to_owned()),
+            modified,
+        }
+    }
+
+    fn cleared(name: &str, modified: u64) -> SettingDto {
+        SettingDto {
+            name: name.to_owned(),
+            value: None,
+            modified,
+        }
+    }
+
+    #[test]
+    fn last_write_wins() {
+        let mut server = SettingsDto::new(vec![
+            field("volume", "0.5", 10),
+            field("shadows", "soft", 30),
+        ]);
+        let client = SettingsDto::new(vec![
+            field("volume", "0.8", 20),
+            field("shadows", "none", 5),
+        ]);
+
+        assert!(server.merge(&client));
+        assert_eq!(
+            server,
+            SettingsDto::new(vec![
+                field("shadows", "soft", 30),
+                field("volume", "0.8", 20)
+            ])
+        );
+        assert!(!server.merge(&client));
+    }
+
+    #[test]
+    fn clear_wins() {
+        let mut server = SettingsDto::new(vec![field("alias", "Bob", 10)]);
+        let client = SettingsDto::new(vec![cleared("alias", 20)]);
+
+        assert!(server.merge(&client));
+        assert_eq!(server, client);
+
+        let stale = SettingsDto::new(vec![field("alias", "Bob", 15)]);
+        assert!(!server.merge(&stale));
+        assert_eq!(server.fields[0].value, None);
+    }
+
+    #[test]
+    fn version_mismatch() {
+        let mut server = SettingsDto::new(vec![field("volume", "0.5", 10)]);
+        let mut client = SettingsDto::new(vec![field("volume", "0.8", 20)]);
+        client.version = SettingsDto::VERSION + 1;
+
+        assert!(!server.merge(&client));
+        assert_eq!(server.fields[0].value.as_deref(), Some("0.5"));
+    }
+}
+
 #[derive(Clone, Debug, Eq, PartialEq, Serialize, Deserialize)]
 pub struct SnippetDto {
     pub cohort_id: Option<CohortId>,
@@ -175,6 +305,25 @@ pub struct TeamDto {
     pub closed: bool,
 }
 
+/// The Treasury Data Transfer Object (DTO) is a team's shared funds, for its members only.
+#[derive(Clone, Debug, Default, PartialEq, Serialize, Deserialize)]
+pub struct TreasuryDto {
+    pub balance: u32,
+    /// Percentage of earnings members contribute automatically.
+    pub share: u8,
+    /// Members, besides the captain, who may grant funds.
+    pub officers: Box<[PlayerId]>,
+    /// Most recent transactions, oldest first.
+    pub history: Box<[TreasuryTransactionDto]>,
+}
+
+/// A single entry in a team treasury's history.
+#[derive(Copy, Clone, Debug, PartialEq, Serialize, Deserialize)]
+pub enum TreasuryTransactionDto {
+    Contributed { player_id: PlayerId, amount: u32 },
+    Granted { player_id: PlayerId, amount: u32 },
+}
+
 /// Filter daily metrics.
 // TODO: Not a DTO?
 #[derive(Copy, Clone, Debug, Serialize, Deserialize)]
@@ -263,11 +412,13 @@ mod admin {
         pub fps: <ContinuousExtremaMetric as Metric>::Summary,
         pub invited: <RatioMetric as Metric>::Summary,
         pub invitations_cached: <DiscreteMetric as Metric>::Summary,
+        pub load_shedding: <ContinuousExtremaMetric as Metric>::Summary,
         pub low_fps: <RatioMetric as Metric>::Summary,
         pub minutes_per_play: <ContinuousExtremaMetric as Metric>::Summary,
         pub minutes_per_visit: <ContinuousExtremaMetric as Metric>::Summary,
         pub new: <RatioMetric as Metric>::Summary,
         pub no_referrer: <RatioMetric as Metric>::Summary,
+        pub over_budget: <RatioMetric as Metric>::Summary,
         pub peek: <RatioMetric as Metric>::Summary,
         pub players_cached: <DiscreteMetric as Metric>::Summary,
         pub plays_per_visit: <ContinuousExtremaMetric as Metric>::Summary,
@@ -305,11 +456,13 @@ mod admin {
         pub fps: <ContinuousExtremaMetric as Metric>::DataPoint,
         pub invited: <RatioMetric as Metric>::DataPoint,
         pub invitations_cached: <DiscreteMetric as Metric>::DataPoint,
+        pub load_shedding: <ContinuousExtremaMetric as Metric>::DataPoint,
         pub low_fps: <RatioMetric as Metric>::DataPoint,
         pub minutes_per_play: <ContinuousExtremaMetric as Metric>::DataPoint,
         pub minutes_per_visit: <ContinuousExtremaMetric as Metric>::DataPoint,
         pub new: <RatioMetric as Metric>::DataPoint,
         pub no_referrer: <RatioMetric as Metric>::DataPoint,
+        pub over_budget: <RatioMetric as Metric>::DataPoint,
         pub peek: <RatioMetric as Metric>::DataPoint,
         pub players_cached: <DiscreteMetric as Metric>::DataPoint,
         pub plays_per_visit: <ContinuousExtremaMetric as Metric>::DataPoint,
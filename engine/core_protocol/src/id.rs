@@ -175,6 +175,20 @@ impl LanguageId {
     pub fn iter() -> impl Iterator<Item = Self> + 'static {
         <Self as IntoEnumIterator>::iter()
     }
+
+    /// Whether the language is written right-to-left, in which case the UI is mirrored.
+    pub fn is_right_to_left(self) -> bool {
+        matches!(self, Self::Arabic)
+    }
+
+    /// Value of the HTML `dir` attribute for the language.
+    pub fn direction(self) -> &'static str {
+        if self.is_right_to_left() {
+            "rtl"
+        } else {
+            "ltr"
+        }
+    }
 }
 
 impl Default for LanguageId {
@@ -15,6 +15,7 @@ pub mod name;
 pub mod rpc;
 pub mod serde_util;
 pub mod web_socket;
+pub mod web_transport;
 
 pub type UnixTime = u64;
 
@@ -90,6 +90,9 @@ pub struct StatusResponse {
     #[serde(default)]
     #[serde(skip_serializing_if = "Option::is_none")]
     pub player_count: Option<u32>,
+    /// If true, this server is overloaded and refusing new sessions.
+    #[serde(default)]
+    pub busy: bool,
     /// Dying servers, in need of DNS replacement, according to this server.
     #[serde(default)]
     #[serde(skip_serializing_if = "Vec::is_empty")]
@@ -163,12 +166,20 @@ pub enum Update<GU> {
 #[derive(Clone, Debug, Serialize, Deserialize)]
 pub enum TeamRequest {
     Accept(PlayerId),
+    /// Contribute this much score to the team treasury.
+    Contribute(u32),
     Create(TeamName),
+    /// Grant this much score from the team treasury to a member. For the captain and officers only.
+    Grant(PlayerId, u32),
     Join(TeamId),
     Kick(PlayerId),
     Leave,
     Promote(PlayerId),
     Reject(PlayerId),
+    /// Appoint or dismiss an officer. For the captain only.
+    SetOfficer(PlayerId, bool),
+    /// Set the percentage of earnings members contribute automatically. For the captain only.
+    SetShare(u8),
 }
 
 /// Team related update from server to client.
@@ -176,7 +187,9 @@ pub enum TeamRequest {
 pub enum TeamUpdate {
     Accepted(PlayerId),
     AddedOrUpdated(Owned<[TeamDto]>),
+    Contributed(u32),
     Created(TeamId, TeamName),
+    Granted(PlayerId, u32),
     /// A complete enumeration of joiners, for the team captain only.
     Joiners(Box<[PlayerId]>),
     Joining(TeamId),
@@ -186,9 +199,13 @@ pub enum TeamUpdate {
     Left,
     /// A complete enumeration of team members, in order (first is captain).
     Members(Owned<[PlayerId]>),
+    OfficerSet(PlayerId, bool),
     Promoted(PlayerId),
     Rejected(PlayerId),
     Removed(Owned<[TeamId]>),
+    ShareSet(u8),
+    /// The team's treasury, for members only (default if not in a team).
+    Treasury(TreasuryDto),
 }
 
 /// Chat related request from client to server.
@@ -280,6 +297,8 @@ pub enum ClientRequest {
     Trace {
         message: String,
     },
+    /// Upload settings of a logged-in user, to be merged with those stored on the server.
+    SyncSettings(SettingsDto),
 }
 
 #[derive(Clone, Debug, Serialize, Deserialize)]
@@ -303,7 +322,16 @@ pub enum ClientUpdate {
         session_id: SessionId,
         player_id: PlayerId,
     },
+    /// Merged settings of a logged-in user. Sent after the session is created, and in response
+    /// to [`ClientRequest::SyncSettings`].
+    SettingsSynced(SettingsDto),
     Traced,
+    /// The server also accepts WebTransport on this (UDP) port. Sent after the session is created.
+    WebTransportAvailable {
+        port: u16,
+        /// Hash of the server's self-signed certificate, if its certificate isn't trusted.
+        certificate_hash: Option<[u8; 32]>,
+    },
 }
 
 /// General update from server to client.
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+//! Framing for WebTransport, which carries the same messages as the WebSocket, but may send
+//! updates that are safe to lose as unreliable datagrams.
+//!
+//! The client opens one bidirectional stream, and writes an empty frame to announce it. Requests
+//! and reliable updates are length prefixed frames on that stream. Unreliable updates are split
+//! into datagrams. Every update carries a sequence number, so the client can discard updates that
+//! arrive after newer ones.
+
+use std::convert::TryInto;
+
+/// The path of the WebTransport endpoint.
+pub const PATH: &str = "/wt";
+
+/// Numbers updates in the order they were sent (wraps around).
+pub type Sequence = u16;
+
+/// Returns true if `a` was sent after `b`, accounting for wrapping.
+pub fn is_newer(a: Sequence, b: Sequence) -> bool {
+    (a.wrapping_sub(b) as i16) > 0
+}
+
+/// Bytes preceding each fragment in a datagram: sequence (u16), index (u8), and count (u8).
+const HEADER_SIZE: usize = 4;
+
+/// Messages that would take more datagrams than this must be sent on the stream.
+pub const MAX_FRAGMENTS: usize = 16;
+
+/// Bytes preceding each frame on a stream.
+const LENGTH_SIZE: usize = 4;
+
+/// Splits a message into datagrams no larger than `max_datagram_size`, or returns `None` if it
+/// would take more than `MAX_FRAGMENTS` datagrams.
+pub fn fragment(
+    sequence: Sequence,
+    message: &[u8],
+    max_datagram_size: usize,
+) -> Option<Vec<Vec<u8>>> {
+    let fragment_size = max_datagram_size
+        .checked_sub(HEADER_SIZE)
+        .filter(|&s| s > 0)?;
+    let count = ((message.len() + fragment_size - 1) / fragment_size).max(1);
+    if count > MAX_FRAGMENTS {
+        return None;
+    }
+
+    let mut datagrams = Vec::with_capacity(count);
+    for index in 0..count {
+        let start = index * fragment_size;
+        let end = (start + fragment_size).min(message.len());
+        let mut datagram = Vec::with_capacity(HEADER_SIZE + end - start);
+        datagram.extend_from_slice(&sequence.to_le_bytes());
+        datagram.push(index as u8);
+        datagram.push(count as u8);
+        datagram.extend_from_slice(&message[start..end]);
+        datagrams.push(datagram);
+    }
+    Some(datagrams)
+}
+
+/// Reassembles messages from datagrams, giving up on a message as soon as a fragment of a newer
+/// one arrives.
+#[derive(Default)]
+pub struct Reassembler {
+    sequence: Option<Sequence>,
+    fragments: Vec<Option<Vec<u8>>>,
+    /// Newest message that was completed.
+    completed: Option<Sequence>,
+}
+
+impl Reassembler {
+    /// Returns a complete message, along with its sequence, once all of its fragments arrived.
+    /// Malformed, duplicate, and outdated datagrams are ignored.
+    pub fn push(&mut self, datagram: &[u8]) -> Option<(Sequence, Vec<u8>)> {
+        if datagram.len() < HEADER_SIZE {
+            return None;
+        }
+        let sequence = Sequence::from_le_bytes(datagram[0..2].try_into().unwrap());
+        let index = datagram[2] as usize;
+        let count = datagram[3] as usize;
+        if index >= count || count > MAX_FRAGMENTS {
+            return None;
+        }
+        if self.completed.map_or(false, |c| !is_newer(sequence, c)) {
+            return None;
+        }
+
+        if self.sequence != Some(sequence) {
+            if self.sequence.map_or(false, |s| is_newer(s, sequence)) {
+                // Fragment of a message older than the one being reassembled.
+                return None;
+            }
+            self.sequence = Some(sequence);
+            self.fragments.clear();
+            self.fragments.resize(count, None);
+        }
+        if self.fragments.len() != count {
+            return None;
+        }
+
+        self.fragments[index] = Some(datagram[HEADER_SIZE..].to_vec());
+        if self.fragments.iter().any(Option::is_none) {
+            return None;
+        }
+
+        let message = self.fragments.drain(..).flatten().flatten().collect();
+        self.sequence = None;
+        self.completed = Some(sequence);
+        Some((sequence, message))
+    }
+}
+
+/// Appends a length prefixed frame to `buffer`. An empty frame carries no message.
+pub fn write_frame(buffer: &mut Vec<u8>, payload: &[u8]) {
+    buffer.extend_from_slice(&(payload.len() as u32).to_le_bytes());
+    buffer.extend_from_slice(payload);
+}
+
+/// Splits a stream of bytes back into frames.
+pub struct FrameReader {
+    buffer: Vec<u8>,
+    max_frame_size: usize,
+}
+
+impl FrameReader {
+    /// Frames larger than `max_frame_size` are an error.
+    pub fn new(max_frame_size: usize) -> Self {
+        Self {
+            buffer: Vec::new(),
+            max_frame_size,
+        }
+    }
+
+    /// Call with bytes as they are read from the stream.
+    pub fn extend(&mut self, bytes: &[u8]) {
+        self.buffer.extend_from_slice(bytes);
+    }
+
+    /// Takes the next complete frame, if any.
+    pub fn next_frame(&mut self) -> Result<Option<Vec<u8>>, &'static str> {
+        if self.buffer.len() < LENGTH_SIZE {
+            return Ok(None);
+        }
+        let length = u32::from_le_bytes(self.buffer[0..LENGTH_SIZE].try_into().unwrap()) as usize;
+        if length > self.max_frame_size {
+            return Err("frame too large");
+        }
+        if self.buffer.len() < LENGTH_SIZE + length {
+            return Ok(None);
+        }
+        let frame = self.buffer[LENGTH_SIZE..LENGTH_SIZE + length].to_vec();
+        self.buffer.drain(..LENGTH_SIZE + length);
+        Ok(Some(frame))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::web_transport::{
+        fragment, is_newer, write_frame, FrameReader, Reassembler, MAX_FRAGMENTS,
+    };
+
+    #[test]
+    fn newer() {
+        assert!(is_newer(1, 0));
+        assert!(!is_newer(0, 1));
+        assert!(!is_newer(5, 5));
+        assert!(is_newer(2, u16::MAX));
+    }
+
+    #[test]
+    fn reassemble() {
+        let message: Vec<u8> = (0..100).collect();
+        let mut datagrams = fragment(7, &message, 34).unwrap();
+        assert_eq!(datagrams.len(), 4);
+        assert!(datagrams.iter().all(|d| d.len() <= 34));
+
+        // Out of order.
+        datagrams.reverse();
+        let mut reassembler = Reassembler::default();
+        let (last, rest) = datagrams.split_last().unwrap();
+        for datagram in rest {
+            assert_eq!(reassembler.push(datagram), None);
+        }
+        assert_eq!(reassembler.push(last), Some((7, message)));
+
+        // Duplicate.
+        assert_eq!(reassembler.push(last), None);
+
+        // Too many fragments.
+        assert_eq!(fragment(8, &[0; 30 * MAX_FRAGMENTS + 1], 34), None);
+    }
+
+    #[test]
+    fn reassemble_drops_outdated() {
+        let old = fragment(1, &[1; 20], 14).unwrap();
+        let new = fragment(2, &[2; 5], 14).unwrap();
+
+        let mut reassembler = Reassembler::default();
+        assert_eq!(reassembler.push(&old[0]), None);
+        assert_eq!(reassembler.push(&new[0]), Some((2, vec![2; 5])));
+        assert_eq!(reassembler.push(&old[1]), None);
+    }
+
+    #[test]
+    fn frames() {
+        let mut buffer = Vec::new();
+        write_frame(&mut buffer, &[]);
+        write_frame(&mut buffer, &[1, 2, 3]);
+
+        let mut reader = FrameReader::new(16);
+        reader.extend(&buffer[..5]);
+        assert_eq!(reader.next_frame(), Ok(Some(vec![])));
+        assert_eq!(reader.next_frame(), Ok(None));
+        reader.extend(&buffer[5..]);
+        assert_eq!(reader.next_frame(), Ok(Some(vec![1, 2, 3])));
+        assert_eq!(reader.next_frame(), Ok(None));
+
+        let mut buffer = Vec::new();
+        write_frame(&mut buffer, &[0; 17]);
+        reader.extend(&buffer);
+        assert!(reader.next_frame().is_err());
+    }
+}
@@ -11,13 +11,34 @@ use syn::{
 };
 
 pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
-    let DeriveInput { ident, data, .. } = parse_macro_input!(input);
+    let DeriveInput {
+        ident, data, attrs, ..
+    } = parse_macro_input!(input);
+
+    // Opt-in to synchronizing settings with the server, for logged-in users.
+    let mut sync = false;
+    for attribute in attrs.into_iter().filter(|a| a.path.is_ident("setting")) {
+        let meta = attribute.parse_meta().expect("couldn't parse as meta");
+        if let Meta::List(MetaList { nested, .. }) = meta {
+            for meta in nested {
+                match meta {
+                    NestedMeta::Meta(Meta::Path(path)) if path.is_ident("sync") => sync = true,
+                    _ => panic!("Expected sync"),
+                }
+            }
+        } else {
+            panic!("Expected a list");
+        }
+    }
+
     if let Data::Struct(DataStruct { fields, .. }) = data {
         if let Fields::Named(FieldsNamed { named, .. }) = fields {
             let mut loaders = Vec::with_capacity(named.len());
             let mut getters = Vec::with_capacity(named.len());
             let mut setters = Vec::with_capacity(named.len());
             let mut validators = Vec::with_capacity(named.len());
+            let mut serializers = Vec::new();
+            let mut appliers = Vec::new();
 
             for Field {
                 ident, ty, attrs, ..
@@ -31,6 +52,7 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
 
                 let mut storage = quote! { local };
                 let mut optional = false;
+                let mut synced = sync;
                 let mut validations = Vec::new();
 
                 for attribute in attrs.into_iter().filter(|a| a.path.is_ident("setting")) {
@@ -64,8 +86,12 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
                                         optional = true;
                                     } else if path.is_ident("volatile") {
                                         storage = quote! { session };
+                                        synced = false;
                                     } else if path.is_ident("no_store") {
                                         storage = quote! { no_op };
+                                        synced = false;
+                                    } else if path.is_ident("no_sync") {
+                                        synced = false;
                                     } else {
                                         panic!("Unexpected path: {}", path.get_ident().unwrap());
                                     }
@@ -100,11 +126,22 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
                     }
                 };
 
+                // When the setting was last written, for last-write-wins merging.
+                let modified_string = format!("{}Modified", ident_string);
+                let touch = if synced {
+                    quote! {
+                        let _ = browser_storages.local.set(#modified_string, Some(js_sys::Date::now() as u64));
+                    }
+                } else {
+                    quote! {}
+                };
+
                 let setter = if optional {
                     quote! {
                         pub fn #setter_name(&mut self, value: #ty, browser_storages: &mut BrowserStorages) {
                             self.#ident = value.clone();
                             let _ = browser_storages.#storage.set(#ident_string, value);
+                            #touch
                         }
                     }
                 } else {
@@ -113,11 +150,56 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
                             if let Some(valid) = Self::#validator_name(value) {
                                 self.#ident = valid.clone();
                                 let _ = browser_storages.#storage.set(#ident_string, Some(valid));
+                                #touch
                             }
                         }
                     }
                 };
 
+                if synced {
+                    serializers.push(quote! {
+                        let value = browser_storages.local.get::<String>(#ident_string);
+                        let modified = browser_storages.local.get(#modified_string).unwrap_or(0);
+                        // A cleared optional setting has no value, but was still modified.
+                        if value.is_some() || modified != 0 {
+                            fields.push(core_protocol::dto::SettingDto {
+                                name: #ident_string.to_owned(),
+                                value,
+                                modified,
+                            });
+                        }
+                    });
+
+                    let apply = if optional {
+                        quote! {
+                            self.#setter_name(field.value.as_deref().and_then(|value| std::str::FromStr::from_str(value).ok()), browser_storages);
+                        }
+                    } else {
+                        quote! {
+                            match field.value.as_deref().map(<#ty as std::str::FromStr>::from_str) {
+                                Some(Ok(value)) => self.#setter_name(value, browser_storages),
+                                _ => continue,
+                            }
+                        }
+                    };
+
+                    appliers.push(quote! {
+                        #ident_string => {
+                            let modified: u64 = browser_storages.local.get(#modified_string).unwrap_or(0);
+                            if field.modified > modified {
+                                #apply
+                                let _ = browser_storages.local.set(#modified_string, Some(field.modified));
+                            } else if field.modified < modified
+                                && browser_storages.local.get::<String>(#ident_string) == field.value
+                            {
+                                // The server clamped a timestamp from a clock that runs ahead. Adopt
+                                // it, or the same value would be uploaded again forever.
+                                let _ = browser_storages.local.set(#modified_string, Some(field.modified));
+                            }
+                        }
+                    });
+                }
+
                 if !optional {
                     let validator = quote! {
                         fn #validator_name(value: #ty) -> Option<#ty> {
@@ -134,6 +216,30 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
                 setters.push(setter);
             }
 
+            let sync_methods = if sync {
+                quote! {
+                    fn synced(browser_storages: &BrowserStorages) -> Vec<core_protocol::dto::SettingDto> {
+                        let mut fields = Vec::new();
+                        #(#serializers)*
+                        fields
+                    }
+
+                    fn apply_synced(&mut self, settings: &core_protocol::dto::SettingsDto, browser_storages: &mut BrowserStorages) {
+                        if settings.version != core_protocol::dto::SettingsDto::VERSION {
+                            return;
+                        }
+                        for field in &settings.fields {
+                            match field.name.as_str() {
+                                #(#appliers)*
+                                _ => {}
+                            }
+                        }
+                    }
+                }
+            } else {
+                quote! {}
+            };
+
             let output = quote! {
                 impl Settings for #ident {
                     fn load(browser_storages: &BrowserStorages, default: Self) -> Self {
@@ -141,6 +247,8 @@ pub(crate) fn derive_settings(input: TokenStream) -> TokenStream {
                             #(#loaders)*
                         }
                     }
+
+                    #sync_methods
                 }
 
                 impl #ident {
@@ -2,10 +2,80 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::game_service::{Bot, BotAction, GameArenaService};
+use crate::load_shedding::LoadShedding;
 use crate::player::{PlayerData, PlayerRepo, PlayerTuple};
-use core_protocol::id::PlayerId;
+use crate::team::TeamRepo;
+use core_protocol::id::{PlayerId, RegionId};
+use core_protocol::{get_unix_time_now, UnixTime};
+use log::warn;
 use maybe_parallel_iterator::IntoMaybeParallelRefMutIterator;
+use std::convert::TryInto;
 use std::sync::Arc;
+use std::time::{Duration, Instant};
+
+/// Multiplier of the bot population, by hour of local time.
+#[derive(Clone, Debug, PartialEq)]
+pub struct PopulationSchedule {
+    hourly: [f32; 24],
+    /// Offset of local time from UTC, in hours.
+    utc_offset_hours: i64,
+}
+
+impl PopulationSchedule {
+    /// Fewer real players are online at night, so more bots keep the game lively, whereas real
+    /// players fill the game in the evening.
+    pub const DEFAULT_HOURLY: [f32; 24] = [
+        1.3, 1.3, 1.3, 1.3, 1.3, 1.3, // 00:00 to 05:59
+        1.15, 1.15, 1.15, // 06:00 to 08:59
+        1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0, // 09:00 to 16:59
+        0.85, 0.85, 0.85, 0.85, 0.85, 0.85, // 17:00 to 22:59
+        1.0,  // 23:00 to 23:59
+    ];
+
+    /// Creates a schedule from options, defaulting to `DEFAULT_HOURLY` and the time where most
+    /// players in `region_id` live.
+    pub fn new_from_options(
+        hourly: Option<Vec<f32>>,
+        utc_offset_hours: Option<i8>,
+        region_id: Option<RegionId>,
+    ) -> Self {
+        let hourly = hourly
+            .and_then(|hourly| {
+                let len = hourly.len();
+                let ret = hourly.try_into().ok();
+                if ret.is_none() {
+                    warn!("population schedule has {} hours instead of 24", len);
+                }
+                ret
+            })
+            .unwrap_or(Self::DEFAULT_HOURLY);
+        let utc_offset_hours = utc_offset_hours
+            .map(i64::from)
+            .unwrap_or_else(|| region_id.map_or(0, Self::region_utc_offset_hours));
+        Self {
+            hourly,
+            utc_offset_hours,
+        }
+    }
+
+    /// Returns the multiplier of the bot population at `unix_time`.
+    pub fn factor(&self, unix_time: UnixTime) -> f32 {
+        let hour = ((unix_time / 3_600_000) as i64 + self.utc_offset_hours).rem_euclid(24);
+        self.hourly[hour as usize]
+    }
+
+    /// Approximate UTC offset, in hours, of where most players in a region live.
+    fn region_utc_offset_hours(region_id: RegionId) -> i64 {
+        match region_id {
+            RegionId::Africa => 2,
+            RegionId::Asia => 8,
+            RegionId::Europe => 1,
+            RegionId::NorthAmerica => -6,
+            RegionId::Oceania => 10,
+            RegionId::SouthAmerica => -3,
+        }
+    }
+}
 
 /// Data stored per bot.
 pub struct BotData<G: GameArenaService> {
@@ -13,14 +83,17 @@ pub struct BotData<G: GameArenaService> {
     /// Only Some during an update cycle.
     action_buffer: BotAction<G::GameRequest>,
     bot: G::Bot,
+    /// When the bot started retiring, if it is no longer needed. Retiring bots don't respawn.
+    retiring: Option<Instant>,
 }
 
 impl<G: GameArenaService> BotData<G> {
-    pub fn new(player_tuple: PlayerTuple<G>) -> Self {
+    pub fn new(player_tuple: PlayerTuple<G>, bot: G::Bot) -> Self {
         Self {
-            bot: G::Bot::default(),
+            bot,
             player_tuple: Arc::new(player_tuple),
             action_buffer: BotAction::None,
+            retiring: None,
         }
     }
 }
@@ -29,23 +102,45 @@ impl<G: GameArenaService> BotData<G> {
 pub struct BotRepo<G: GameArenaService> {
     /// Collection of bots, indexed corresponding to player id.
     bots: Vec<BotData<G>>,
+    /// Bots hired by the game, e.g. to escort a player, which don't count towards the population.
+    hired: Vec<BotData<G>>,
     /// Minimum number of bots (always less than or equal to max_bots).
     pub(crate) min_bots: usize,
     /// Maximum number of bots.
     max_bots: usize,
     /// This percent of real players will help determine the target bot quantity.
     bot_percent: usize,
+    /// Varies the number of bots by time of day.
+    schedule: PopulationSchedule,
+    /// When a living bot was last removed, to spread out removals.
+    last_retirement: Instant,
 }
 
 impl<G: GameArenaService> BotRepo<G> {
+    /// Living bots that are no longer needed are given this long to die before being removed.
+    const RETIREMENT: Duration = Duration::from_secs(90);
+    /// Minimum time between removing living bots.
+    const RETIREMENT_INTERVAL: Duration = Duration::from_secs(2);
+    /// Hired bots get player ids starting from the nth bot id, so they never collide with the
+    /// population.
+    const HIRED_OFFSET: usize = 1 << 20;
+
     /// Creates a new bot zoo.
-    pub fn new(min_bots: usize, max_bots: usize, bot_percent: usize) -> Self {
+    pub fn new(
+        min_bots: usize,
+        max_bots: usize,
+        bot_percent: usize,
+        schedule: PopulationSchedule,
+    ) -> Self {
         let min_bots = min_bots.min(max_bots);
         Self {
             bots: Vec::with_capacity(min_bots),
+            hired: Vec::new(),
             min_bots,
             max_bots,
             bot_percent,
+            schedule,
+            last_retirement: Instant::now(),
         }
     }
 
@@ -53,68 +148,194 @@ impl<G: GameArenaService> BotRepo<G> {
         min_bots: Option<usize>,
         max_bots: Option<usize>,
         bot_percent: Option<usize>,
+        schedule: PopulationSchedule,
     ) -> Self {
         Self::new(
             min_bots.unwrap_or(G::Bot::DEFAULT_MIN_BOTS),
             max_bots.unwrap_or(G::Bot::DEFAULT_MAX_BOTS),
             bot_percent.unwrap_or(G::Bot::DEFAULT_BOT_PERCENT),
+            schedule,
         )
     }
 
     /// Updates all bots.
-    pub fn update(&mut self, service: &G, players: &PlayerRepo<G>) {
+    pub fn update(&mut self, service: &G, players: &PlayerRepo<G>, teams: &TeamRepo<G>) {
         self.bots
             .maybe_par_iter_mut()
             .with_min_sequential(64)
             .for_each(|bot_data: &mut BotData<G>| {
-                let update = G::Bot::get_input(service, &bot_data.player_tuple, &players);
-                bot_data.action_buffer = bot_data.bot.update(
-                    update,
-                    bot_data.player_tuple.player.borrow().player_id,
-                    players,
-                )
+                if bot_data.retiring.is_some() && !service.is_alive(&bot_data.player_tuple) {
+                    // Wait to be removed instead of respawning.
+                    bot_data.action_buffer = BotAction::None;
+                    return;
+                }
+                Self::update_bot(bot_data, service, players, teams);
             });
+
+        for bot_data in &mut self.hired {
+            Self::update_bot(bot_data, service, players, teams);
+        }
+    }
+
+    fn update_bot(
+        bot_data: &mut BotData<G>,
+        service: &G,
+        players: &PlayerRepo<G>,
+        teams: &TeamRepo<G>,
+    ) {
+        let update = G::Bot::get_input(service, &bot_data.player_tuple, &players);
+        bot_data.action_buffer = bot_data.bot.update(
+            update,
+            bot_data.player_tuple.player.borrow().player_id,
+            players,
+            teams,
+        )
     }
 
     /// Call after `GameService::post_update` to avoid sending commands between `GameService::tick` and it.
-    pub fn post_update(&mut self, service: &mut G, players: &PlayerRepo<G>) {
+    pub fn post_update(
+        &mut self,
+        service: &mut G,
+        players: &mut PlayerRepo<G>,
+        teams: &mut TeamRepo<G>,
+    ) {
         for bot_data in &mut self.bots {
-            match std::mem::take(&mut bot_data.action_buffer) {
-                BotAction::Some(command) => {
-                    let _ = service.player_command(command, &bot_data.player_tuple, players);
-                }
-                BotAction::None => {}
-                BotAction::Quit => {
-                    // Recycle.
-                    service.player_left(&bot_data.player_tuple, players);
-                    let player_id = bot_data.player_tuple.player.borrow().player_id;
-                    *bot_data = Self::bot_data(player_id);
-                    service.player_joined(&bot_data.player_tuple, players);
-                }
-            };
+            if Self::act(bot_data, service, players, teams) {
+                // Recycle.
+                service.player_left(&bot_data.player_tuple, players);
+                let player_id = bot_data.player_tuple.player.borrow().player_id;
+                // The new player data must not inherit a team.
+                teams.cleanup_player(player_id, players);
+                *bot_data = Self::bot_data(player_id, service, players);
+                service.player_joined(&bot_data.player_tuple, players);
+            }
         }
+
+        // Hired bots aren't recycled, but removed for good.
+        self.hired.retain_mut(|bot_data| {
+            if !Self::act(bot_data, service, players, teams) {
+                return true;
+            }
+            service.player_left(&bot_data.player_tuple, players);
+            let player_id = bot_data.player_tuple.player.borrow().player_id;
+            teams.cleanup_player(player_id, players);
+            players.players.remove(&player_id);
+            false
+        });
+    }
+
+    /// Carries out a bot's buffered action, returning true if it quit.
+    fn act(
+        bot_data: &mut BotData<G>,
+        service: &mut G,
+        players: &mut PlayerRepo<G>,
+        teams: &mut TeamRepo<G>,
+    ) -> bool {
+        match std::mem::take(&mut bot_data.action_buffer) {
+            BotAction::Some(command) => {
+                let _ = service.player_command(command, &bot_data.player_tuple, players);
+            }
+            BotAction::Team(request) => {
+                let player_id = bot_data.player_tuple.player.borrow().player_id;
+                let _ = teams.handle_team_request(player_id, request, players);
+            }
+            BotAction::None => {}
+            BotAction::Quit => return true,
+        };
+        false
+    }
+
+    /// Hires a bot for a purpose of the game's choosing, such as escorting a player. It joins
+    /// immediately, and is removed when it quits. Returns None if out of ids.
+    pub fn hire(
+        &mut self,
+        bot: G::Bot,
+        service: &mut G,
+        players: &mut PlayerRepo<G>,
+    ) -> Option<Arc<PlayerTuple<G>>> {
+        let player_id = (Self::HIRED_OFFSET..)
+            .map(PlayerId::nth_bot)
+            .find(|id| id.map_or(true, |id| !players.contains(id)))??;
+        let bot_data = BotData::new(PlayerTuple::new(PlayerData::new(player_id, None)), bot);
+        let player_tuple = Arc::clone(&bot_data.player_tuple);
+        players.insert(player_id, Arc::clone(&player_tuple));
+        service.player_joined(&player_tuple, &*players);
+        self.hired.push(bot_data);
+        Some(player_tuple)
     }
 
-    /// Spawns/despawns bots based on number of (real) player clients.
-    pub fn update_count(&mut self, service: &mut G, players: &mut PlayerRepo<G>) {
-        let count = (self.bot_percent * players.real_players_live / 100)
+    /// Spawns/despawns bots based on the game's desired population, or the number of (real)
+    /// player clients, varying by time of day. Overloaded servers have fewer bots.
+    pub fn update_count(
+        &mut self,
+        service: &mut G,
+        players: &mut PlayerRepo<G>,
+        teams: &mut TeamRepo<G>,
+        load_shedding: LoadShedding,
+    ) {
+        let factor = self.schedule.factor(get_unix_time_now());
+        // Relative to the default, so games that determine their own target count still honor
+        // the bot percent option.
+        let percent_factor = self.bot_percent as f32 / G::Bot::DEFAULT_BOT_PERCENT as f32;
+        let mut count = G::Bot::target_count(service, players, factor * percent_factor)
+            .unwrap_or_else(|| {
+                (self.bot_percent as f32 * 0.01 * factor * players.real_players_live as f32)
+                    as usize
+            })
             .clamp(self.min_bots, self.max_bots);
-        self.set_count(count, service, players);
+        if load_shedding >= LoadShedding::FewerBots {
+            // Even below the minimum.
+            count /= 2;
+        }
+        self.set_count(count, service, players, teams);
     }
 
-    /// Changes number of bots by spawning/despawning.
-    fn set_count(&mut self, count: usize, service: &mut G, players: &mut PlayerRepo<G>) {
+    /// Changes number of bots by spawning/despawning. Excess bots retire gradually, by not
+    /// respawning after they die, or eventually leaving if they survive.
+    fn set_count(
+        &mut self,
+        count: usize,
+        service: &mut G,
+        players: &mut PlayerRepo<G>,
+        teams: &mut TeamRepo<G>,
+    ) {
         // Give server 3 seconds (50 ticks) to create all testing bots.
         let mut governor = 4.max(self.min_bots / 50);
 
-        while count < self.bots.len() && governor > 0 {
-            governor -= 1;
+        let now = Instant::now();
+        for (i, bot_data) in self.bots.iter_mut().enumerate() {
+            if i < count {
+                bot_data.retiring = None;
+            } else if bot_data.retiring.is_none() {
+                bot_data.retiring = Some(now);
+            }
+        }
 
-            if let Some(last) = self.bots.pop() {
-                service.player_left(&last.player_tuple, &*players);
-            } else {
+        // Bots are indexed by player id, so only the last bot can be removed.
+        while let Some(last) = self.bots.last() {
+            if governor == 0 {
                 break;
             }
+            let retired = match last.retiring {
+                Some(since) => {
+                    !service.is_alive(&last.player_tuple)
+                        || (now.duration_since(since) > Self::RETIREMENT
+                            && now.duration_since(self.last_retirement) > Self::RETIREMENT_INTERVAL)
+                }
+                None => false,
+            };
+            if !retired {
+                break;
+            }
+            if service.is_alive(&last.player_tuple) {
+                self.last_retirement = now;
+            }
+            governor -= 1;
+
+            let last = self.bots.pop().unwrap();
+            service.player_left(&last.player_tuple, &*players);
+            let player_id = last.player_tuple.player.borrow().player_id;
+            teams.cleanup_player(player_id, players);
         }
 
         while count > self.bots.len() && governor > 0 {
@@ -122,7 +343,7 @@ impl<G: GameArenaService> BotRepo<G> {
 
             if let Some(next_id) = PlayerId::nth_bot(self.bots.len()) {
                 debug_assert!(next_id.is_bot());
-                let bot = Self::bot_data(next_id);
+                let bot = Self::bot_data(next_id, service, players);
                 // This player will never be forgotten by PlayerRepo.
                 players.insert(next_id, Arc::clone(&bot.player_tuple));
                 service.player_joined(&bot.player_tuple, &*players);
@@ -133,8 +354,46 @@ impl<G: GameArenaService> BotRepo<G> {
         }
     }
 
-    fn bot_data(player_id: PlayerId) -> BotData<G> {
+    fn bot_data(player_id: PlayerId, service: &G, players: &PlayerRepo<G>) -> BotData<G> {
         let player_data = PlayerData::new(player_id, None);
-        BotData::new(PlayerTuple::new(player_data))
+        BotData::new(PlayerTuple::new(player_data), G::Bot::new(service, players))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::bot::PopulationSchedule;
+    use core_protocol::id::RegionId;
+
+    #[test]
+    fn population_factor_by_region() {
+        let factor = |region_id: Option<RegionId>, unix_time| {
+            PopulationSchedule::new_from_options(None, None, region_id).factor(unix_time)
+        };
+        // 2022-01-01 03:00 UTC.
+        let unix_time = 1_641_006_000_000;
+        let europe = factor(Some(RegionId::Europe), unix_time);
+        let asia = factor(Some(RegionId::Asia), unix_time);
+        let north_america = factor(Some(RegionId::NorthAmerica), unix_time);
+        // Night in Europe, afternoon in Asia, evening in North America.
+        assert!(europe > asia, "{} {}", europe, asia);
+        assert!(asia > north_america, "{} {}", asia, north_america);
+        assert_eq!(factor(None, 0), 1.3);
+    }
+
+    #[test]
+    fn population_schedule_options() {
+        let hourly: Vec<f32> = (0..24).map(|hour| hour as f32).collect();
+        let schedule =
+            PopulationSchedule::new_from_options(Some(hourly), Some(-6), Some(RegionId::Europe));
+        // 2022-01-01 03:00 UTC is 21:00 the previous day.
+        assert_eq!(schedule.factor(1_641_006_000_000), 21.0);
+
+        // Invalid schedules are ignored.
+        let schedule = PopulationSchedule::new_from_options(Some(vec![2.0; 23]), None, None);
+        assert_eq!(
+            schedule,
+            PopulationSchedule::new_from_options(None, None, None)
+        );
     }
 }
@@ -7,6 +7,7 @@ use crate::infrastructure::Infrastructure;
 use crate::invitation::{ClientInvitationData, InvitationRepo};
 use crate::leaderboard::LeaderboardRepo;
 use crate::liveboard::LiveboardRepo;
+use crate::load_shedding::LoadShedding;
 use crate::metric::{ClientMetricData, MetricRepo};
 use crate::player::{PlayerData, PlayerRepo, PlayerTuple};
 use crate::system::SystemRepo;
@@ -17,25 +18,27 @@ use actix::{
     Message, ResponseActFuture, WrapFuture,
 };
 use atomic_refcell::AtomicRefCell;
-use core_protocol::dto::{InvitationDto, ServerDto};
+use core_protocol::dto::{InvitationDto, ServerDto, SettingsDto};
 use core_protocol::get_unix_time_now;
 use core_protocol::id::{
-    ArenaId, CohortId, InvitationId, PlayerId, ServerId, SessionId, UserAgentId,
+    ArenaId, CohortId, InvitationId, LoginType, PlayerId, ServerId, SessionId, UserAgentId, UserId,
 };
 use core_protocol::name::{PlayerAlias, Referrer};
 use core_protocol::rpc::{
     AdType, ClientRequest, ClientUpdate, LeaderboardUpdate, LiveboardUpdate, PlayerUpdate, Request,
-    SystemUpdate, TeamUpdate, Update,
+    SystemUpdate, TeamUpdate, Update, WebSocketQuery,
 };
 use futures::stream::FuturesUnordered;
 use log::{error, info, warn};
 use maybe_parallel_iterator::IntoMaybeParallelRefIterator;
 use rust_embed::RustEmbed;
-use server_util::database_schema::SessionItem;
+use server_util::database::Database;
+use server_util::database_schema::{LoginItem, SessionItem, SettingsItem};
 use server_util::generate_id::{generate_id, generate_id_64};
 use server_util::ip_rate_limiter::IpRateLimiter;
 use server_util::observer::{ObserverMessage, ObserverUpdate};
 use server_util::rate_limiter::{RateLimiter, RateLimiterProps};
+use server_util::user_agent::UserAgent;
 use std::borrow::Cow;
 use std::collections::hash_map::Entry;
 use std::collections::{HashMap, HashSet};
@@ -60,6 +63,7 @@ pub struct ClientRepo<G: GameArenaService> {
     prune_rate_limiter: RateLimiter,
     database_rate_limiter: RateLimiter,
     pending_session_write: Vec<SessionItem>,
+    pending_settings_write: Vec<SettingsItem>,
     pub(crate) snippets: HashMap<(Option<CohortId>, Option<Referrer>), Arc<str>>,
     /// Where to log traces to.
     trace_log: Option<Arc<str>>,
@@ -77,6 +81,7 @@ impl<G: GameArenaService> ClientRepo<G> {
             prune_rate_limiter: RateLimiter::new(Duration::from_secs(1), 0),
             database_rate_limiter: RateLimiter::new(Duration::from_secs(30), 0),
             pending_session_write: Vec::new(),
+            pending_settings_write: Vec::new(),
             snippets: Self::load_default_snippets(),
             trace_log: trace_log.map(Into::into),
             _spooky: PhantomData,
@@ -152,6 +157,7 @@ impl<G: GameArenaService> ClientRepo<G> {
         let arena_id = infrastructure.context_service.context.arena_id;
 
         let queue = FuturesUnordered::new();
+        let settings_queue = FuturesUnordered::new();
 
         // Backlog from leaving sessions.
         for pending in infrastructure
@@ -164,6 +170,16 @@ impl<G: GameArenaService> ClientRepo<G> {
             queue.push(infrastructure.database.put_session(pending));
         }
 
+        for pending in infrastructure
+            .context_service
+            .context
+            .clients
+            .pending_settings_write
+            .drain(..)
+        {
+            settings_queue.push(infrastructure.database.put_settings(pending));
+        }
+
         for mut player in infrastructure
             .context_service
             .context
@@ -177,6 +193,9 @@ impl<G: GameArenaService> ClientRepo<G> {
                 {
                     queue.push(infrastructure.database.put_session(session_item))
                 }
+                if let Some(settings_item) = Self::db_settings_item(client) {
+                    settings_queue.push(infrastructure.database.put_settings(settings_item))
+                }
             }
         }
 
@@ -189,6 +208,30 @@ impl<G: GameArenaService> ClientRepo<G> {
             })
             .finish()
             .spawn(ctx);
+
+        settings_queue
+            .into_actor(infrastructure)
+            .map(|result, _, _| {
+                if let Err(e) = result {
+                    error!("error putting settings: {:?}", e);
+                }
+            })
+            .finish()
+            .spawn(ctx);
+    }
+
+    /// If the synchronized settings are dirty with respect to the database, creates a settings
+    /// item to overwrite the database version.
+    fn db_settings_item(client: &mut PlayerClientData<G>) -> Option<SettingsItem> {
+        if !client.settings_dirty {
+            return None;
+        }
+        client.settings_dirty = false;
+        Some(SettingsItem {
+            user_id: client.user_id?,
+            game_id: G::GAME_ID,
+            settings: client.settings.clone()?,
+        })
     }
 
     /// If the session is dirty with respect to the database, creates a session item to overwrite
@@ -271,6 +314,13 @@ impl<G: GameArenaService> ClientRepo<G> {
             }),
         });
 
+        // Logged-in clients merge these with their local settings and upload the result.
+        if let Some(settings) = client.settings.as_ref() {
+            let _ = register_observer.send(ObserverUpdate::Send {
+                message: Update::Client(ClientUpdate::SettingsSynced(settings.clone())),
+            });
+        }
+
         // Don't assume client remembered anything, although it may/should have.
         *client.data.borrow_mut() = G::ClientData::default();
         client.chat.forget_state();
@@ -482,7 +532,7 @@ impl<G: GameArenaService> ClientRepo<G> {
                         }
                     }
 
-                    if let Some((chat_update, (members, joiners, joins))) =
+                    if let Some((chat_update, (members, joiners, joins, treasury))) =
                         player_chat_team_updates.get(&player_id)
                     {
                         if let Some(chat_update) = chat_update {
@@ -515,6 +565,12 @@ impl<G: GameArenaService> ClientRepo<G> {
                                 )),
                             });
                         }
+
+                        if let Some(treasury) = treasury {
+                            let _ = observer.send(ObserverUpdate::Send {
+                                message: Update::Team(TeamUpdate::Treasury(treasury.clone())),
+                            });
+                        }
                     } else {
                         debug_assert!(
                             false,
@@ -614,6 +670,9 @@ impl<G: GameArenaService> ClientRepo<G> {
                                         self.pending_session_write.push(session_item);
                                     }
                                 }
+                                if let Some(settings_item) = Self::db_settings_item(client_data) {
+                                    self.pending_settings_write.push(settings_item);
+                                }
                                 info!("player_id {:?} expired from limbo", player_id);
                                 true
                             }
@@ -792,6 +851,45 @@ impl<G: GameArenaService> ClientRepo<G> {
         }
     }
 
+    /// Merge settings uploaded by a logged-in client with those stored on the server.
+    fn sync_settings(
+        player_id: PlayerId,
+        settings: SettingsDto,
+        players: &PlayerRepo<G>,
+    ) -> Result<ClientUpdate, &'static str> {
+        let mut player = players
+            .borrow_player_mut(player_id)
+            .ok_or("player doesn't exist")?;
+        let client = player
+            .client_mut()
+            .ok_or("only clients can sync settings")?;
+        let stored = client
+            .settings
+            .as_mut()
+            .ok_or("must be logged in to sync settings")?;
+
+        if settings.fields.len() > 64
+            || settings
+                .fields
+                .iter()
+                .any(|f| f.name.len() > 64 || f.value.as_ref().map_or(0, String::len) > 256)
+        {
+            return Err("settings too large");
+        }
+
+        // Don't let a skewed clock win every future merge.
+        let now = get_unix_time_now();
+        let mut settings = settings;
+        for field in &mut settings.fields {
+            field.modified = field.modified.min(now);
+        }
+
+        if stored.merge(&settings) {
+            client.settings_dirty = true;
+        }
+        Ok(ClientUpdate::SettingsSynced(stored.clone()))
+    }
+
     /// Handles an arbitrary [`ClientRequest`].
     fn handle_client_request(
         &mut self,
@@ -805,6 +903,9 @@ impl<G: GameArenaService> ClientRepo<G> {
             ClientRequest::TallyAd(ad_type) => Self::tally_ad(player_id, ad_type, players, metrics),
             ClientRequest::TallyFps(fps) => Self::tally_fps(player_id, fps, players),
             ClientRequest::Trace { message } => self.trace(player_id, message, players),
+            ClientRequest::SyncSettings(settings) => {
+                Self::sync_settings(player_id, settings, players)
+            }
         }
     }
 
@@ -865,6 +966,48 @@ impl<G: GameArenaService> ClientRepo<G> {
     }
 }
 
+/// Looks up (or creates) the user logged in via Discord, and loads their synchronized settings.
+async fn load_user_settings<G: GameArenaService>(
+    database: &Database,
+    discord_id: NonZeroU64,
+) -> Option<(UserId, SettingsDto)> {
+    let user_id = match database
+        .get_login(LoginType::Discord, discord_id.to_string())
+        .await
+    {
+        Ok(Some(login)) => login.user_id,
+        Ok(None) => {
+            let login = LoginItem {
+                login_type: LoginType::Discord,
+                id: discord_id.to_string(),
+                user_id: UserId(generate_id_64()),
+            };
+            let user_id = login.user_id;
+            if let Err(e) = database.put_login(login).await {
+                error!("error putting login: {:?}", e);
+                return None;
+            }
+            user_id
+        }
+        Err(e) => {
+            error!("error getting login: {:?}", e);
+            return None;
+        }
+    };
+
+    // Fail closed, so an unreachable database doesn't result in stored settings being overwritten.
+    match database.get_settings(user_id, G::GAME_ID).await {
+        Ok(Some(item)) if item.settings.version == SettingsDto::VERSION => {
+            Some((user_id, item.settings))
+        }
+        Ok(_) => Some((user_id, SettingsDto::new(Vec::new()))),
+        Err(e) => {
+            error!("error getting settings: {:?}", e);
+            None
+        }
+    }
+}
+
 /// Don't let bad values sneak in.
 fn sanitize_tps(tps: f32) -> Option<f32> {
     tps.is_finite().then_some(tps.clamp(0.0, 144.0))
@@ -881,6 +1024,12 @@ pub struct PlayerClientData<G: GameArenaService> {
     pub(crate) status: ClientStatus<G>,
     /// Discord user id.
     pub(crate) discord_id: Option<NonZeroU64>,
+    /// Persistent user id, if logged in.
+    pub(crate) user_id: Option<UserId>,
+    /// Synchronized settings, if logged in.
+    pub(crate) settings: Option<SettingsDto>,
+    /// Whether settings changed since they were last written to the database.
+    pub(crate) settings_dirty: bool,
     /// Ip address.
     pub(crate) ip_address: IpAddr,
     /// Is moderator for in-game chat?
@@ -935,6 +1084,9 @@ impl<G: GameArenaService> PlayerClientData<G> {
                 expiry: Instant::now() + Duration::from_secs(10),
             },
             discord_id,
+            user_id: None,
+            settings: None,
+            settings_dirty: false,
             ip_address: ip,
             moderator,
             session_item: None,
@@ -1067,6 +1219,24 @@ pub struct Authenticate {
     pub oauth2_code: Option<Oauth2Code>,
 }
 
+impl Authenticate {
+    /// Takes the credentials from the query string of a WebSocket (or WebTransport) request.
+    pub fn new(ip_address: IpAddr, user_agent: Option<&str>, query: WebSocketQuery) -> Self {
+        let login_type = query.login_type;
+        Self {
+            ip_address,
+            user_agent_id: user_agent.map(UserAgent::new).and_then(UserAgent::into_id),
+            referrer: query.referrer,
+            arena_id_session_id: query.arena_id.zip(query.session_id),
+            invitation_id: query.invitation_id,
+            oauth2_code: query
+                .login_id
+                .filter(|id| id.len() <= 2048 && login_type == Some(LoginType::Discord))
+                .map(Oauth2Code::Discord),
+        }
+    }
+}
+
 pub enum Oauth2Code {
     Discord(String),
 }
@@ -1103,6 +1273,13 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                     .map(|p| (msg_session_id, p.player_id))
             });
 
+        if cached_session_id_player_id.is_none()
+            && self.status.load_shedder.level() >= LoadShedding::RefuseSessions
+        {
+            // Existing sessions may reconnect, but new players are redirected elsewhere.
+            return Box::pin(fut::ready(Err("server busy")));
+        }
+
         let arena_id_session_id = msg.arena_id_session_id;
         let oauth2_code = std::mem::take(&mut msg.oauth2_code);
         let database = self.database();
@@ -1125,6 +1302,12 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                     None
                 };
 
+                let user_settings = if let Some(discord_id) = discord_id {
+                    load_user_settings::<G>(database, discord_id).await
+                } else {
+                    None
+                };
+
                 let is_moderator =
                     if let Some((discord_id, discord_bot)) = discord_id.zip(discord_bot) {
                         match discord_bot.is_moderator(discord_id).await {
@@ -1148,11 +1331,11 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                     Result::Ok(None)
                 };
 
-                (discord_id, is_moderator, session_item)
+                (discord_id, is_moderator, session_item, user_settings)
             }
             .into_actor(self)
             .map(
-                move |(discord_id, mut is_moderator, db_result), act, _ctx| {
+                move |(discord_id, mut is_moderator, db_result, user_settings), act, _ctx| {
                     let invitation = msg
                         .invitation_id
                         .and_then(|id| act.invitations.get(id).cloned());
@@ -1217,6 +1400,10 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                                     client.discord_id = Some(discord_id);
                                     client.moderator = is_moderator;
                                 }
+                                if let Some((user_id, settings)) = user_settings {
+                                    client.user_id = Some(user_id);
+                                    client.settings = Some(settings);
+                                }
                             } else {
                                 debug_assert!(
                                     false,
@@ -1225,7 +1412,7 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                             }
                         }
                         Entry::Vacant(vacant) => {
-                            let client = PlayerClientData::new(
+                            let mut client = PlayerClientData::new(
                                 session_id,
                                 client_metric_data,
                                 invitation_dto,
@@ -1233,6 +1420,10 @@ impl<G: GameArenaService> Handler<Authenticate> for Infrastructure<G> {
                                 msg.ip_address,
                                 is_moderator,
                             );
+                            if let Some((user_id, settings)) = user_settings {
+                                client.user_id = Some(user_id);
+                                client.settings = Some(settings);
+                            }
                             let pd = PlayerData::new(player_id, Some(Box::new(client)));
                             let pt = Arc::new(PlayerTuple::new(pd));
                             vacant.insert(pt);
@@ -6,20 +6,26 @@ use crate::chat::ChatRepo;
 use crate::client::ClientRepo;
 use crate::game_service::GameArenaService;
 use crate::liveboard::LiveboardRepo;
+use crate::load_shedding::LoadShedding;
 use crate::player::PlayerRepo;
 use crate::team::TeamRepo;
+use core_protocol::dto::{LiveboardDto, MessageDto};
+use core_protocol::get_unix_time_now;
 use core_protocol::id::ArenaId;
 use server_util::rate_limiter::RateLimiterProps;
+use std::sync::Arc;
 
 /// Things that go along with every instance of a [`GameArenaService`].
 pub struct Context<G: GameArenaService> {
     pub arena_id: ArenaId,
     pub players: PlayerRepo<G>,
     pub(crate) clients: ClientRepo<G>,
-    pub(crate) bots: BotRepo<G>,
+    pub bots: BotRepo<G>,
     pub(crate) chat: ChatRepo<G>,
     pub teams: TeamRepo<G>,
     pub(crate) liveboard: LiveboardRepo<G>,
+    /// Degradations the game should apply, because the server is overloaded.
+    pub load_shedding: LoadShedding,
 }
 
 impl<G: GameArenaService> Context<G> {
@@ -38,6 +44,27 @@ impl<G: GameArenaService> Context<G> {
             teams: TeamRepo::new(),
             chat: ChatRepo::new(chat_log),
             liveboard: LiveboardRepo::new(),
+            load_shedding: LoadShedding::None,
         }
     }
+
+    /// Gets the most recent liveboard, sorted by descending score.
+    pub fn liveboard(&self) -> &[LiveboardDto] {
+        self.liveboard.get()
+    }
+
+    /// Sends a chat message, from the server, to all players.
+    pub fn announce(&mut self, text: String) {
+        let message = MessageDto {
+            alias: G::authority_alias(),
+            date_sent: get_unix_time_now(),
+            player_id: None,
+            team_captain: false,
+            team_name: None,
+            text,
+            whisper: false,
+        };
+        self.chat
+            .broadcast_message(Arc::new(message), &mut self.players);
+    }
 }
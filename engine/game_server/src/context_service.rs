@@ -1,7 +1,7 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-use crate::bot::BotRepo;
+use crate::bot::{BotRepo, PopulationSchedule};
 use crate::context::Context;
 use crate::game_service::GameArenaService;
 use crate::invitation::InvitationRepo;
@@ -24,11 +24,12 @@ impl<G: GameArenaService> ContextService<G> {
         min_bots: Option<usize>,
         max_bots: Option<usize>,
         bot_percent: Option<usize>,
+        population_schedule: PopulationSchedule,
         chat_log: Option<String>,
         trace_log: Option<String>,
         client_authenticate: RateLimiterProps,
     ) -> Self {
-        let bots = BotRepo::new_from_options(min_bots, max_bots, bot_percent);
+        let bots = BotRepo::new_from_options(min_bots, max_bots, bot_percent, population_schedule);
 
         Self {
             service: G::new(bots.min_bots),
@@ -54,9 +55,12 @@ impl<G: GameArenaService> ContextService<G> {
             server_id,
             self.context.arena_id,
         );
-        self.context
-            .bots
-            .update_count(&mut self.service, &mut self.context.players);
+        self.context.bots.update_count(
+            &mut self.service,
+            &mut self.context.players,
+            &mut self.context.teams,
+            self.context.load_shedding,
+        );
 
         // Update game logic.
         self.service.tick(&mut self.context);
@@ -65,6 +69,7 @@ impl<G: GameArenaService> ContextService<G> {
             &mut self.context.teams,
             metrics,
         );
+        self.context.teams.update_treasuries(&self.context.players);
 
         // Update clients and bots.
         self.context.clients.update(
@@ -77,7 +82,7 @@ impl<G: GameArenaService> ContextService<G> {
         );
         self.context
             .bots
-            .update(&self.service, &self.context.players);
+            .update(&self.service, &self.context.players, &self.context.teams);
 
         leaderboard.process(&self.context.liveboard, &self.context.players);
 
@@ -86,8 +91,10 @@ impl<G: GameArenaService> ContextService<G> {
 
         // Bot commands/joining/leaving, postponed because no commands should be issued between
         // `GameService::tick` and `GameService::post_update`.
-        self.context
-            .bots
-            .post_update(&mut self.service, &self.context.players);
+        self.context.bots.post_update(
+            &mut self.service,
+            &mut self.context.players,
+            &mut self.context.teams,
+        );
     }
 }
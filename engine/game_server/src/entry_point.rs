@@ -5,7 +5,8 @@
 //! via web_socket.
 
 use crate::admin::ParameterizedAdminRequest;
-use crate::client::{Authenticate, Oauth2Code};
+use crate::bot::PopulationSchedule;
+use crate::client::Authenticate;
 use crate::discord::{DiscordBotRepo, DiscordOauth2Repo};
 use crate::game_service::GameArenaService;
 use crate::infrastructure::Infrastructure;
@@ -27,7 +28,7 @@ use axum::routing::get;
 use axum::{Json, Router};
 use bincode::{self, Options as _};
 use core_protocol::id::*;
-use core_protocol::rpc::{Request, SystemQuery, Update, WebSocketQuery};
+use core_protocol::rpc::{ClientUpdate, Request, SystemQuery, Update, WebSocketQuery};
 use core_protocol::web_socket::WebSocketProtocol;
 use core_protocol::{get_unix_time_now, UnixTime};
 use futures::pin_mut;
@@ -42,7 +43,6 @@ use server_util::linode::Linode;
 use server_util::observer::{ObserverMessage, ObserverUpdate};
 use server_util::os::set_open_file_limit;
 use server_util::rate_limiter::{RateLimiterProps, RateLimiterState};
-use server_util::user_agent::UserAgent;
 use std::convert::TryInto;
 use std::net::SocketAddr;
 use std::str::FromStr;
@@ -136,6 +136,11 @@ pub fn entry_point<G: GameArenaService>(game_client: MiniCdn, browser_router: bo
                 options.min_bots,
                 options.max_bots,
                 options.bot_percent,
+                PopulationSchedule::new_from_options(
+                    options.population_schedule,
+                    options.utc_offset,
+                    region_id,
+                ),
                 options.chat_log,
                 options.trace_log,
                 Arc::clone(&game_client),
@@ -155,6 +160,19 @@ pub fn entry_point<G: GameArenaService>(game_client: MiniCdn, browser_router: bo
             .as_ref()
             .zip(options.private_key_path.as_ref());
 
+        #[cfg(debug_assertions)]
+        let web_transport_certificate_paths = None;
+        #[cfg(not(debug_assertions))]
+        let web_transport_certificate_paths = certificate_paths.map(|(c, k)| (c.as_str(), k.as_str()));
+
+        let web_transport_offer = if let Some(port) = options.web_transport_port {
+            let (certificate, certificate_hash) = crate::web_transport::load_certificate(web_transport_certificate_paths).await;
+            tokio::spawn(crate::web_transport::serve(srv.to_owned(), port, certificate));
+            Some((port, certificate_hash))
+        } else {
+            None
+        };
+
         let ws_srv = srv.to_owned();
         let admin_srv = srv.to_owned();
         let leaderboard_srv = srv.to_owned();
@@ -204,30 +222,19 @@ pub fn entry_point<G: GameArenaService>(game_client: MiniCdn, browser_router: bo
                     .unwrap())
             }))
             .route("/ws", axum::routing::get(async move |upgrade: WebSocketUpgrade, ConnectInfo(addr): ConnectInfo<SocketAddr>, user_agent: Option<TypedHeader<axum::headers::UserAgent>>, Query(query): Query<WebSocketQuery>| {
-                let user_agent_id = user_agent
-                    .map(|h| UserAgent::new(h.as_str()))
-                    .and_then(UserAgent::into_id);
-                let login_type = query.login_type;
-
-                let authenticate = Authenticate {
-                    ip_address: addr.ip(),
-                    referrer: query.referrer,
-                    user_agent_id,
-                    arena_id_session_id: query.arena_id.zip(query.session_id),
-                    invitation_id: query.invitation_id,
-                    oauth2_code: query.login_id.filter(|id| id.len() <= 2048 && login_type == Some(LoginType::Discord)).map(Oauth2Code::Discord),
-                };
+                let mut protocol = query.protocol.unwrap_or_default();
+                let authenticate = Authenticate::new(addr.ip(), user_agent.as_ref().map(|h| h.as_str()), query);
 
                 const MAX_MESSAGE_SIZE: usize = 32768;
                 const TIMER_SECONDS: u64 = 10;
                 const TIMER_DURATION: Duration = Duration::from_secs(TIMER_SECONDS);
                 const WEBSOCKET_HARD_TIMEOUT: Duration = Duration::from_secs(TIMER_SECONDS * 2);
 
-                let mut protocol = query.protocol.unwrap_or_default();
                 match ws_srv.send(authenticate).await {
                     Err(e) => Err((StatusCode::INTERNAL_SERVER_ERROR, e.to_string()).into_response()),
                     Ok(result) => match result {
-                        // Currently, if authentication fails, it was due to rate limit.
+                        Err("server busy") => Err((StatusCode::SERVICE_UNAVAILABLE, "server busy").into_response()),
+                        // Otherwise, if authentication fails, it was due to rate limit.
                         Err(_) => Err(StatusCode::TOO_MANY_REQUESTS.into_response()),
                         Ok(player_id) => Ok(upgrade
                             .max_frame_size(MAX_MESSAGE_SIZE)
@@ -361,13 +368,24 @@ pub fn entry_point<G: GameArenaService>(game_client: MiniCdn, browser_router: bo
                                                 if !ALLOW_WEB_SOCKET_JSON.load(Ordering::Relaxed) {
                                                     protocol = WebSocketProtocol::Binary;
                                                 }
-                                                let web_socket_message = match protocol {
-                                                    WebSocketProtocol::Binary => Message::Binary(bincode::serialize(&message).unwrap()),
-                                                    WebSocketProtocol::Json => Message::Text(serde_json::to_string(&message).unwrap()),
+
+                                                // Offer WebTransport once the client knows its session.
+                                                let offer = web_transport_offer
+                                                    .filter(|_| matches!(message, Update::Client(ClientUpdate::SessionCreated{..})))
+                                                    .map(|(port, certificate_hash)| Update::<G::GameUpdate>::Client(ClientUpdate::WebTransportAvailable{port, certificate_hash}));
+
+                                                let encode = |message: &Update<G::GameUpdate>| match protocol {
+                                                    WebSocketProtocol::Binary => Message::Binary(bincode::serialize(message).unwrap()),
+                                                    WebSocketProtocol::Json => Message::Text(serde_json::to_string(message).unwrap()),
                                                 };
-                                                if web_socket.send(web_socket_message).await.is_err() {
+                                                if web_socket.send(encode(&message)).await.is_err() {
                                                     break NORMAL_CLOSURE;
                                                 }
+                                                if let Some(offer) = offer {
+                                                    if web_socket.send(encode(&offer)).await.is_err() {
+                                                        break NORMAL_CLOSURE;
+                                                    }
+                                                }
 
                                                 if !measure_rtt_ping_governor.should_limit_rate_with_now(&MEASURE_RTT_PING, last_activity) {
                                                     if web_socket.send(Message::Ping(get_unix_time_now().to_ne_bytes().into())).await.is_err() {
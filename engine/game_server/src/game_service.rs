@@ -3,8 +3,11 @@
 
 use crate::context::Context;
 use crate::player::{PlayerRepo, PlayerTuple};
+use crate::team::TeamRepo;
 use core_protocol::id::{GameId, PlayerId, TeamId};
+use core_protocol::metrics::RatioMetric;
 use core_protocol::name::PlayerAlias;
+use core_protocol::rpc::TeamRequest;
 use serde::de::DeserializeOwned;
 use serde::Serialize;
 use std::fmt::Debug;
@@ -33,6 +36,12 @@ pub trait GameArenaService: 'static + Unpin + Sized + Send + Sync {
     const TEAM_JOINERS_MAX: usize = 6;
     /// Maximum number of teams a player may try to join at once, before old requests are cancelled.
     const TEAM_JOINS_MAX: usize = 3;
+    /// Maximum percentage of earnings a team captain may have members contribute automatically.
+    const TEAM_SHARE_MAX: u8 = 50;
+    /// Maximum score a team member may be granted from the treasury per [`Self::TEAM_GRANT_WINDOW`],
+    /// to prevent boosting.
+    const TEAM_GRANT_MAX: u32 = 1000;
+    const TEAM_GRANT_WINDOW: Duration = Duration::from_secs(5 * 60);
 
     type Bot: 'static + Bot<Self>;
     type ClientData: 'static + Default + Debug + Unpin + Send + Sync;
@@ -112,6 +121,13 @@ pub trait GameArenaService: 'static + Unpin + Sized + Send + Sync {
         _players: &PlayerRepo<Self>,
     ) -> Option<Self::GameUpdate>;
 
+    /// Returns true if the update may be lost without consequence, because the next one supersedes
+    /// it. Such updates may be sent unreliably (e.g. as WebTransport datagrams).
+    fn is_update_lossy(update: &Self::GameUpdate) -> bool {
+        let _ = update;
+        false
+    }
+
     /// Returns true iff the player is considered to be "alive" i.e. they cannot change their alias.
     fn is_alive(&self, player_tuple: &Arc<PlayerTuple<Self>>) -> bool;
     /// Before sending.
@@ -120,6 +136,10 @@ pub trait GameArenaService: 'static + Unpin + Sized + Send + Sync {
     fn post_update(&mut self, context: &mut Context<Self>) {
         let _ = context;
     }
+    /// Returns, and resets, the ratio of entity spawns that exceeded a budget (for metrics).
+    fn take_over_budget(&mut self) -> RatioMetric {
+        RatioMetric::default()
+    }
 }
 
 /// Implemented by game bots.
@@ -135,6 +155,20 @@ pub trait Bot<G: GameArenaService>: Default + Unpin + Sized + Send {
     where
         G: 'a;
 
+    /// Creates a bot, possibly depending on the state of the game (e.g. to complement real
+    /// players).
+    fn new(_game: &G, _players: &PlayerRepo<G>) -> Self {
+        Self::default()
+    }
+
+    /// Returns the desired number of bots, or `None` to use `DEFAULT_BOT_PERCENT` (or the
+    /// corresponding option) of real players. `factor` varies by time of day and by the bot
+    /// percent option relative to `DEFAULT_BOT_PERCENT`, and should scale the result. Either way,
+    /// the result is clamped to the minimum and maximum number of bots.
+    fn target_count(_game: &G, _players: &PlayerRepo<G>, _factor: f32) -> Option<usize> {
+        None
+    }
+
     /// Note that mutable borrowing of the player_tuple is not permitted (will panic).
     fn get_input<'a>(
         game: &'a G,
@@ -148,12 +182,15 @@ pub trait Bot<G: GameArenaService>: Default + Unpin + Sized + Send {
         update: Self::Input<'a>,
         player_id: PlayerId,
         _players: &'a PlayerRepo<G>,
+        _teams: &'a TeamRepo<G>,
     ) -> BotAction<G::GameRequest>;
 }
 
 #[derive(Debug)]
 pub enum BotAction<GR> {
     Some(GR),
+    /// Create, join, leave, or manage a team, as a real player would.
+    Team(TeamRequest),
     None,
     Quit,
 }
@@ -189,6 +226,7 @@ impl Bot<MockGame> for MockGameBot {
         _update: Self::Input<'_>,
         _player_id: PlayerId,
         _players: &PlayerRepo<MockGame>,
+        _teams: &TeamRepo<MockGame>,
     ) -> BotAction<<MockGame as GameArenaService>::GameRequest> {
         BotAction::None
     }
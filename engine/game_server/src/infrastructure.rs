@@ -2,6 +2,7 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::admin::AdminRepo;
+use crate::bot::PopulationSchedule;
 use crate::client::ClientRepo;
 use crate::context_service::ContextService;
 use crate::discord::{DiscordBotRepo, DiscordOauth2Repo};
@@ -88,6 +89,7 @@ impl<G: GameArenaService> Infrastructure<G> {
         min_bots: Option<usize>,
         max_bots: Option<usize>,
         bot_percent: Option<usize>,
+        population_schedule: PopulationSchedule,
         chat_log: Option<String>,
         trace_log: Option<String>,
         game_client: Arc<RwLock<MiniCdn>>,
@@ -115,6 +117,7 @@ impl<G: GameArenaService> Infrastructure<G> {
                 min_bots,
                 max_bots,
                 bot_percent,
+                population_schedule,
                 chat_log,
                 trace_log,
                 client_authenticate,
@@ -139,6 +142,7 @@ impl<G: GameArenaService> Infrastructure<G> {
 
         let status = &self.status;
         let server_delta = self.system.as_mut().and_then(|system| system.delta(status));
+        self.context_service.context.load_shedding = self.status.load_shedder.level();
         self.context_service.update(
             &mut self.leaderboard,
             &mut self.invitations,
@@ -148,6 +152,10 @@ impl<G: GameArenaService> Infrastructure<G> {
         );
         self.leaderboard.clear_deltas();
         self.status.health.record_tick(G::TICK_PERIOD_SECS);
+        let cpu = self.status.health.cpu();
+        self.status
+            .load_shedder
+            .record_tick(now.elapsed(), G::TICK_PERIOD_SECS, cpu, now);
 
         // These are all rate-limited internally.
         LeaderboardRepo::update_to_database(self, ctx);
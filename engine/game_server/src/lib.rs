@@ -27,11 +27,14 @@ pub mod infrastructure;
 pub mod invitation;
 pub mod leaderboard;
 pub mod liveboard;
+pub mod load_shedding;
 pub mod metric;
 pub mod ordered_set;
 pub mod player;
 pub mod status;
 pub mod team;
+pub mod treasury;
+pub mod web_transport;
 #[macro_use]
 pub mod util;
 pub mod discord;
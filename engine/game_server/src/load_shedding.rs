@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use core_protocol::metrics::ContinuousExtremaMetric;
+use log::warn;
+use std::mem;
+use std::time::{Duration, Instant};
+
+/// Degradations, applied cumulatively in order, when the server can't keep up.
+#[derive(Copy, Clone, Debug, Eq, PartialEq, Ord, PartialOrd)]
+pub enum LoadShedding {
+    /// Running normally.
+    None,
+    /// Fewer bots are spawned.
+    FewerBots,
+    /// Static entities (e.g. crates) spawn less densely.
+    SparserStatics,
+    /// Idle or distant clients are updated less often.
+    ThrottleClients,
+    /// Players may have fewer weapons, aircraft, etc. at once.
+    CapEntities,
+    /// New sessions are refused, so `system.json` redirects players elsewhere.
+    RefuseSessions,
+}
+
+impl Default for LoadShedding {
+    fn default() -> Self {
+        Self::None
+    }
+}
+
+impl LoadShedding {
+    fn escalated(self) -> Self {
+        match self {
+            Self::None => Self::FewerBots,
+            Self::FewerBots => Self::SparserStatics,
+            Self::SparserStatics => Self::ThrottleClients,
+            Self::ThrottleClients => Self::CapEntities,
+            Self::CapEntities | Self::RefuseSessions => Self::RefuseSessions,
+        }
+    }
+
+    fn relaxed(self) -> Self {
+        match self {
+            Self::None | Self::FewerBots => Self::None,
+            Self::SparserStatics => Self::FewerBots,
+            Self::ThrottleClients => Self::SparserStatics,
+            Self::CapEntities => Self::ThrottleClients,
+            Self::RefuseSessions => Self::CapEntities,
+        }
+    }
+}
+
+/// Steps through [`LoadShedding`] degradations in response to sustained tick overruns or high
+/// CPU usage, and back again once the server recovers.
+pub struct LoadShedder {
+    level: LoadShedding,
+    /// Smoothed fraction of the tick period spent ticking.
+    utilization: f32,
+    /// When the server started (continuously) being overloaded or underloaded, if it is.
+    overloaded_since: Option<Instant>,
+    underloaded_since: Option<Instant>,
+    /// Level, sampled every tick (for metrics).
+    metric: ContinuousExtremaMetric,
+}
+
+impl Default for LoadShedder {
+    fn default() -> Self {
+        Self {
+            level: LoadShedding::None,
+            utilization: 0.0,
+            overloaded_since: None,
+            underloaded_since: None,
+            metric: ContinuousExtremaMetric::default(),
+        }
+    }
+}
+
+impl LoadShedder {
+    /// Utilization (or CPU usage) above which the server is overloaded.
+    const OVERLOADED: f32 = 0.9;
+    /// Utilization (and CPU usage) below which the server is underloaded.
+    const UNDERLOADED: f32 = 0.6;
+    /// How long the server must be overloaded to shed more load.
+    const ESCALATE_AFTER: Duration = Duration::from_secs(5);
+    /// How long the server must be underloaded to shed less load.
+    const RELAX_AFTER: Duration = Duration::from_secs(30);
+    /// Weight of each tick in the smoothed utilization.
+    const SMOOTHING: f32 = 0.1;
+
+    /// Returns the current degradations.
+    pub fn level(&self) -> LoadShedding {
+        self.level
+    }
+
+    /// Call every tick with how long the tick took, and the (possibly cached) CPU usage.
+    pub fn record_tick(&mut self, elapsed: Duration, tick_period: f32, cpu: f32, now: Instant) {
+        let utilization = elapsed.as_secs_f32() / tick_period;
+        self.utilization += (utilization - self.utilization) * Self::SMOOTHING;
+
+        if self.utilization > Self::OVERLOADED || cpu > Self::OVERLOADED {
+            self.underloaded_since = None;
+            let since = *self.overloaded_since.get_or_insert(now);
+            if now.duration_since(since) >= Self::ESCALATE_AFTER {
+                self.set_level(self.level.escalated());
+            }
+        } else if self.utilization < Self::UNDERLOADED && cpu < Self::UNDERLOADED {
+            self.overloaded_since = None;
+            let since = *self.underloaded_since.get_or_insert(now);
+            if now.duration_since(since) >= Self::RELAX_AFTER {
+                self.set_level(self.level.relaxed());
+            }
+        } else {
+            self.overloaded_since = None;
+            self.underloaded_since = None;
+        }
+
+        self.metric.push(self.level as u8 as f32);
+    }
+
+    /// Changes the level, giving it time to take effect before changing again.
+    fn set_level(&mut self, level: LoadShedding) {
+        if level != self.level {
+            warn!("load shedding changed from {:?} to {:?}", self.level, level);
+            self.level = level;
+        }
+        self.overloaded_since = None;
+        self.underloaded_since = None;
+    }
+
+    /// Takes level measurements.
+    pub fn take_metric(&mut self) -> ContinuousExtremaMetric {
+        mem::take(&mut self.metric)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::load_shedding::{LoadShedder, LoadShedding};
+    use std::time::{Duration, Instant};
+
+    #[test]
+    fn load_shedder() {
+        const PERIOD: f32 = 0.1;
+        let mut shedder = LoadShedder::default();
+        let mut now = Instant::now();
+        let mut tick = |shedder: &mut LoadShedder, secs: f32, cpu: f32, duration: Duration| {
+            let end = now + duration;
+            while now < end {
+                now += Duration::from_secs_f32(PERIOD);
+                shedder.record_tick(Duration::from_secs_f32(secs), PERIOD, cpu, now);
+            }
+        };
+
+        // A brief spike isn't sustained.
+        tick(&mut shedder, 0.5, 0.0, Duration::from_secs(1));
+        assert_eq!(shedder.level(), LoadShedding::None);
+
+        tick(&mut shedder, 0.5, 0.0, Duration::from_secs(10));
+        assert_eq!(shedder.level(), LoadShedding::SparserStatics);
+
+        // High CPU alone is enough.
+        tick(&mut shedder, 0.0, 0.95, Duration::from_secs(30));
+        assert_eq!(shedder.level(), LoadShedding::RefuseSessions);
+
+        // Recovers gradually.
+        tick(&mut shedder, 0.0, 0.0, Duration::from_secs(40));
+        assert_eq!(shedder.level(), LoadShedding::CapEntities);
+        tick(&mut shedder, 0.0, 0.0, Duration::from_secs(5 * 30));
+        assert_eq!(shedder.level(), LoadShedding::None);
+
+        let metric = shedder.take_metric();
+        assert_eq!(metric.max, LoadShedding::RefuseSessions as u8 as f32);
+    }
+}
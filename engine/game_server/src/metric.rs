@@ -427,7 +427,9 @@ impl<G: GameArenaService> MetricRepo<G> {
 
         let context = &mut infrastructure.context_service.context;
         let uptime = infrastructure.status.uptime();
+        let load_shedding = infrastructure.status.load_shedder.take_metric();
         let health = &mut infrastructure.status.health;
+        let over_budget = infrastructure.context_service.service.take_over_budget();
 
         let mut concurrent = Bundle::<u32>::default();
 
@@ -489,6 +491,8 @@ impl<G: GameArenaService> MetricRepo<G> {
             m.connections.push(health.connections() as f32);
             m.tps = m.tps + health.take_tps();
             m.spt = m.spt + health.take_spt();
+            m.over_budget = m.over_budget + over_budget;
+            m.load_shedding = m.load_shedding + load_shedding;
             m.uptime.push(uptime.as_secs_f32() / (24.0 * 60.0 * 60.0));
         };
         // metrics_repo.mutate_all(general);
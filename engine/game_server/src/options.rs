@@ -19,6 +19,13 @@ pub struct Options {
     /// This percent of real players will help determine number of bots.
     #[structopt(long)]
     pub bot_percent: Option<usize>,
+    /// Multiplier of the number of bots by hour of local time, as 24 comma-separated values.
+    #[structopt(long, use_delimiter = true)]
+    pub population_schedule: Option<Vec<f32>>,
+    /// Offset of local time from UTC, in hours (defaults to where most players in the region
+    /// live).
+    #[structopt(long, allow_hyphen_values = true)]
+    pub utc_offset: Option<i8>,
     /// Log incoming HTTP requests
     #[cfg_attr(debug_assertions, structopt(long, default_value = "warn"))]
     #[cfg_attr(not(debug_assertions), structopt(long, default_value = "error"))]
@@ -87,6 +94,9 @@ pub struct Options {
     /// Private key path.
     #[structopt(long)]
     pub private_key_path: Option<String>,
+    /// Also accept WebTransport on this UDP port.
+    #[structopt(long)]
+    pub web_transport_port: Option<u16>,
     /// HTTP request bandwidth limiting (in bytes per second).
     #[structopt(long, default_value = "500000")]
     pub http_bandwidth_limit: u32,
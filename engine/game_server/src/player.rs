@@ -75,6 +75,11 @@ impl<G: GameArenaService> PlayerRepo<G> {
         }
     }
 
+    /// Returns recently computed number of real players (not bots) that were alive recently.
+    pub fn real_players_live(&self) -> usize {
+        self.real_players_live
+    }
+
     /// Returns total number of players (including bots).
     pub fn len(&self) -> usize {
         self.players.len()
@@ -227,11 +232,13 @@ impl<G: GameArenaService> PlayerRepo<G> {
                     real_players += 1;
                 }
 
-                if p.is_bot() || p.is_out_of_game() {
-                    // TODO: Game can optionally allow bots to participate in teams.
+                if p.is_out_of_game() || (p.is_bot() && p.team_id().is_none()) {
+                    // Bots are only relevant to clients if they participate in teams.
                     None
                 } else {
-                    real_players_live += 1;
+                    if !p.is_bot() {
+                        real_players_live += 1;
+                    }
 
                     Some(PlayerDto {
                         alias: p.alias(),
@@ -3,6 +3,7 @@
 
 use crate::game_service::GameArenaService;
 use crate::infrastructure::Infrastructure;
+use crate::load_shedding::{LoadShedder, LoadShedding};
 use actix::{Handler, Message};
 use core_protocol::rpc::StatusResponse;
 use server_util::health::Health;
@@ -11,6 +12,7 @@ use std::time::{Duration, Instant};
 /// Manages updating and reporting of server status.
 pub struct StatusRepo {
     pub(crate) health: Health,
+    pub(crate) load_shedder: LoadShedder,
     uptime: Instant,
     /// Possibly overridden.
     pub(crate) client_hash: u64,
@@ -22,6 +24,7 @@ impl StatusRepo {
     pub fn new(client_hash: u64) -> Self {
         Self {
             health: Health::default(),
+            load_shedder: LoadShedder::default(),
             uptime: Instant::now(),
             client_hash,
             original_client_hash: client_hash,
@@ -50,6 +53,7 @@ impl<G: GameArenaService> Handler<StatusRequest> for Infrastructure<G> {
             client_hash: Some(self.status.client_hash),
             // TODO: In the future, this will sum players for all arenas.
             player_count: Some(self.context_service.context.players.real_players_live as u32),
+            busy: self.status.load_shedder.level() >= LoadShedding::RefuseSessions,
             dying_server_ids: self
                 .system
                 .as_ref()
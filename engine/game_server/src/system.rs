@@ -3,6 +3,7 @@
 
 use crate::game_service::GameArenaService;
 use crate::infrastructure::Infrastructure;
+use crate::load_shedding::LoadShedding;
 use crate::status::StatusRepo;
 use crate::util::diff_small_n;
 use actix::fut::wrap_future;
@@ -113,6 +114,8 @@ pub(crate) struct ServerAdvertisement {
     pub(crate) redirect_server_id: Option<ServerId>,
     pub(crate) client_hash: Option<u64>,
     pub(crate) player_count: Option<u32>,
+    /// Whether the server is refusing new sessions.
+    pub(crate) busy: bool,
 }
 
 impl ServerStatus {
@@ -224,6 +227,7 @@ impl<G: GameArenaService> SystemRepo<G> {
                             redirect_server_id,
                             client_hash,
                             player_count,
+                            busy,
                         },
                     ..
                 } = &server.status
@@ -232,6 +236,7 @@ impl<G: GameArenaService> SystemRepo<G> {
                         .map(|hash| hash == status.client_hash)
                         .unwrap_or(Self::MISSING_HASH_IS_COMPATIBLE)
                         && redirect_server_id.is_none()
+                        && !busy
                     {
                         if let Some((region_id, player_count)) =
                             server.region_id.zip(player_count.or(Some(0)))
@@ -401,6 +406,7 @@ impl<G: GameArenaService> SystemRepo<G> {
                                 redirect_server_id: status.redirect_server_id,
                                 client_hash: status.client_hash,
                                 player_count: status.player_count,
+                                busy: status.busy,
                             };
                             if status.healthy {
                                 info!("watchdog {:?} is healthy", server_id);
@@ -847,6 +853,10 @@ impl<G: GameArenaService> Handler<SystemRequest> for Infrastructure<G> {
         let ideal_region_id = request
             .region_id
             .or_else(|| SystemRepo::<G>::ip_to_region_id(request.ip));
+        // Redirect new players elsewhere, if possible, while refusing new sessions.
+        let busy_server_id = self
+            .server_id
+            .filter(|_| self.status.load_shedder.level() >= LoadShedding::RefuseSessions);
 
         let ideal_server_id = SystemRepo::iter_server_priorities(
             &self.system,
@@ -854,6 +864,7 @@ impl<G: GameArenaService> Handler<SystemRequest> for Infrastructure<G> {
             invitation_server_id,
             ideal_region_id,
         )
+        .filter(|&(server_id, _, _)| Some(server_id) != busy_server_id)
         .min_by_key(|&(_, priority, player_count)| {
             (
                 priority,
@@ -879,8 +890,9 @@ impl<G: GameArenaService> Handler<SystemRequest> for Infrastructure<G> {
                         invitation_server_id,
                         ideal_region_id,
                     )
-                    .filter(|&(_, priority, player_count)| {
-                        priority == ideal_server_priority
+                    .filter(|&(server_id, priority, player_count)| {
+                        Some(server_id) != busy_server_id
+                            && priority == ideal_server_priority
                             && (!use_player_count || player_count == ideal_server_player_count)
                     })
                     .map(|(server_id, _, _)| server_id)
@@ -4,10 +4,11 @@
 use crate::game_service::GameArenaService;
 use crate::ordered_set::OrderedSet;
 use crate::player::{PlayerData, PlayerRepo};
+use crate::treasury::Treasury;
 use crate::unwrap_or_return;
 use crate::util::diff_small_n;
 use atomic_refcell::AtomicRefMut;
-use core_protocol::dto::TeamDto;
+use core_protocol::dto::{TeamDto, TreasuryDto};
 use core_protocol::id::{PlayerId, TeamId};
 use core_protocol::name::TeamName;
 use core_protocol::rpc::{TeamRequest, TeamUpdate};
@@ -16,6 +17,7 @@ use std::collections::hash_map::Entry;
 use std::collections::{HashMap, VecDeque};
 use std::marker::PhantomData;
 use std::sync::Arc;
+use std::time::Instant;
 
 /// Data stored for team.
 pub struct TeamData<G: GameArenaService> {
@@ -24,6 +26,7 @@ pub struct TeamData<G: GameArenaService> {
     pub members: OrderedSet<PlayerId>,
     /// In order of request. They are never reordered.
     joiners: OrderedSet<PlayerId>,
+    pub treasury: Treasury<G>,
     _spooky: PhantomData<G>,
 }
 
@@ -34,6 +37,7 @@ impl<G: GameArenaService> TeamData<G> {
             name,
             members: OrderedSet::new_with_one(captain),
             joiners: OrderedSet::new(),
+            treasury: Treasury::default(),
             _spooky: PhantomData,
         }
     }
@@ -53,11 +57,21 @@ impl<G: GameArenaService> TeamData<G> {
         self.members.peek_front() == Some(player_id)
     }
 
+    /// Returns whether player_id may grant funds from the treasury.
+    pub fn may_grant(&self, player_id: PlayerId) -> bool {
+        self.is_captain(player_id) || self.treasury.is_officer(player_id)
+    }
+
     /// Returns if the team has the maximum possible amount of members.
     pub fn is_full(&self, players_online: usize) -> bool {
         self.members.len() >= G::team_members_max(players_online)
     }
 
+    /// Iterates players requesting to join, in order of request.
+    pub fn joiners(&self) -> impl Iterator<Item = PlayerId> + '_ {
+        self.joiners.iter()
+    }
+
     /// Returns if the team has the maximum possible amount of joiners a.k.a. requests.
     pub fn is_closed(&self) -> bool {
         self.joiners.len() >= G::TEAM_JOINERS_MAX
@@ -94,6 +108,8 @@ pub struct ClientTeamData {
     previous_joiners: OrderedSet<PlayerId>,
     /// For diffing.
     previous_joins: VecDeque<TeamId>,
+    /// For diffing, the team and version of the last treasury sent.
+    previous_treasury: Option<(TeamId, u32)>,
 }
 
 impl Drop for PlayerTeamData {
@@ -299,12 +315,121 @@ impl<G: GameArenaService> TeamRepo<G> {
         }
         // This updates members_changed automatically.
         if team.assign_captain(assign_player_id) {
+            // Captains may already grant funds.
+            let _ = team.treasury.set_officer(assign_player_id, false);
             Ok(TeamUpdate::Promoted(assign_player_id))
         } else {
             Err("can only assign team members to be captain")
         }
     }
 
+    fn set_officer(
+        &mut self,
+        req_player_id: PlayerId,
+        officer_player_id: PlayerId,
+        officer: bool,
+        players: &mut PlayerRepo<G>,
+    ) -> Result<TeamUpdate, &'static str> {
+        let req_player = players
+            .borrow_player(req_player_id)
+            .ok_or("requesting player nonexistent")?;
+
+        let team_id = req_player.team_id().ok_or("not in team")?;
+        let team = self.teams.get_mut(&team_id).ok_or_else(|| {
+            debug_assert!(false, "team id should have been cleared");
+            "nonexistent team"
+        })?;
+        if !team.is_captain(req_player_id) {
+            return Err("not captain");
+        }
+        if officer_player_id == req_player_id || !team.is_member(officer_player_id) {
+            return Err("can only appoint other team members");
+        }
+        team.treasury.set_officer(officer_player_id, officer)?;
+        Ok(TeamUpdate::OfficerSet(officer_player_id, officer))
+    }
+
+    fn set_share(
+        &mut self,
+        req_player_id: PlayerId,
+        share: u8,
+        players: &mut PlayerRepo<G>,
+    ) -> Result<TeamUpdate, &'static str> {
+        let req_player = players
+            .borrow_player(req_player_id)
+            .ok_or("requesting player nonexistent")?;
+
+        let team_id = req_player.team_id().ok_or("not in team")?;
+        let team = self.teams.get_mut(&team_id).ok_or_else(|| {
+            debug_assert!(false, "team id should have been cleared");
+            "nonexistent team"
+        })?;
+        if !team.is_captain(req_player_id) {
+            return Err("not captain");
+        }
+        team.treasury.set_share(share)?;
+        Ok(TeamUpdate::ShareSet(share))
+    }
+
+    fn contribute(
+        &mut self,
+        req_player_id: PlayerId,
+        amount: u32,
+        players: &mut PlayerRepo<G>,
+    ) -> Result<TeamUpdate, &'static str> {
+        let mut req_player = players
+            .borrow_player_mut(req_player_id)
+            .ok_or("requesting player nonexistent")?;
+
+        let team_id = req_player.team_id().ok_or("not in team")?;
+        let team = self.teams.get_mut(&team_id).ok_or_else(|| {
+            debug_assert!(false, "team id should have been cleared");
+            "nonexistent team"
+        })?;
+        team.treasury
+            .contribute(req_player_id, amount, &mut req_player.score)?;
+        Ok(TeamUpdate::Contributed(amount))
+    }
+
+    fn grant(
+        &mut self,
+        req_player_id: PlayerId,
+        grant_player_id: PlayerId,
+        amount: u32,
+        players: &mut PlayerRepo<G>,
+    ) -> Result<TeamUpdate, &'static str> {
+        if grant_player_id == req_player_id {
+            return Err("cannot grant to self");
+        }
+
+        let req_player = players
+            .borrow_player(req_player_id)
+            .ok_or("requesting player nonexistent")?;
+
+        let team_id = req_player.team_id().ok_or("not in team")?;
+        let team = self.teams.get_mut(&team_id).ok_or_else(|| {
+            debug_assert!(false, "team id should have been cleared");
+            "nonexistent team"
+        })?;
+        if !team.may_grant(req_player_id) {
+            return Err("not captain or officer");
+        }
+        if !team.is_member(grant_player_id) {
+            return Err("can only grant to team members");
+        }
+        let mut grant_player = players
+            .borrow_player_mut(grant_player_id)
+            .ok_or("nonexistent player")?;
+
+        team.treasury.grant(
+            grant_player_id,
+            amount,
+            &mut grant_player.score,
+            Instant::now(),
+        )?;
+        Ok(TeamUpdate::Granted(grant_player_id, amount))
+    }
+
     fn create_team(
         &mut self,
         req_player_id: PlayerId,
@@ -380,6 +505,7 @@ impl<G: GameArenaService> TeamRepo<G> {
         if team.members.remove(kick_player_id) {
             debug_assert_eq!(kick_player.team_id(), Some(team_id));
             debug_assert!(!team.members.is_empty(), "kick reduced team to no members");
+            team.treasury.remove_member(kick_player_id);
             kick_player.team.status = PlayerTeamStatus::solo();
 
             Ok(TeamUpdate::Kicked(kick_player_id))
@@ -410,6 +536,7 @@ impl<G: GameArenaService> TeamRepo<G> {
         })?;
 
         if team.members.remove(req_player_id) {
+            team.treasury.remove_member(req_player_id);
             if team.members.is_empty() {
                 // Last one to leave, delete the team, starting with its joiners.
                 for joiner_player_id in team.joiners.iter() {
@@ -516,13 +643,35 @@ impl<G: GameArenaService> TeamRepo<G> {
             TeamRequest::Promote(player_id) => {
                 self.promote_player(req_player_id, player_id, players)
             }
+            TeamRequest::Contribute(amount) => self.contribute(req_player_id, amount, players),
             TeamRequest::Create(name) => self.create_team(req_player_id, name, players),
+            TeamRequest::Grant(player_id, amount) => {
+                self.grant(req_player_id, player_id, amount, players)
+            }
             TeamRequest::Kick(player_id) => self.kick_player(req_player_id, player_id, players),
             TeamRequest::Leave => self.quit_team(req_player_id, players),
             TeamRequest::Reject(player_id) => {
                 self.accept_or_reject_player(req_player_id, player_id, false, players)
             }
             TeamRequest::Join(team_id) => self.request_join(req_player_id, team_id, players),
+            TeamRequest::SetOfficer(player_id, officer) => {
+                self.set_officer(req_player_id, player_id, officer, players)
+            }
+            TeamRequest::SetShare(share) => self.set_share(req_player_id, share, players),
+        }
+    }
+
+    /// Call once per tick, after the game updates scores, for members to contribute a share of
+    /// their earnings to their team's treasury.
+    pub(crate) fn update_treasuries(&mut self, players: &PlayerRepo<G>) {
+        for team in self.teams.values_mut() {
+            for player_id in team.members.iter() {
+                if let Some(mut player) = players.borrow_player_mut(player_id) {
+                    team.treasury.share_earnings(player_id, &mut player.score);
+                } else {
+                    debug_assert!(false, "team member doesn't exist");
+                }
+            }
         }
     }
 
@@ -561,7 +710,7 @@ impl<G: GameArenaService> TeamRepo<G> {
         }
     }
 
-    /// Return delta in members, joiners, and joins for a given player.
+    /// Return delta in members, joiners, joins, and treasury for a given player.
     /// Only returns [`None`] at the outer level if the player doesn't exist or isn't a real player.
     pub(crate) fn player_delta(
         &mut self,
@@ -571,6 +720,7 @@ impl<G: GameArenaService> TeamRepo<G> {
         Option<OrderedSet<PlayerId>>,
         Option<OrderedSet<PlayerId>>,
         Option<VecDeque<TeamId>>,
+        Option<TreasuryDto>,
     )> {
         let mut player = players.borrow_player_mut(player_id)?;
         let player = &mut *player;
@@ -587,8 +737,9 @@ impl<G: GameArenaService> TeamRepo<G> {
         let previous_members = &mut team.previous_members;
         let previous_joiners = &mut team.previous_joiners;
         let previous_joins = &mut team.previous_joins;
+        let previous_treasury = &mut team.previous_treasury;
 
-        let (members, joiners, joins) = match &player.team.status {
+        let (members, joiners, joins, treasury) = match &player.team.status {
             PlayerTeamStatus::Teamed { team_id } => {
                 if let Some(team) = self.teams.get_mut(team_id) {
                     let joiners = if team.is_captain(player_id) {
@@ -596,19 +747,21 @@ impl<G: GameArenaService> TeamRepo<G> {
                     } else {
                         &EMPTY_PLAYERS
                     };
+                    let treasury = Some((*team_id, &team.treasury));
 
                     // In a team, not joining any other team.
-                    (&team.members, joiners, &*EMPTY_TEAMS)
+                    (&team.members, joiners, &*EMPTY_TEAMS, treasury)
                 } else {
                     debug_assert!(false, "player's team doesn't exist");
-                    (&EMPTY_PLAYERS, &EMPTY_PLAYERS, &*EMPTY_TEAMS)
+                    (&EMPTY_PLAYERS, &EMPTY_PLAYERS, &*EMPTY_TEAMS, None)
                 }
             }
             PlayerTeamStatus::Solo { joins } => {
-                // Not in a team, don't have members or joiners.
-                (&EMPTY_PLAYERS, &EMPTY_PLAYERS, joins)
+                // Not in a team, don't have members, joiners, or a treasury.
+                (&EMPTY_PLAYERS, &EMPTY_PLAYERS, joins, None)
             }
         };
+        let treasury_version = treasury.map(|(team_id, treasury)| (team_id, treasury.version()));
 
         Some((
             (members != previous_members).then(|| {
@@ -623,6 +776,12 @@ impl<G: GameArenaService> TeamRepo<G> {
                 *previous_joins = joins.clone();
                 joins.clone()
             }),
+            (treasury_version != *previous_treasury).then(|| {
+                *previous_treasury = treasury_version;
+                treasury
+                    .map(|(_, treasury)| treasury.dto())
+                    .unwrap_or_default()
+            }),
         ))
     }
 
@@ -795,7 +954,7 @@ mod test {
             let rand_player_id_1 = PlayerId::nth_bot(thread_rng().gen_range(0..50)).unwrap();
             let rand_player_id_2 = PlayerId::nth_bot(thread_rng().gen_range(25..80)).unwrap();
 
-            let req = match thread_rng().gen_range(0..8) {
+            let req = match thread_rng().gen_range(0..12) {
                 0 => TeamRequest::Leave,
                 1 => TeamRequest::Create(*team_names.iter().choose(&mut thread_rng()).unwrap()),
                 2 => {
@@ -807,6 +966,10 @@ mod test {
                 4 => TeamRequest::Reject(rand_player_id_1),
                 5 => TeamRequest::Kick(rand_player_id_1),
                 6 => TeamRequest::Promote(rand_player_id_1),
+                7 => TeamRequest::Contribute(thread_rng().gen_range(0..10)),
+                8 => TeamRequest::Grant(rand_player_id_1, thread_rng().gen_range(0..10)),
+                9 => TeamRequest::SetOfficer(rand_player_id_1, thread_rng().gen()),
+                10 => TeamRequest::SetShare(thread_rng().gen_range(0..100)),
                 _ => {
                     teams.cleanup_player(rand_player_id_1, &mut players);
                     continue;
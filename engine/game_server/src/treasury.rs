@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::game_service::GameArenaService;
+use crate::ordered_set::OrderedSet;
+use core_protocol::dto::{TreasuryDto, TreasuryTransactionDto};
+use core_protocol::id::PlayerId;
+use std::collections::{HashMap, VecDeque};
+use std::marker::PhantomData;
+use std::time::Instant;
+
+/// A team's shared funds. Members contribute a share of their earnings automatically, or
+/// contribute manually, and the captain or officers may grant funds to members.
+pub struct Treasury<G: GameArenaService> {
+    balance: u32,
+    /// Percentage of earnings members contribute automatically.
+    share: u8,
+    /// Members, besides the captain, who may grant funds.
+    officers: OrderedSet<PlayerId>,
+    /// Most recent transactions, oldest first.
+    history: VecDeque<TreasuryTransactionDto>,
+    /// Each member's score when their earnings were last shared, and the fraction of a point
+    /// they still owe.
+    accounts: HashMap<PlayerId, (u32, f32)>,
+    /// Score granted to each recipient in their current window, and when that window started.
+    /// Outlives membership, so leaving and rejoining doesn't reset the limit.
+    grants: HashMap<PlayerId, (Instant, u32)>,
+    /// Incremented on every change, for diffing.
+    version: u32,
+    _spooky: PhantomData<G>,
+}
+
+impl<G: GameArenaService> Default for Treasury<G> {
+    fn default() -> Self {
+        Self {
+            balance: 0,
+            share: 0,
+            officers: OrderedSet::new(),
+            history: VecDeque::new(),
+            accounts: HashMap::new(),
+            grants: HashMap::new(),
+            version: 0,
+            _spooky: PhantomData,
+        }
+    }
+}
+
+impl<G: GameArenaService> Treasury<G> {
+    /// Maximum number of transactions to remember.
+    const HISTORY_MAX: usize = 8;
+
+    /// Returns the current balance.
+    pub fn balance(&self) -> u32 {
+        self.balance
+    }
+
+    /// Returns a number that changes whenever the treasury does.
+    pub fn version(&self) -> u32 {
+        self.version
+    }
+
+    /// Returns whether player_id may grant funds, in addition to the captain.
+    pub fn is_officer(&self, player_id: PlayerId) -> bool {
+        self.officers.contains(player_id)
+    }
+
+    /// Appoints or dismisses an officer. The caller must ensure they are a member.
+    pub fn set_officer(&mut self, player_id: PlayerId, officer: bool) -> Result<(), &'static str> {
+        let changed = if officer {
+            self.officers.insert_back(player_id)
+        } else {
+            self.officers.remove(player_id)
+        };
+        if !changed {
+            return Err(if officer {
+                "already an officer"
+            } else {
+                "not an officer"
+            });
+        }
+        self.version = self.version.wrapping_add(1);
+        Ok(())
+    }
+
+    /// Sets the percentage of earnings members contribute automatically.
+    pub fn set_share(&mut self, share: u8) -> Result<(), &'static str> {
+        if share > G::TEAM_SHARE_MAX {
+            return Err("share too high");
+        }
+        self.share = share;
+        self.version = self.version.wrapping_add(1);
+        Ok(())
+    }
+
+    /// Call when a player stops being a member.
+    pub fn remove_member(&mut self, player_id: PlayerId) {
+        self.accounts.remove(&player_id);
+        if self.officers.remove(player_id) {
+            self.version = self.version.wrapping_add(1);
+        }
+    }
+
+    /// Contributes the configured share of a member's earnings since the last call.
+    pub fn share_earnings(&mut self, player_id: PlayerId, score: &mut u32) {
+        let (previous, owed) = self.accounts.entry(player_id).or_insert((*score, 0.0));
+        let mut amount = 0;
+        if *score > *previous && self.share > 0 {
+            *owed += (*score - *previous) as f32 * self.share as f32 / 100.0;
+            amount = (*owed as u32).min(*score);
+            *owed -= amount as f32;
+            *score -= amount;
+        }
+        // Also resets the baseline if score went down, e.g. because the member died.
+        *previous = *score;
+
+        if amount > 0 {
+            self.balance = self.balance.saturating_add(amount);
+            self.record(TreasuryTransactionDto::Contributed { player_id, amount });
+        }
+    }
+
+    /// Contributes amount of a member's score.
+    pub fn contribute(
+        &mut self,
+        player_id: PlayerId,
+        amount: u32,
+        score: &mut u32,
+    ) -> Result<(), &'static str> {
+        if amount == 0 {
+            return Err("cannot contribute nothing");
+        }
+        if amount > *score {
+            return Err("insufficient score");
+        }
+        // The next call to share_earnings will lower the baseline accordingly.
+        *score -= amount;
+        self.balance = self.balance.saturating_add(amount);
+        self.record(TreasuryTransactionDto::Contributed { player_id, amount });
+        Ok(())
+    }
+
+    /// Grants amount to a member, subject to the per-window limit. The caller must ensure that
+    /// the granter is allowed to grant, and the recipient is a member.
+    pub fn grant(
+        &mut self,
+        player_id: PlayerId,
+        amount: u32,
+        score: &mut u32,
+        now: Instant,
+    ) -> Result<(), &'static str> {
+        if amount == 0 {
+            return Err("cannot grant nothing");
+        }
+        if amount > self.balance {
+            return Err("insufficient funds");
+        }
+
+        self.grants
+            .retain(|_, &mut (start, _)| now.duration_since(start) < G::TEAM_GRANT_WINDOW);
+        let (_, granted) = self.grants.entry(player_id).or_insert((now, 0));
+        if granted.saturating_add(amount) > G::TEAM_GRANT_MAX {
+            return Err("grant limit reached");
+        }
+        *granted += amount;
+
+        self.balance -= amount;
+        *score = score.saturating_add(amount);
+        // Grants aren't earnings, so aren't shared.
+        if let Some((previous, _)) = self.accounts.get_mut(&player_id) {
+            *previous = previous.saturating_add(amount);
+        }
+        self.record(TreasuryTransactionDto::Granted { player_id, amount });
+        Ok(())
+    }
+
+    /// Records a transaction, merging consecutive contributions by the same member.
+    fn record(&mut self, transaction: TreasuryTransactionDto) {
+        self.version = self.version.wrapping_add(1);
+
+        if let (
+            Some(TreasuryTransactionDto::Contributed {
+                player_id: previous_player_id,
+                amount: previous_amount,
+            }),
+            TreasuryTransactionDto::Contributed { player_id, amount },
+        ) = (self.history.back_mut(), transaction)
+        {
+            if *previous_player_id == player_id {
+                *previous_amount = previous_amount.saturating_add(amount);
+                return;
+            }
+        }
+
+        if self.history.len() >= Self::HISTORY_MAX {
+            self.history.pop_front();
+        }
+        self.history.push_back(transaction);
+    }
+
+    /// Gets the dto sent to members.
+    pub fn dto(&self) -> TreasuryDto {
+        TreasuryDto {
+            balance: self.balance,
+            share: self.share,
+            officers: self.officers.iter().collect(),
+            history: self.history.iter().copied().collect(),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::game_service::{GameArenaService, MockGame};
+    use crate::treasury::Treasury;
+    use core_protocol::dto::TreasuryTransactionDto;
+    use core_protocol::id::PlayerId;
+    use std::time::Instant;
+
+    #[test]
+    fn treasury() {
+        let mut treasury = Treasury::<MockGame>::default();
+        let player_id = PlayerId::nth_bot(1).unwrap();
+        let mut score = 100;
+
+        // Nothing is shared until a share is set.
+        treasury.share_earnings(player_id, &mut score);
+        score += 100;
+        treasury.share_earnings(player_id, &mut score);
+        assert_eq!((score, treasury.balance()), (200, 0));
+
+        assert!(treasury.set_share(MockGame::TEAM_SHARE_MAX + 1).is_err());
+        treasury.set_share(10).unwrap();
+        score += 100;
+        treasury.share_earnings(player_id, &mut score);
+        assert_eq!((score, treasury.balance()), (290, 10));
+
+        // Fractions of a point add up.
+        for _ in 0..2 {
+            score += 5;
+            treasury.share_earnings(player_id, &mut score);
+        }
+        assert_eq!((score, treasury.balance()), (299, 11));
+
+        // Losing score isn't earnings.
+        score = 50;
+        treasury.share_earnings(player_id, &mut score);
+        assert_eq!(treasury.balance(), 11);
+
+        assert!(treasury.contribute(player_id, 51, &mut score).is_err());
+        treasury.contribute(player_id, 50, &mut score).unwrap();
+        assert_eq!((score, treasury.balance()), (0, 61));
+        assert_eq!(
+            treasury.dto().history.as_ref(),
+            &[TreasuryTransactionDto::Contributed {
+                player_id,
+                amount: 61
+            }]
+        );
+
+        // Grants are limited.
+        let now = Instant::now();
+        assert!(treasury.grant(player_id, 62, &mut score, now).is_err());
+        treasury.grant(player_id, 61, &mut score, now).unwrap();
+        treasury.share_earnings(player_id, &mut score);
+        assert_eq!((score, treasury.balance()), (61, 0));
+
+        treasury.balance = MockGame::TEAM_GRANT_MAX;
+        assert!(treasury
+            .grant(player_id, MockGame::TEAM_GRANT_MAX, &mut score, now)
+            .is_err());
+        treasury
+            .grant(
+                player_id,
+                MockGame::TEAM_GRANT_MAX,
+                &mut score,
+                now + MockGame::TEAM_GRANT_WINDOW,
+            )
+            .unwrap();
+    }
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+//! Serves the same requests and updates as the WebSocket over WebTransport, sending game updates
+//! that are safe to lose as datagrams so that a lost packet doesn't delay newer updates.
+
+use crate::client::Authenticate;
+use crate::game_service::GameArenaService;
+use crate::infrastructure::Infrastructure;
+use actix::Addr;
+use bincode::Options as _;
+use core_protocol::rpc::{Request, Update, WebSocketQuery};
+use core_protocol::web_transport::{fragment, write_frame, FrameReader, Sequence, PATH};
+use log::{debug, error, info, warn};
+use server_util::observer::{ObserverMessage, ObserverUpdate};
+use server_util::rate_limiter::{RateLimiterProps, RateLimiterState};
+use std::time::{Duration, Instant};
+use wtransport::endpoint::IncomingSession;
+use wtransport::tls::Certificate;
+use wtransport::{Endpoint, ServerConfig};
+
+const MAX_MESSAGE_SIZE: usize = 32768;
+
+/// Loads the certificate, or generates a self-signed one, returning it along with the hash that
+/// clients need in order to trust a self-signed certificate.
+///
+/// The endpoint must be restarted to use a renewed certificate.
+pub async fn load_certificate(paths: Option<(&str, &str)>) -> (Certificate, Option<[u8; 32]>) {
+    if let Some((certificate_path, private_key_path)) = paths {
+        match Certificate::load(certificate_path, private_key_path).await {
+            Ok(certificate) => return (certificate, None),
+            Err(e) => error!("could not load certificate for web transport: {}", e),
+        }
+    }
+
+    // Browsers only accept the hash of a short-lived, self-signed certificate.
+    warn!("Using self-signed certificate for web transport.");
+    let certificate = Certificate::self_signed(["localhost"]);
+    let hash = certificate.hashes().first().map(|hash| *hash.as_ref());
+    (certificate, hash)
+}
+
+/// Accepts WebTransport sessions on the given UDP port until the endpoint fails.
+pub async fn serve<G: GameArenaService>(
+    srv: Addr<Infrastructure<G>>,
+    port: u16,
+    certificate: Certificate,
+) {
+    let config = ServerConfig::builder()
+        .with_bind_default(port)
+        .with_certificate(certificate)
+        .keep_alive_interval(Some(Duration::from_secs(5)))
+        .build();
+
+    let endpoint = match Endpoint::server(config) {
+        Ok(endpoint) => endpoint,
+        Err(e) => {
+            error!("could not start web transport server: {}", e);
+            return;
+        }
+    };
+    info!("WebTransport port is {}", port);
+
+    loop {
+        let incoming_session = endpoint.accept().await;
+        tokio::spawn(serve_session(srv.clone(), incoming_session));
+    }
+}
+
+async fn serve_session<G: GameArenaService>(
+    srv: Addr<Infrastructure<G>>,
+    incoming_session: IncomingSession,
+) {
+    let session_request = match incoming_session.await {
+        Ok(session_request) => session_request,
+        Err(e) => {
+            debug!("web transport session error: {:?}", e);
+            return;
+        }
+    };
+
+    let (path, query) = session_request
+        .path()
+        .split_once('?')
+        .unwrap_or((session_request.path(), ""));
+    if path != PATH {
+        session_request.not_found().await;
+        return;
+    }
+    let query: WebSocketQuery = match serde_urlencoded::from_str(query) {
+        Ok(query) => query,
+        Err(_) => {
+            session_request.forbidden().await;
+            return;
+        }
+    };
+    let user_agent = session_request.user_agent().map(str::to_owned);
+
+    let connection = match session_request.accept().await {
+        Ok(connection) => connection,
+        Err(e) => {
+            debug!("web transport accept error: {:?}", e);
+            return;
+        }
+    };
+
+    let authenticate = Authenticate::new(
+        connection.remote_address().ip(),
+        user_agent.as_deref(),
+        query,
+    );
+    let player_id = match srv.send(authenticate).await {
+        Ok(Ok(player_id)) => player_id,
+        // Dropping the connection closes it.
+        _ => return,
+    };
+
+    // The client announces its stream by writing an empty frame.
+    let (mut send_stream, mut recv_stream) = match connection.accept_bi().await {
+        Ok(streams) => streams,
+        Err(e) => {
+            debug!("web transport stream error: {:?}", e);
+            return;
+        }
+    };
+
+    let (server_sender, mut server_receiver) =
+        tokio::sync::mpsc::unbounded_channel::<ObserverUpdate<Update<G::GameUpdate>>>();
+
+    let _ = srv.do_send(
+        ObserverMessage::<Request<G::GameRequest>, Update<G::GameUpdate>>::Register {
+            player_id,
+            observer: server_sender.clone(),
+        },
+    );
+
+    let mut reader = FrameReader::new(MAX_MESSAGE_SIZE);
+    let mut read_buffer = [0u8; 4096];
+    let mut sequence: Sequence = 0;
+    let mut rate_limiter = RateLimiterState::default();
+    let mut measure_rtt_governor = RateLimiterState::default();
+    const RATE: RateLimiterProps = RateLimiterProps::const_new(Duration::from_millis(80), 5);
+    const MEASURE_RTT: RateLimiterProps = RateLimiterProps::const_new(Duration::from_secs(60), 0);
+
+    'session: loop {
+        tokio::select! {
+            read = recv_stream.read(&mut read_buffer) => {
+                let read = match read {
+                    Ok(Some(read)) => read,
+                    // Stream finished or failed.
+                    _ => break,
+                };
+                reader.extend(&read_buffer[..read]);
+
+                loop {
+                    let frame = match reader.next_frame() {
+                        Ok(Some(frame)) => frame,
+                        Ok(None) => break,
+                        Err(e) => {
+                            debug!("web transport frame error: {}", e);
+                            break 'session;
+                        }
+                    };
+
+                    // Empty frames only keep the stream open.
+                    if frame.is_empty() || rate_limiter.should_limit_rate_with_now(&RATE, Instant::now()) {
+                        continue;
+                    }
+
+                    match bincode::DefaultOptions::new()
+                        .with_limit(MAX_MESSAGE_SIZE as u64)
+                        .with_fixint_encoding()
+                        .allow_trailing_bytes()
+                        .deserialize(&frame)
+                    {
+                        Ok(request) => {
+                            let _ = srv.do_send(ObserverMessage::<Request<G::GameRequest>, Update<G::GameUpdate>>::Request {
+                                player_id,
+                                request,
+                            });
+                        }
+                        Err(err) => {
+                            warn!("deserialize binary err ignored {}", err);
+                        }
+                    }
+                }
+            },
+            maybe_observer_update = server_receiver.recv() => {
+                let message = match maybe_observer_update {
+                    Some(ObserverUpdate::Send{message}) => message,
+                    // Infrastructure wants connection closed.
+                    Some(ObserverUpdate::Close) | None => break,
+                };
+
+                sequence = sequence.wrapping_add(1);
+                let mut payload = sequence.to_le_bytes().to_vec();
+                bincode::serialize_into(&mut payload, &message).unwrap();
+
+                // Datagrams carry the sequence in their own header.
+                let datagrams = match &message {
+                    Update::Game(update) if G::is_update_lossy(update) => connection
+                        .max_datagram_size()
+                        .and_then(|size| fragment(sequence, &payload[2..], size)),
+                    _ => None,
+                };
+
+                if let Some(datagrams) = datagrams {
+                    for datagram in datagrams {
+                        // Lost datagrams are superseded by the next update anyway.
+                        let _ = connection.send_datagram(datagram);
+                    }
+                } else {
+                    let mut frame = Vec::with_capacity(payload.len() + 4);
+                    write_frame(&mut frame, &payload);
+                    if send_stream.write_all(&frame).await.is_err() {
+                        break;
+                    }
+                }
+
+                if !measure_rtt_governor.should_limit_rate_with_now(&MEASURE_RTT, Instant::now()) {
+                    let rtt = connection.rtt().as_millis();
+                    if rtt <= 10000 {
+                        let _ = srv.do_send(ObserverMessage::<Request<G::GameRequest>, Update<G::GameUpdate>>::RoundTripTime {
+                            player_id,
+                            rtt: rtt as u16,
+                        });
+                    }
+                }
+            },
+        }
+    }
+
+    let _ = srv.do_send(
+        ObserverMessage::<Request<G::GameRequest>, Update<G::GameUpdate>>::Unregister {
+            player_id,
+            observer: server_sender,
+        },
+    );
+}
@@ -473,6 +473,11 @@ impl Texture {
         const FONT: &str = "30px Arial";
         const HEIGHT: u32 = 36; // 32 -> 36 to fit "😊".
 
+        // Isolate the text so its direction comes from its first strong character (e.g. right to
+        // left for Arabic names), rather than the canvas, so that mixed text and numbers aren't
+        // jumbled. Shaping (e.g. joining Arabic letters) is up to the browser.
+        let text = &format!("\u{2068}{}\u{2069}", text);
+
         context.set_font(FONT);
         context.set_text_baseline("bottom");
         let text_width = context.measure_text(text).unwrap().width();
@@ -486,6 +491,8 @@ impl Texture {
         context.set_fill_style(&JsValue::from_str(&color_string));
         context.set_font(FONT);
         context.set_text_baseline("bottom");
+        // Not "start", which is on the right if the page is right-to-left.
+        context.set_text_align("left");
 
         context
             .fill_text(text, 1.0, (HEIGHT - 1) as f64)
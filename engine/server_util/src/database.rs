@@ -6,7 +6,7 @@
 
 use crate::database_schema::{
     GameIdMetricFilter, GameIdScoreType, LoginItem, Metrics, MetricsItem, Score, ScoreItem,
-    ScoreType, SessionItem,
+    ScoreType, SessionItem, SettingsItem,
 };
 use aws_config::default_provider::credentials::DefaultCredentialsChain;
 use aws_config::TimeoutConfig;
@@ -43,6 +43,7 @@ impl Database {
     const METRICS_TABLE_NAME: &'static str = "core_metrics";
     const SESSIONS_TABLE_NAME: &'static str = "core_sessions";
     const SCORES_TABLE_NAME: &'static str = "core_scores";
+    const SETTINGS_TABLE_NAME: &'static str = "core_settings";
     //const USERS_TABLE_NAME: &'static str = "core_users";
 
     pub async fn new(read_only: bool) -> Self {
@@ -533,6 +534,25 @@ impl Database {
         self.put(login, Self::LOGINS_TABLE_NAME).await
     }
 
+    pub async fn get_settings(
+        &self,
+        user_id: UserId,
+        game_id: GameId,
+    ) -> Result<Option<SettingsItem>, Error> {
+        self.get2(
+            Self::SETTINGS_TABLE_NAME,
+            "user_id",
+            user_id,
+            "game_id",
+            game_id,
+        )
+        .await
+    }
+
+    pub async fn put_settings(&self, settings: SettingsItem) -> Result<(), Error> {
+        self.put(settings, Self::SETTINGS_TABLE_NAME).await
+    }
+
     pub async fn get_metrics_between(
         &self,
         game_id: GameId,
@@ -3,7 +3,7 @@
 
 use aws_sdk_dynamodb::model::AttributeValue;
 use common_util::serde::is_default;
-use core_protocol::dto::{MetricFilter, MetricsDataPointDto, MetricsSummaryDto};
+use core_protocol::dto::{MetricFilter, MetricsDataPointDto, MetricsSummaryDto, SettingsDto};
 use core_protocol::id::{
     ArenaId, CohortId, GameId, LoginType, PlayerId, ServerId, SessionId, UserAgentId, UserId,
 };
@@ -191,6 +191,9 @@ pub struct Metrics {
     /// Number of invitations in RAM cache.
     #[serde(default, skip_serializing_if = "is_default")]
     pub invitations_cached: DiscreteMetric,
+    /// Load shedding level, from 0 (none) to 5 (refusing new sessions).
+    #[serde(default, skip_serializing_if = "is_default")]
+    pub load_shedding: ContinuousExtremaMetric,
     /// Ratio of players with FPS below 24 to all players.
     #[serde(default, skip_serializing_if = "is_default")]
     pub low_fps: RatioMetric,
@@ -206,6 +209,9 @@ pub struct Metrics {
     /// Ratio of players with no referrer to all players.
     #[serde(default)]
     pub no_referrer: RatioMetric,
+    /// Ratio of budgeted entity spawns that expired older entities to stay within budget.
+    #[serde(default, skip_serializing_if = "is_default")]
+    pub over_budget: RatioMetric,
     /// Ratio of previous players that leave without playing (e.g. to peek at player count).
     #[serde(default, skip_serializing_if = "is_default")]
     pub peek: RatioMetric,
@@ -296,11 +302,13 @@ impl Metrics {
             fps,
             invited,
             invitations_cached,
+            load_shedding,
             low_fps,
             minutes_per_play,
             minutes_per_visit,
             new,
             no_referrer,
+            over_budget,
             peek,
             players_cached,
             plays_per_visit,
@@ -343,11 +351,13 @@ impl Metrics {
             fps,
             invited,
             invitations_cached,
+            load_shedding,
             low_fps,
             minutes_per_play,
             minutes_per_visit,
             new,
             no_referrer,
+            over_budget,
             peek,
             players_cached,
             plays_per_visit,
@@ -491,6 +501,15 @@ pub struct MetricsItem {
     pub metrics: Metrics,
 }
 
+#[derive(Clone, Serialize, Deserialize)]
+pub struct SettingsItem {
+    /// Hash key.
+    pub user_id: UserId,
+    /// Range key.
+    pub game_id: GameId,
+    pub settings: SettingsDto,
+}
+
 #[derive(Serialize, Deserialize)]
 pub struct UserItem {
     pub user_id: UserId,
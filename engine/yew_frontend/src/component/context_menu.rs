@@ -1,9 +1,10 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-use crate::frontend::use_set_context_menu_callback;
+use crate::frontend::{use_ctw, use_set_context_menu_callback};
 use crate::WindowEventListener;
 use gloo::timers::callback::Timeout;
+use js_hooks::window;
 use stylist::yew::styled_component;
 use web_sys::MouseEvent;
 use yew::{
@@ -18,7 +19,18 @@ pub struct ContextMenuProps {
 
 #[function_component(ContextMenu)]
 pub fn context_menu(props: &ContextMenuProps) -> Html {
-    let style = format!("background-color: #444444aa; min-width: 100px; position: absolute; display: flex; flex-direction: column; left: {}px; top: {}px;", props.event.x(), props.event.y());
+    // Offset from the edge where the language starts (the right edge, if right-to-left).
+    let inline_start = if use_ctw().setting_cache.language.is_right_to_left() {
+        window()
+            .inner_width()
+            .ok()
+            .and_then(|width| width.as_f64())
+            .unwrap_or_default() as i32
+            - props.event.x()
+    } else {
+        props.event.x()
+    };
+    let style = format!("background-color: #444444aa; min-width: 100px; position: absolute; display: flex; flex-direction: column; inset-inline-start: {}px; top: {}px;", inline_start, props.event.y());
 
     // Provide for closing the menu by rightclicking elsewhere.
     let set_context_menu_callback = use_set_context_menu_callback();
@@ -30,7 +30,7 @@ pub fn language_menu() -> Html {
         background-color: #CCC;
         color: black;
         position: absolute;
-        right: 0;
+        inset-inline-end: 0;
         top: 0;
         width: min-content;
         border-radius: 0.25em;
@@ -41,19 +41,25 @@ impl Position {
     }
 }
 
+/// Left and right are relative to the direction of the document (i.e. swapped if the language is
+/// right-to-left).
 impl Display for Position {
     fn fmt(&self, f: &mut Formatter<'_>) -> std::fmt::Result {
         f.write_str("position: absolute;")?;
 
         let horizontal = self.horizontal();
         let (h_position, h_margin, h_translation) = match horizontal {
-            HorizontalPosition::Left { margin } => {
-                ("left: 0;", format!("margin-left: {};", margin), 0)
-            }
+            HorizontalPosition::Left { margin } => (
+                "inset-inline-start: 0;",
+                format!("margin-inline-start: {};", margin),
+                0,
+            ),
             HorizontalPosition::Middle => ("left: 50%;", String::new(), -50),
-            HorizontalPosition::Right { margin, .. } => {
-                ("right: 0;", format!("margin-right: {};", margin), 0)
-            }
+            HorizontalPosition::Right { margin, .. } => (
+                "inset-inline-end: 0;",
+                format!("margin-inline-end: {};", margin),
+                0,
+            ),
         };
 
         f.write_str(h_position)?;
@@ -86,6 +92,7 @@ impl Display for Position {
     }
 }
 
+/// Left and right are relative to the direction of the document, like [`Position`].
 #[derive(Copy, Clone, Debug, Eq, PartialEq)]
 pub enum Align {
     Left,
@@ -96,9 +103,9 @@ pub enum Align {
 impl Align {
     pub fn as_css(self) -> &'static str {
         match self {
-            Align::Left => "text-align: left;",
+            Align::Left => "text-align: start;",
             Align::Center => "text-align: center;",
-            Align::Right => "text-align: right;",
+            Align::Right => "text-align: end;",
         }
     }
 }
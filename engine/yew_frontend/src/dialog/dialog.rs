@@ -54,8 +54,8 @@ pub fn dialog(props: &DialogProps) -> Html {
         r#"
         height: 85%;
         overflow-y: auto;
-        padding-left: 0.75rem;
-        padding-right: 0.75rem;
+        padding-inline-start: 0.75rem;
+        padding-inline-end: 0.75rem;
         width: calc(100% - 1.5em);
         top: 10%;
         position: absolute;
@@ -142,7 +142,7 @@ pub fn dialog(props: &DialogProps) -> Html {
                         <RouteLink<AnyRoute> route={AnyRoute::new(route)} class={classes!(link_style.clone(), (pathname.starts_with(route)).then(|| link_selected_style.clone()))}>{route_to_title(route)}</RouteLink<AnyRoute>>
                     }).collect::<Html>()}
                 </div>
-                <div style="position: absolute; top: 0.5rem; right: 0.5em;">
+                <div style="position: absolute; top: 0.5rem; inset-inline-end: 0.5em;">
                     <XButton {onclick}/>
                 </div>
             </div>
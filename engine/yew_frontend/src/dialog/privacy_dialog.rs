@@ -91,7 +91,7 @@ pub fn privacy(outbound_enabled: bool, email: &str) -> Html {
 
             <p>{r#"In order to ensure the continuity and consistency of your experience, and provide for internal operations, we store a persistent session identifier in your browser's local storage. You can reset it at any time, by using your browser's "clear site data" option. We do not use this information for advertising purposes."#}</p>
 
-            <p>{"Settings, such as which language and volume level you select, are also stored in your browser's local storage but we don't collect them."}</p>
+            <p>{"Settings, such as which language and volume level you select, are also stored in your browser's local storage. If you log in, we also store them on our servers, linked to your account, so that they follow you between devices."}</p>
 
             <h2>{"Changes"}</h2>
 
@@ -16,6 +16,7 @@ pub mod event;
 pub mod frontend;
 mod keyboard;
 pub mod overlay;
+mod service_worker;
 pub mod translation;
 pub mod window;
 
@@ -27,6 +28,8 @@ use crate::error_tracer::ErrorTracer;
 use crate::frontend::{post_message, RewardedAd};
 use crate::overlay::fatal_error::FatalError;
 use crate::overlay::reconnecting::Reconnecting;
+use crate::overlay::update_available::UpdateAvailable;
+use crate::service_worker::{reload_to_update, update_service_worker};
 use crate::window::event_listener::WindowEventListener;
 use client_util::browser_storage::BrowserStorages;
 use client_util::context::WeakCoreState;
@@ -40,15 +43,19 @@ use core_protocol::name::Referrer;
 use core_protocol::rpc::{AdType, ChatRequest, PlayerRequest, Request, TeamRequest};
 use engine_macros::SmolRoutable;
 use frontend::{Ctw, Gctw, PropertiesWrapper, Yew};
+use gloo::timers::callback::Interval;
 use gloo_render::{request_animation_frame, AnimationFrame};
-use js_hooks::console_log;
+use js_hooks::{console_log, document, window};
 use keyboard::KeyboardEventsListener;
 use std::marker::PhantomData;
 use std::num::NonZeroU8;
 use stylist::{global_style, GlobalStyle};
 use wasm_bindgen::JsValue;
 use wasm_bindgen_futures::future_to_promise;
-use web_sys::{FocusEvent, KeyboardEvent, MessageEvent, MouseEvent, TouchEvent, WheelEvent};
+use web_sys::{
+    FocusEvent, KeyboardEvent, MessageEvent, MouseEvent, ServiceWorker, TouchEvent,
+    VisibilityState, WheelEvent,
+};
 use yew::prelude::*;
 use yew_router::prelude::*;
 
@@ -66,11 +73,17 @@ struct App<
     ui_props: G::UiProps,
     rewarded_ad: RewardedAd,
     fatal_error: Option<String>,
+    /// Service worker of a new version of the client, cached and waiting for a reload.
+    update_available: Option<ServiceWorker>,
     /// After [`AppMsg::RecreateCanvas`] is received, before [`AppMsg::RecreateRenderer`] is received.
     recreating_canvas: RecreatingCanvas,
     /// Whether outbound links are enabled.
     outbound_enabled: bool,
     _animation_frame: AnimationFrame,
+    /// Animation frames are paused while the page is hidden, but updates must still be processed
+    /// (e.g. to alert the player).
+    _hidden_frame_interval: Option<Interval>,
+    _update_interval: Interval,
     _keyboard_events_listener: KeyboardEventsListener,
     _visibility_listener: WindowEventListener<Event>,
     /// Message from parent window.
@@ -121,6 +134,19 @@ impl<G: GameClient> PendingInfrastructure<G> {
             }
         }
     }
+
+    fn common_settings(&self) -> CommonSettings {
+        match self {
+            Self::Done(infrastructure) => infrastructure.context.common_settings.clone(),
+            Self::Pending {
+                common_settings, ..
+            } => common_settings.clone(),
+            Self::Swapping => {
+                debug_assert!(false, "PendingInfrastructure::Swapping::common_settings");
+                CommonSettings::default()
+            }
+        }
+    }
 }
 
 #[derive(Copy, Clone, Default, PartialEq)]
@@ -148,6 +174,12 @@ enum AppMsg<G: GameClient> {
     RecreateCanvasPart2,
     /// Signals just the renderer should be recreated.
     RecreateRenderer,
+    /// Check whether the server advertises a new version of the client.
+    CheckForUpdate,
+    /// A new version of the client is cached and waiting.
+    UpdateAvailable(ServiceWorker),
+    /// Reload into the new version of the client.
+    Update,
     SetServerId(Option<ServerId>),
     #[allow(unused)]
     FatalError(String),
@@ -184,6 +216,11 @@ impl<
 where
     G::UiProps: Default + PartialEq + Clone,
 {
+    /// How often to check for a new version of the client.
+    const UPDATE_CHECK_PERIOD_MILLIS: u32 = 5 * 60 * 1000;
+    /// How often to process updates while the page is hidden.
+    const HIDDEN_FRAME_PERIOD_MILLIS: u32 = 1000;
+
     pub fn create_animation_frame(ctx: &Context<Self>) -> AnimationFrame {
         let link = ctx.link().clone();
         request_animation_frame(move |time| link.send_message(AppMsg::Frame { time }))
@@ -207,6 +244,7 @@ where
         let visibility_callback = ctx.link().callback(AppMsg::VisibilityChange);
         let message_callback = ctx.link().callback(AppMsg::Message);
         let trace_callback = ctx.link().callback(AppMsg::Trace);
+        let check_for_update_callback = ctx.link().callback(|_| AppMsg::CheckForUpdate);
 
         // First load local storage common settings.
         // Not guaranteed to set either or both to Some. Could fail to load.
@@ -225,8 +263,13 @@ where
             recreating_canvas: RecreatingCanvas::default(),
             rewarded_ad: RewardedAd::Unavailable,
             fatal_error: None,
+            update_available: None,
             outbound_enabled: true,
             _animation_frame: Self::create_animation_frame(ctx),
+            _hidden_frame_interval: None,
+            _update_interval: Interval::new(Self::UPDATE_CHECK_PERIOD_MILLIS, move || {
+                check_for_update_callback.emit(())
+            }),
             _keyboard_events_listener: KeyboardEventsListener::new(
                 keyboard_callback,
                 keyboard_focus_callback,
@@ -399,6 +442,28 @@ where
                 */
                 return true;
             }
+            AppMsg::CheckForUpdate => {
+                if self.update_available.is_none() {
+                    let update_available_callback = ctx.link().callback(AppMsg::UpdateAvailable);
+                    let _ = future_to_promise(async move {
+                        match update_service_worker().await {
+                            Ok(Some(waiting)) => update_available_callback.emit(waiting),
+                            Ok(None) => {}
+                            Err(e) => console_log!("service worker error: {}", e),
+                        }
+                        Ok(JsValue::NULL)
+                    });
+                }
+            }
+            AppMsg::UpdateAvailable(waiting) => {
+                self.update_available = Some(waiting);
+                return true;
+            }
+            AppMsg::Update => {
+                if let Some(waiting) = self.update_available.as_ref() {
+                    reload_to_update(waiting);
+                }
+            }
             AppMsg::SetServerId(server_id) => {
                 if let Some(infrastructure) = self.infrastructure.as_mut() {
                     infrastructure.choose_server_id(server_id);
@@ -496,6 +561,17 @@ where
                 if let Some(infrastructure) = self.infrastructure.as_mut() {
                     infrastructure.visibility_change(event);
                 }
+                let hidden = document().visibility_state() == VisibilityState::Hidden;
+                self._hidden_frame_interval = hidden.then(|| {
+                    let link = ctx.link().clone();
+                    Interval::new(Self::HIDDEN_FRAME_PERIOD_MILLIS, move || {
+                        if let Some(performance) = window().performance() {
+                            link.send_message(AppMsg::Frame {
+                                time: performance.now(),
+                            });
+                        }
+                    })
+                });
             }
             AppMsg::Message(message) => {
                 console_log!("received message: {}", message);
@@ -606,18 +682,7 @@ where
             set_context_menu_callback,
             routes,
             licenses: G::LICENSES,
-            setting_cache: match &self.infrastructure {
-                PendingInfrastructure::Done(infrastructure) => {
-                    infrastructure.context.common_settings.clone()
-                }
-                PendingInfrastructure::Pending {
-                    common_settings, ..
-                } => common_settings.clone(),
-                PendingInfrastructure::Swapping => {
-                    debug_assert!(false, "PendingInfrastructure::Swapping in render");
-                    CommonSettings::default()
-                }
-            },
+            setting_cache: self.infrastructure.common_settings(),
             state: self
                 .infrastructure
                 .as_ref()
@@ -668,6 +733,9 @@ where
                                 if self.infrastructure.as_ref().map(|i| i.context.socket.is_reconnecting()).unwrap_or_default() {
                                     <Reconnecting/>
                                 }
+                                if self.update_available.is_some() {
+                                    <UpdateAvailable reload_callback={ctx.link().callback(|_| AppMsg::Update)}/>
+                                }
                             </>
                         }
                     </ContextProvider<Gctw<G>>>
@@ -684,6 +752,14 @@ where
                 frontend_created_callback.emit(Box::new(Yew::new(set_ui_props).await));
                 Ok(JsValue::NULL)
             });
+            ctx.link().send_message(AppMsg::CheckForUpdate);
+        }
+
+        // Lay out text and overlays in the direction of the language (e.g. mirrored for Arabic).
+        if let Some(root) = document().document_element() {
+            let language = self.infrastructure.common_settings().language;
+            let _ = root.set_attribute("lang", &language.to_string());
+            let _ = root.set_attribute("dir", language.direction());
         }
         match self.recreating_canvas {
             RecreatingCanvas::None => {}
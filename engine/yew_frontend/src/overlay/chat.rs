@@ -47,7 +47,7 @@ pub fn chat_overlay(props: &ChatProps) -> Html {
 		text-overflow: ellipsis;
 		word-break: normal;
 		user-select: text;
-		text-align: left;
+		text-align: start;
         "#
     );
 
@@ -305,12 +305,17 @@ pub fn chat_overlay(props: &ChatProps) -> Html {
                     onclick={move |_| onclick_reply()}
                     class={if dto.player_id.is_some() { name_css_class.clone() } else { official_name_css_class.clone() }}
                 >
-                    {dto.team_name.map(|team_name| format!("[{}] {}", team_name, dto.alias)).unwrap_or(dto.alias.to_string())}
+                    if let Some(team_name) = dto.team_name {
+                        {"["}<bdi>{team_name}</bdi>{"] "}
+                    }
+                    <bdi>{dto.alias}</bdi>
                 </span>
                 <span class={no_select_style.clone()}>{" "}</span>
-                {segments(&dto.text, &mention_string).map(|Segment{contents, mention}| html_nested!{
-                    <span class={classes!(mention.then(|| mention_style.clone()))}>{contents.to_owned()}</span>
-                }).collect::<Html>()}
+                <bdi>
+                    {segments(&dto.text, &mention_string).map(|Segment{contents, mention}| html_nested!{
+                        <span class={classes!(mention.then(|| mention_style.clone()))}>{contents.to_owned()}</span>
+                    }).collect::<Html>()}
+                </bdi>
             </p>
         }
     }).collect::<Html>();
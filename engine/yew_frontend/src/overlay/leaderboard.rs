@@ -93,11 +93,11 @@ pub fn leaderboard_overlay(props: &LeaderboardProps) -> Html {
 
         td.name {
             font-weight: bold;
-            text-align: left;
+            text-align: start;
         }
 
         td.score {
-            text-align: right;
+            text-align: end;
         }
     "#
     );
@@ -153,22 +153,31 @@ pub fn leaderboard_overlay(props: &LeaderboardProps) -> Html {
                         true,
                     )
                 });
-            let items = core_state.liveboard.iter().map(|dto| (dto.clone(), false)).chain(extra).filter_map(|(dto, fake)| {
-                core_state
-                    .player_or_bot(dto.player_id)
-                    .map(|player| {
+            let items = core_state
+                .liveboard
+                .iter()
+                .map(|dto| (dto.clone(), false))
+                .chain(extra)
+                .filter_map(|(dto, fake)| {
+                    core_state.player_or_bot(dto.player_id).map(|player| {
                         let team_name = dto
                             .team_id
                             .and_then(|team_id| core_state.teams.get(&team_id))
                             .map(|team_dto| team_dto.name);
                         html_nested! {
                             <tr class={fake.then(|| fake_style.clone())}>
-                                <td class="name">{team_name.map(|team_name| format!("[{}] {}", team_name, player.alias)).unwrap_or(player.alias.to_string())}</td>
+                                <td class="name">
+                                    if let Some(team_name) = team_name {
+                                        {"["}<bdi>{team_name}</bdi>{"] "}
+                                    }
+                                    <bdi>{player.alias}</bdi>
+                                </td>
                                 <td class="score">{(props.fmt_score)(dto.score)}</td>
                             </tr>
                         }
                     })
-            }).collect::<Html>();
+                })
+                .collect::<Html>();
 
             (name, items)
         }
@@ -181,7 +190,7 @@ pub fn leaderboard_overlay(props: &LeaderboardProps) -> Html {
                 .map(|dto| {
                     html_nested! {
                         <tr>
-                            <td class="name">{dto.alias}</td>
+                            <td class="name"><bdi>{dto.alias}</bdi></td>
                             <td class="score">{(props.fmt_score)(dto.score)}</td>
                         </tr>
                     }
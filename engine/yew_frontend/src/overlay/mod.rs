@@ -7,3 +7,4 @@ pub mod leaderboard;
 pub(crate) mod reconnecting;
 pub mod spawn;
 pub mod team;
+pub(crate) mod update_available;
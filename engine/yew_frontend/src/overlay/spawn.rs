@@ -52,7 +52,7 @@ pub fn spawn_overlay(props: &DialogProps) -> Html {
         font-weight: bold;
         margin-top: 0.25em;
         outline: 0;
-        padding-left: 2rem;
+        padding-inline-start: 2rem;
         padding: 0.7em;
         pointer-events: all;
         text-align: center;
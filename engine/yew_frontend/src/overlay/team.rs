@@ -8,7 +8,7 @@ use crate::frontend::{use_core_state, use_ctw};
 use crate::translation::Translation;
 use client_util::browser_storage::BrowserStorages;
 use client_util::setting::CommonSettings;
-use core_protocol::dto::{PlayerDto, TeamDto};
+use core_protocol::dto::{PlayerDto, TeamDto, TreasuryTransactionDto};
 use core_protocol::id::{LanguageId, PlayerId, TeamId};
 use core_protocol::name::TeamName;
 use core_protocol::rpc::TeamRequest;
@@ -132,6 +132,12 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
     "#
     );
 
+    let officer_css_class = css!(
+        r#"
+        font-style: italic;
+    "#
+    );
+
     let ctw = use_ctw();
     let t = ctw.setting_cache.language;
     let core_state = use_core_state();
@@ -139,9 +145,16 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
     let team = team_id.and_then(|team_id| core_state.teams.get(&team_id));
     let team_name = team.map(|t| t.name);
     let i_am_team_captain = core_state.player().map(|p| p.team_captain).unwrap_or(false);
+    let treasury = &core_state.treasury;
+    let i_may_grant = i_am_team_captain
+        || core_state
+            .player_id
+            .map(|player_id| treasury.officers.contains(&player_id))
+            .unwrap_or(false);
     let team_full = team.map(|t| t.full).unwrap_or(false);
     let team_request_callback = ctw.team_request_callback;
     let input_ref = use_node_ref();
+    let amount_input_ref = use_node_ref();
     let team_name_empty = use_state_eq(|| true);
     let team_name_exists = use_state_eq(|| true);
 
@@ -223,6 +236,49 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
         }
     };
 
+    let amount = {
+        let amount_input_ref = amount_input_ref.clone();
+        move || {
+            amount_input_ref
+                .cast::<HtmlInputElement>()
+                .and_then(|input| input.value().parse::<u32>().ok())
+                .filter(|&amount| amount > 0)
+        }
+    };
+
+    let on_contribute = {
+        let cb = team_request_callback.clone();
+        let amount = amount.clone();
+        move || {
+            if let Some(amount) = amount() {
+                cb.emit(TeamRequest::Contribute(amount));
+            }
+        }
+    };
+
+    let on_grant = {
+        let cb = team_request_callback.clone();
+        move |player_id: PlayerId| {
+            if let Some(amount) = amount() {
+                cb.emit(TeamRequest::Grant(player_id, amount));
+            }
+        }
+    };
+
+    let on_set_officer = {
+        let cb = team_request_callback.clone();
+        move |player_id: PlayerId, officer: bool| {
+            cb.emit(TeamRequest::SetOfficer(player_id, officer));
+        }
+    };
+
+    let on_set_share = {
+        let cb = team_request_callback.clone();
+        move |share: u8| {
+            cb.emit(TeamRequest::SetShare(share));
+        }
+    };
+
     let on_leave_team = {
         let cb = team_request_callback.clone();
         move || cb.emit(TeamRequest::Leave)
@@ -271,6 +327,10 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
 
     const CHECK_MARK: &'static str = "✔";
     const X_MARK: &'static str = "✘";
+    const GRANT_MARK: &'static str = "$";
+    const OFFICER_MARK: &'static str = "★";
+    const NOT_OFFICER_MARK: &'static str = "☆";
+    const SHARE_STEP: u8 = 5;
 
     // We don't have a dirty flag if teams have changed so assume it has.
     on_new_team_name_change();
@@ -286,14 +346,22 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
             {on_open_changed}
         >
             if team_name.is_some() {
-                <table class={table_css_class}>
+                <table class={table_css_class.clone()}>
                     {core_state.members.iter().filter_map(|player_id| core_state.player_or_bot(*player_id)).map(|PlayerDto{alias, player_id, team_captain, ..}| {
                         let on_kick_from_team = on_kick_from_team.clone();
+                        let on_grant = on_grant.clone();
+                        let on_set_officer = on_set_officer.clone();
+                        let officer = treasury.officers.contains(&player_id);
+                        let me = core_state.player_id == Some(player_id);
 
                         html_nested!{
                             <tr class={tr_css_class.clone()}>
-                                <td class={classes!(name_css_class.clone(), team_captain.then(|| owner_css_class.clone()))}>{alias}</td>
+                                <td class={classes!(name_css_class.clone(), team_captain.then(|| owner_css_class.clone()), officer.then(|| officer_css_class.clone()))}><bdi>{alias}</bdi></td>
+                                if i_may_grant {
+                                    <td><button class={classes!(button_css_class.clone(), me.then(|| hidden_css_class.clone()))} onclick={move |_| on_grant(player_id)} title={t.team_grant_hint()}>{GRANT_MARK}</button></td>
+                                }
                                 if i_am_team_captain {
+                                    <td><button class={classes!(button_css_class.clone(), team_captain.then(|| hidden_css_class.clone()))} onclick={move |_| on_set_officer(player_id, !officer)} title={t.team_officer_hint()}>{if officer { OFFICER_MARK } else { NOT_OFFICER_MARK }}</button></td>
                                     <td><button class={classes!(button_css_class.clone(), hidden_css_class.clone())}>{CHECK_MARK}</button></td>
                                     <td><button class={classes!(button_css_class.clone(), team_captain.then(|| hidden_css_class.clone()))} onclick={move |_| on_kick_from_team(player_id)} title={t.team_kick_hint()}>{X_MARK}</button></td>
                                 }
@@ -305,13 +373,46 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
                         let on_reject_join_team = on_reject_join_team.clone();
                         html_nested!{
                             <tr class={tr_css_class.clone()}>
-                                <td class={classes!(name_css_class.clone(), name_pending_css_class.clone())}>{alias}</td>
+                                <td class={classes!(name_css_class.clone(), name_pending_css_class.clone())}><bdi>{alias}</bdi></td>
                                 <td><button class={classes!(button_css_class.clone(), team_full.then(|| disabled_css_class.clone()))} onclick={move |_| on_accept_join_team(player_id)} title={t.team_accept_hint()}>{CHECK_MARK}</button></td>
                                 <td><button class={button_css_class.clone()} onclick={move |_| on_reject_join_team(player_id)} title={t.team_deny_hint()}>{X_MARK}</button></td>
                             </tr>
                         }
                     }).collect::<Html>()}
                 </table>
+                <table class={table_css_class}>
+                    <tr class={tr_css_class.clone()}>
+                        <td class={name_css_class.clone()}>{t.team_treasury_label()}</td>
+                        <td>{treasury.balance}</td>
+                    </tr>
+                    <tr class={tr_css_class.clone()}>
+                        <td class={name_css_class.clone()}>{t.team_share_label()}</td>
+                        <td>{format!("{}%", treasury.share)}</td>
+                        if i_am_team_captain {
+                            <td><button class={classes!(button_css_class.clone(), (treasury.share == 0).then(|| disabled_css_class.clone()))} onclick={let on_set_share = on_set_share.clone(); let share = treasury.share.saturating_sub(SHARE_STEP); move |_| on_set_share(share)}>{"-"}</button></td>
+                            <td><button class={button_css_class.clone()} onclick={let share = treasury.share.saturating_add(SHARE_STEP); move |_| on_set_share(share)}>{"+"}</button></td>
+                        }
+                    </tr>
+                    {treasury.history.iter().rev().filter_map(|transaction| {
+                        let (player_id, amount) = match *transaction {
+                            TreasuryTransactionDto::Contributed{player_id, amount} => (player_id, amount as i64),
+                            TreasuryTransactionDto::Granted{player_id, amount} => (player_id, -(amount as i64)),
+                        };
+                        core_state.player_or_bot(player_id).map(|PlayerDto{alias, ..}| html_nested!{
+                            <tr class={tr_css_class.clone()}>
+                                <td class={classes!(name_css_class.clone(), name_pending_css_class.clone())}><bdi>{alias}</bdi></td>
+                                <td>{format!("{:+}", amount)}</td>
+                            </tr>
+                        })
+                    }).collect::<Html>()}
+                </table>
+                <input
+                    ref={amount_input_ref}
+                    type="number"
+                    min="1"
+                    class={input_css_class.clone()}
+                />
+                <button onclick={move |_| on_contribute()} class={button_css_class.clone()}>{t.team_contribute_hint()}</button>
                 <button onclick={move |_| on_leave_team()} class={button_css_class}>{t.team_leave_hint()}</button>
             } else {
                 <table>
@@ -321,7 +422,7 @@ pub fn team_overlay(props: &TeamOverlayProps) -> Html {
 
                         html_nested!{
                             <tr>
-                                <td class={name_css_class.clone()}>{name}</td>
+                                <td class={name_css_class.clone()}><bdi>{name}</bdi></td>
                                 <td>
                                     <button type="button" class={classes!(button_css_class.clone(), unavailable.then(|| hidden_css_class.clone()))} onclick={move |_| on_request_join_team(team_id)}>{t.team_request_hint()}</button>
                                 </td>
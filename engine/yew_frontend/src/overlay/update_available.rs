@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::component::positioner::{Position, Positioner};
+use crate::translation::{use_translation, Translation};
+use stylist::yew::styled_component;
+use yew::{classes, html, Callback, Html, MouseEvent, Properties};
+
+#[derive(Properties, PartialEq)]
+pub struct UpdateAvailableProps {
+    pub reload_callback: Callback<MouseEvent>,
+}
+
+/// Prompts the player to reload into a new version of the client, which is already cached.
+#[styled_component(UpdateAvailable)]
+pub fn update_available(props: &UpdateAvailableProps) -> Html {
+    let container_style = css!(
+        r#"
+        background-color: #f6f6f6;
+        border-radius: 0.5rem;
+        box-shadow: 0em 0.25rem 0 #cccccc;
+        color: #000000;
+        padding: 0.5rem 1rem;
+        "#
+    );
+
+    let button_css = css!(
+        r#"
+        background-color: #549f57;
+        border-radius: 0.5rem;
+        border: 1px solid #61b365;
+        color: white;
+        cursor: pointer;
+        font-size: 1rem;
+        margin-inline-start: 1rem;
+        padding: 0.25rem 1rem;
+
+        :hover {
+            filter: brightness(0.95);
+        }
+
+        :active {
+            filter: brightness(0.9);
+        }
+        "#
+    );
+
+    let t = use_translation();
+
+    html! {
+        <Positioner id="update_available" position={Position::TopMiddle{margin: "1rem"}} class={classes!(container_style)}>
+            <span>{t.update_available_message()}</span>
+            <button onclick={props.reload_callback.clone()} class={button_css}>{t.update_reload_label()}</button>
+        </Positioner>
+    }
+}
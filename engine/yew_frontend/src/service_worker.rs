@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use core_protocol::rpc::StatusResponse;
+use gloo_events::EventListener;
+use wasm_bindgen::{JsCast, JsValue};
+use wasm_bindgen_futures::JsFuture;
+use web_sys::{
+    window, Request, RequestInit, RequestMode, Response, ServiceWorker, ServiceWorkerRegistration,
+};
+
+/// Registers the service worker (see the game's `sw.js`) for the version of the client that the
+/// server advertises, so it caches that version. Returns the service worker of a new version, if
+/// that version is cached and waiting to replace the current one.
+pub(crate) async fn update_service_worker() -> Result<Option<ServiceWorker>, String> {
+    let window = window().unwrap();
+    let navigator = window.navigator();
+    if !js_sys::Reflect::has(&navigator, &JsValue::from_str("serviceWorker")).unwrap_or(false) {
+        // Not supported, or not a secure context.
+        return Ok(None);
+    }
+
+    let mut opts = RequestInit::new();
+    opts.method("GET");
+    opts.mode(RequestMode::Cors);
+
+    let request =
+        Request::new_with_str_and_init("/status.json", &opts).map_err(|e| format!("{:?}", e))?;
+    let resp_value = JsFuture::from(window.fetch_with_request(&request))
+        .await
+        .map_err(|e| format!("{:?}", e))?;
+    let resp: Response = resp_value.dyn_into().map_err(|e| format!("{:?}", e))?;
+    let json_promise = resp.text().map_err(|e| format!("{:?}", e))?;
+    let json: String = JsFuture::from(json_promise)
+        .await
+        .map_err(|e| format!("{:?}", e))?
+        .as_string()
+        .ok_or(String::from("JSON not string"))?;
+    let decoded: StatusResponse = serde_json::from_str(&json).map_err(|e| e.to_string())?;
+
+    let client_hash = match decoded.client_hash {
+        Some(client_hash) => client_hash,
+        None => return Ok(None),
+    };
+
+    // Registering a different version installs it alongside the current one, which keeps serving
+    // the page until it is reloaded.
+    let registration: ServiceWorkerRegistration = JsFuture::from(
+        navigator
+            .service_worker()
+            .register(&format!("/sw.js?v={}", client_hash)),
+    )
+    .await
+    .map_err(|e| format!("{:?}", e))?
+    .dyn_into()
+    .map_err(|e| format!("{:?}", e))?;
+
+    Ok(registration.waiting())
+}
+
+/// Replaces the current version of the client with that cached by `waiting`, and reloads.
+pub(crate) fn reload_to_update(waiting: &ServiceWorker) {
+    let window = window().unwrap();
+    let location = window.location();
+    EventListener::once(
+        &window.navigator().service_worker(),
+        "controllerchange",
+        move |_| {
+            let _ = location.reload();
+        },
+    )
+    .forget();
+    let _ = waiting.post_message(&JsValue::from_str("skipWaiting"));
+}
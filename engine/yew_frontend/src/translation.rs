@@ -74,12 +74,17 @@ pub trait Translation: Sized {
     s!(team_label);
     s!(team_accept_hint);
     s!(team_accept_full_hint);
+    s!(team_contribute_hint);
     s!(team_create_hint);
     s!(team_deny_hint);
+    s!(team_grant_hint);
     s!(team_kick_hint);
     s!(team_leave_hint);
     s!(team_name_placeholder);
+    s!(team_officer_hint);
     s!(team_request_hint);
+    s!(team_share_label);
+    s!(team_treasury_label);
 
     // Players online.
     fn online(self, players: u32) -> String;
@@ -114,6 +119,10 @@ pub trait Translation: Sized {
     // Alert
     s!(alert_dismiss);
 
+    // New version of the client.
+    s!(update_available_message);
+    s!(update_reload_label);
+
     // Score.
     s!(point);
     s!(points);
@@ -408,6 +417,23 @@ impl Translation for LanguageId {
         }
     }
 
+    fn team_contribute_hint(self) -> &'static str {
+        match self {
+            Bork => "Bork in",
+            German => "Beitragen",
+            English => "Contribute",
+            Spanish => "Contribuir",
+            French => "Contribuer",
+            Italian => "Contribuisci",
+            Arabic => "ساهم",
+            Japanese => "寄付する",
+            Russian => "Внести",
+            Vietnamese => "Đóng góp",
+            SimplifiedChinese => "捐献",
+            Hindi => "योगदान",
+        }
+    }
+
     fn team_create_hint(self) -> &'static str {
         match self {
             Bork => "Bork",
@@ -442,6 +468,23 @@ impl Translation for LanguageId {
         }
     }
 
+    fn team_grant_hint(self) -> &'static str {
+        match self {
+            Bork => "Bork out",
+            German => "Gewähren",
+            English => "Grant",
+            Spanish => "Conceder",
+            French => "Accorder",
+            Italian => "Concedi",
+            Arabic => "منح",
+            Japanese => "支給する",
+            Russian => "Выдать",
+            Vietnamese => "Cấp",
+            SimplifiedChinese => "拨款",
+            Hindi => "अनुदान",
+        }
+    }
+
     fn team_kick_hint(self) -> &'static str {
         match self {
             Bork => "Unbork",
@@ -493,6 +536,23 @@ impl Translation for LanguageId {
         }
     }
 
+    fn team_officer_hint(self) -> &'static str {
+        match self {
+            Bork => "Bork officer",
+            German => "Offizier",
+            English => "Officer",
+            Spanish => "Oficial",
+            French => "Officier",
+            Italian => "Ufficiale",
+            Arabic => "ضابط",
+            Japanese => "士官",
+            Russian => "Офицер",
+            Vietnamese => "Sĩ quan",
+            SimplifiedChinese => "军官",
+            Hindi => "अधिकारी",
+        }
+    }
+
     fn team_request_hint(self) -> &'static str {
         match self {
             Bork => "Bork",
@@ -510,6 +570,40 @@ impl Translation for LanguageId {
         }
     }
 
+    fn team_share_label(self) -> &'static str {
+        match self {
+            Bork => "Bork share",
+            German => "Anteil",
+            English => "Share",
+            Spanish => "Aporte",
+            French => "Part",
+            Italian => "Quota",
+            Arabic => "حصة",
+            Japanese => "分担率",
+            Russian => "Доля",
+            Vietnamese => "Phần đóng góp",
+            SimplifiedChinese => "分成",
+            Hindi => "हिस्सा",
+        }
+    }
+
+    fn team_treasury_label(self) -> &'static str {
+        match self {
+            Bork => "Borkery",
+            German => "Kasse",
+            English => "Treasury",
+            Spanish => "Tesorería",
+            French => "Trésor",
+            Italian => "Tesoreria",
+            Arabic => "الخزينة",
+            Japanese => "金庫",
+            Russian => "Казна",
+            Vietnamese => "Ngân quỹ",
+            SimplifiedChinese => "金库",
+            Hindi => "खज़ाना",
+        }
+    }
+
     fn online(self, players: u32) -> String {
         match self {
             Bork => format!("{players} borks"),
@@ -803,6 +897,40 @@ impl Translation for LanguageId {
         }
     }
 
+    fn update_available_message(self) -> &'static str {
+        match self {
+            Bork => "New bork available!",
+            German => "Neue Version verfügbar!",
+            English => "New version available!",
+            Spanish => "¡Nueva versión disponible!",
+            French => "Nouvelle version disponible !",
+            Italian => "Nuova versione disponibile!",
+            Arabic => "إصدار جديد متاح!",
+            Japanese => "新しいバージョンが利用可能です！",
+            Russian => "Доступна новая версия!",
+            Vietnamese => "Đã có phiên bản mới!",
+            SimplifiedChinese => "有新版本可用！",
+            Hindi => "नया संस्करण उपलब्ध है!",
+        }
+    }
+
+    fn update_reload_label(self) -> &'static str {
+        match self {
+            Bork => "Rebork",
+            German => "Neu laden",
+            English => "Reload",
+            Spanish => "Recargar",
+            French => "Recharger",
+            Italian => "Ricarica",
+            Arabic => "إعادة تحميل",
+            Japanese => "再読み込み",
+            Russian => "Перезагрузить",
+            Vietnamese => "Tải lại",
+            SimplifiedChinese => "重新加载",
+            Hindi => "पुनः लोड करें",
+        }
+    }
+
     fn point(self) -> &'static str {
         match self {
             Bork => "bork",
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+//! Trains the bots' model on recorded play of real players, and evaluates it against rule-based
+//! bots (see `make train_bots` and `make eval_bots`).
+
+use common::entity::EntityType;
+use common::protocol::{Command, Spawn};
+use common::ticks::Ticks;
+use core_protocol::id::PlayerId;
+use game_server::bot::{BotRepo, PopulationSchedule};
+use game_server::game_service::{self, BotAction, GameArenaService};
+use game_server::load_shedding::LoadShedding;
+use game_server::player::{PlayerData, PlayerRepo, PlayerTuple};
+use game_server::team::TeamRepo;
+use rand::seq::{IteratorRandom, SliceRandom};
+use rand::thread_rng;
+use server::bot::Bot;
+use server::bot_model::{Decision, Mlp, Observation, Sample, MODEL_PATH, RECORDING_PATH};
+use server::{noise, Server};
+use std::fs::File;
+use std::io::{BufRead, BufReader};
+use std::process;
+use std::sync::Arc;
+use structopt::StructOpt;
+
+#[derive(StructOpt)]
+enum Options {
+    /// Trains a model on recorded samples.
+    Train {
+        /// Samples recorded by servers.
+        #[structopt(long, default_value = RECORDING_PATH)]
+        recording: String,
+        /// Where to save the model.
+        #[structopt(long, default_value = MODEL_PATH)]
+        model: String,
+        #[structopt(long, default_value = "50")]
+        epochs: usize,
+        /// Size of the hidden layer.
+        #[structopt(long, default_value = "32")]
+        hidden: usize,
+    },
+    /// Pits bots using a model against rule-based bots, among a population of other bots.
+    Evaluate {
+        #[structopt(long, default_value = MODEL_PATH)]
+        model: String,
+        /// Number of bots that use the model, and, separately, that don't.
+        #[structopt(long, default_value = "20")]
+        bots_per_side: usize,
+        /// Number of other bots, which behave as they would on a real server.
+        #[structopt(long, default_value = "60")]
+        population: usize,
+        /// Length of the match, in simulated seconds.
+        #[structopt(long, default_value = "600")]
+        seconds: usize,
+    },
+}
+
+fn main() {
+    let result = match Options::from_args() {
+        Options::Train {
+            recording,
+            model,
+            epochs,
+            hidden,
+        } => train(&recording, &model, epochs, hidden),
+        Options::Evaluate {
+            model,
+            bots_per_side,
+            population,
+            seconds,
+        } => evaluate(&model, bots_per_side, population, seconds),
+    };
+
+    if let Err(e) = result {
+        eprintln!("{}", e);
+        process::exit(1);
+    }
+}
+
+fn train(recording: &str, model_path: &str, epochs: usize, hidden: usize) -> Result<(), String> {
+    let file = File::open(recording).map_err(|e| format!("error opening {}: {}", recording, e))?;
+    let mut samples: Vec<(Vec<f32>, Vec<f32>)> = BufReader::new(file)
+        .lines()
+        .filter_map(|line| serde_json::from_str::<Sample>(&line.ok()?).ok())
+        .filter(|s| {
+            s.observation.len() == Observation::FEATURES && s.decision.len() == Decision::OUTPUTS
+        })
+        .map(|s| (s.observation, s.decision))
+        .collect();
+    if samples.is_empty() {
+        return Err(format!("no valid samples in {}", recording));
+    }
+
+    let mut rng = thread_rng();
+    samples.shuffle(&mut rng);
+    let validation = samples.split_off(samples.len() * 9 / 10);
+
+    let mut model = Mlp::new(
+        &[Observation::FEATURES, hidden, Decision::OUTPUTS],
+        &mut rng,
+    );
+    for epoch in 0..epochs {
+        let learning_rate = 0.02 * 0.95f32.powi(epoch as i32);
+        model.train(&samples, learning_rate, &mut rng);
+        eprintln!(
+            "epoch {}: train loss {:.4}, validation loss {:.4}",
+            epoch,
+            model.loss(&samples),
+            model.loss(&validation)
+        );
+    }
+
+    model.save(model_path)?;
+    eprintln!("saved {}", model_path);
+    Ok(())
+}
+
+fn evaluate(
+    model_path: &str,
+    bots_per_side: usize,
+    population: usize,
+    seconds: usize,
+) -> Result<(), String> {
+    let model = Arc::new(Mlp::load(model_path)?);
+    if !model.is_compatible() {
+        return Err(format!("model in {} has the wrong shape", model_path));
+    }
+
+    noise::init();
+    let mut service = Server::new(population + bots_per_side * 2);
+    service.world.generate_terrain();
+    let mut players = PlayerRepo::<Server>::new();
+    let mut teams = TeamRepo::<Server>::new();
+    // The population is fixed, regardless of the time of day.
+    let mut others = BotRepo::<Server>::new(
+        population,
+        population,
+        0,
+        PopulationSchedule::new_from_options(None, None, None),
+    );
+
+    // Even indices use the model. Ids follow those of the other bots.
+    let mut contestants: Vec<(Arc<PlayerTuple<Server>>, Bot)> = (0..bots_per_side * 2)
+        .map(|i| {
+            let player_id = PlayerId::nth_bot(population + i).unwrap();
+            let player_tuple = Arc::new(PlayerTuple::new(PlayerData::new(player_id, None)));
+            service.player_joined(&player_tuple, &players);
+            let model = (i % 2 == 0).then(|| Arc::clone(&model));
+            (player_tuple, Bot::with_model(model))
+        })
+        .collect();
+
+    // Deaths of each side.
+    let mut deaths = [0u32; 2];
+    let mut was_alive = vec![false; contestants.len()];
+    let mut rng = thread_rng();
+
+    for _ in 0..seconds * Ticks::FREQUENCY_HZ.0 as usize {
+        others.update_count(&mut service, &mut players, &mut teams, LoadShedding::None);
+        others.update(&service, &players, &teams);
+
+        for (i, (player_tuple, bot)) in contestants.iter_mut().enumerate() {
+            let alive = service.is_alive(player_tuple);
+            if was_alive[i] && !alive {
+                deaths[i % 2] += 1;
+            }
+            was_alive[i] = alive;
+
+            let player_id = player_tuple.borrow_player().player_id;
+            let input =
+                <Bot as game_service::Bot<Server>>::get_input(&service, player_tuple, &players);
+            let action = game_service::Bot::update(bot, input, player_id, &players, &teams);
+            let command = match action {
+                BotAction::Some(command) => command,
+                BotAction::Quit => {
+                    // Respawn instead, to keep the sides even.
+                    Command::Spawn(Spawn {
+                        entity_type: EntityType::spawn_options(0, true).choose(&mut rng).unwrap(),
+                    })
+                }
+                BotAction::Team(_) | BotAction::None => continue,
+            };
+            let _ = service.player_command(command, player_tuple, &players);
+        }
+
+        service.world.update(Ticks::ONE);
+        others.post_update(&mut service, &mut players, &mut teams);
+    }
+
+    for (side, name) in ["model", "rules"].iter().enumerate() {
+        let score: u32 = contestants
+            .iter()
+            .skip(side)
+            .step_by(2)
+            .map(|(player_tuple, _)| player_tuple.borrow_player().score)
+            .sum();
+        println!(
+            "{}: mean score {:.1}, deaths {}",
+            name,
+            score as f32 / bots_per_side.max(1) as f32,
+            deaths[side]
+        );
+    }
+    Ok(())
+}
@@ -1,25 +1,33 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::bot_model::{Decision, Mlp, Observation, MODEL};
 use crate::complete_ref::CompleteRef;
 use crate::contact_ref::ContactRef;
+use crate::escort::formation_position;
+use crate::player::Status;
 use crate::server::Server;
+use crate::world::World;
 use common::altitude::Altitude;
 use common::angle::Angle;
 use common::complete::CompleteTrait;
 use common::contact::ContactTrait;
 use common::entity::*;
+use common::firing_solution::{intercept, time_to_travel, Trajectory};
 use common::guidance::Guidance;
 use common::protocol::*;
-use common::terrain;
 use common::terrain::Terrain;
 use common_util::range::gen_radius;
 use core_protocol::id::PlayerId;
+use core_protocol::name::TeamName;
+use core_protocol::rpc::TeamRequest;
 use game_server::game_service::{BotAction, GameArenaService};
 use game_server::player::{PlayerRepo, PlayerTuple};
+use game_server::team::{TeamData, TeamRepo};
 use glam::Vec2;
+use rand::distributions::{Distribution, Standard};
 use rand::rngs::ThreadRng;
-use rand::seq::IteratorRandom;
+use rand::seq::{IteratorRandom, SliceRandom};
 use rand::{thread_rng, Rng};
 use std::sync::Arc;
 
@@ -29,14 +37,27 @@ pub struct Bot {
     aggression: f32,
     /// Amount to offset steering by. This creates more interesting behavior.
     steer_bias: Angle,
-    /// Amount to offset aiming by. This creates more interesting hit patterns.
-    aim_bias: Vec2,
+    /// Determines how accurately the bot aims.
+    tier: BotTier,
+    /// Amount to offset aiming by, as a fraction of the distance to the target, before scaling by
+    /// `tier`. Changes after each shot, which creates more interesting hit patterns.
+    aim_error: Vec2,
     /// Maximum level bot will try to upgrade to, randomized to improve variety of bots.
     level_ambition: u8,
+    /// Whether the bot creates and joins teams.
+    sociable: bool,
+    /// Learned policy that overrides steering, submerging, and when to fire, if any.
+    model: Option<Arc<Mlp>>,
+    /// The model's most recent decision, which is followed until the next one.
+    decision: Option<Decision>,
+    /// Updates until the model is consulted again, which staggers the bots' use of it.
+    decision_delay: u8,
     /// Whether the bot spawned at least once, and therefore is capable of rage-quitting.
     spawned_at_least_once: bool,
     /// The value of submerge previously sent.
     was_submerging: bool,
+    /// The player this bot was hired to escort, if any.
+    escort: Option<PlayerId>,
 }
 
 impl Default for Bot {
@@ -51,11 +72,126 @@ impl Default for Bot {
             // Raise aggression to a power such that lower values are more common.
             aggression: rng.gen::<f32>().powi(2) * Self::MAX_AGGRESSION,
             steer_bias: rng.gen::<Angle>() * 0.1,
-            aim_bias: gen_radius(&mut rng, 10.0),
+            tier: rng.gen(),
+            aim_error: gen_radius(&mut rng, 1.0),
             // Bias towards lower levels.
             level_ambition: random_level(&mut rng).min(random_level(&mut rng)),
+            sociable: rng.gen_bool(0.6),
+            // Only half of bots use the model, to preserve variety.
+            model: MODEL.clone().filter(|_| rng.gen_bool(0.5)),
+            decision: None,
+            decision_delay: rng.gen_range(0..Self::DECISION_PERIOD),
             spawned_at_least_once: false,
             was_submerging: false,
+            escort: None,
+        }
+    }
+}
+
+/// Statistics about boats, for tuning the bot population. Updated periodically by the server.
+#[derive(Clone, Debug, Default)]
+pub struct PopulationStats {
+    /// Number of real players' boats at each level.
+    real_levels: [u32; EntityData::MAX_BOAT_LEVEL as usize + 1],
+    /// Total visual area of real players' boats, counting players that are yet to (re)spawn as
+    /// having the smallest boat.
+    real_visual_area: f32,
+    /// Average visual area of bots' boats, if any.
+    bot_visual_area: Option<f32>,
+}
+
+impl PopulationStats {
+    pub fn new(world: &World, players: &PlayerRepo<Server>) -> Self {
+        let mut stats = Self::default();
+        let mut bot_visual_area_sum = 0.0;
+        let mut bot_count = 0u32;
+        let smallest = EntityType::FairmileD.data().visual_area();
+
+        for player in players.iter_borrow() {
+            if player.data.flags.left_game {
+                continue;
+            }
+            let data = if let Status::Alive { entity_index, .. } = player.data.status {
+                Some(world.entities[entity_index].data())
+            } else {
+                None
+            };
+            if player.data.owner.is_some() {
+                // Escorts are hired, not part of the population.
+                continue;
+            } else if player.is_bot() {
+                if let Some(data) = data {
+                    bot_visual_area_sum += data.visual_area();
+                    bot_count += 1;
+                }
+            } else if let Some(data) = data {
+                stats.real_levels[data.level as usize] += 1;
+                stats.real_visual_area += data.visual_area();
+            } else if player.client.is_some() {
+                stats.real_visual_area += smallest;
+            }
+        }
+
+        stats.bot_visual_area = (bot_count > 0).then(|| bot_visual_area_sum / bot_count as f32);
+        stats
+    }
+
+    /// Picks the level of a random real player's boat, if any.
+    fn random_real_level(&self, rng: &mut impl Rng) -> Option<u8> {
+        let total: u32 = self.real_levels.iter().sum();
+        if total == 0 {
+            return None;
+        }
+        let mut n = rng.gen_range(0..total);
+        self.real_levels
+            .iter()
+            .enumerate()
+            .find_map(|(level, &count)| {
+                if n < count {
+                    Some(level as u8)
+                } else {
+                    n -= count;
+                    None
+                }
+            })
+    }
+}
+
+/// Difficulty tier of a bot.
+#[derive(Copy, Clone, Debug, Eq, PartialEq)]
+pub enum BotTier {
+    Novice,
+    Regular,
+    Veteran,
+}
+
+impl BotTier {
+    /// How much of the target's predicted movement the bot leads by.
+    fn lead(self) -> f32 {
+        match self {
+            Self::Novice => 0.5,
+            Self::Regular => 0.9,
+            Self::Veteran => 1.0,
+        }
+    }
+
+    /// Maximum aim error, as a fraction of the distance to the target.
+    fn inaccuracy(self) -> f32 {
+        match self {
+            Self::Novice => 0.15,
+            Self::Regular => 0.07,
+            Self::Veteran => 0.02,
+        }
+    }
+}
+
+impl Distribution<BotTier> for Standard {
+    fn sample<R: Rng + ?Sized>(&self, rng: &mut R) -> BotTier {
+        // Most bots are regulars.
+        match rng.gen_range(0..10) {
+            0..=2 => BotTier::Novice,
+            3..=8 => BotTier::Regular,
+            _ => BotTier::Veteran,
         }
     }
 }
@@ -64,29 +200,192 @@ impl Bot {
     /// This arbitrary value controls how chill the bots are. If too high, bots are trigger-happy
     /// maniacs, and the waters get filled with stray torpedoes.
     const MAX_AGGRESSION: f32 = 0.1;
+    /// Chance, per update, of considering a team request.
+    const TEAM_DECISION_PROBABILITY: f64 = 0.01;
+    /// Chance of accepting a real player into a team. Low, so that teaming with bots is a rare
+    /// treat rather than a way to farm them.
+    const HUMAN_JOIN_PROBABILITY: f64 = 0.1;
+    /// Visual area of bots' boats per visual area of real players' boats. Since higher level boats
+    /// see further, they are surrounded by more bots.
+    const BOT_DENSITY: f32 = 1.5;
+    /// Chance of a bot aspiring to the level of a random real player, instead of a random level.
+    const COMPLEMENT_PROBABILITY: f64 = 0.5;
+    /// Weight of team behaviors (escorting, retreating), relative to the other movement weights.
+    const TEAM_WEIGHT: f32 = 0.02;
+    /// Weight of keeping station in an escort formation, relative to the other movement weights.
+    const FORMATION_WEIGHT: f32 = 0.05;
+    /// Updates between consulting the model, since observing is costly and the model's decisions
+    /// don't need to change every tick.
+    const DECISION_PERIOD: u8 = 5;
+    /// Names of teams created by bots.
+    const TEAM_NAMES: &'static [&'static str] = &[
+        "Armada", "Convoy", "Fleet", "Kraken", "Orcas", "Sharks", "Storm", "Tide", "Wolves",
+    ];
+
+    /// Creates a bot with a specific model (or lack thereof), for evaluating models.
+    pub fn with_model(model: Option<Arc<Mlp>>) -> Self {
+        Self {
+            model,
+            ..Self::default()
+        }
+    }
+
+    /// Creates a bot that escorts `owner`, engaging their target or whoever is nearest them.
+    pub(crate) fn escort(owner: PlayerId) -> Self {
+        Self {
+            // Escorts are always eager to fire.
+            aggression: Self::MAX_AGGRESSION,
+            steer_bias: Angle::ZERO,
+            tier: BotTier::Regular,
+            // Escorts never upgrade.
+            level_ambition: 0,
+            sociable: false,
+            model: None,
+            escort: Some(owner),
+            ..Self::default()
+        }
+    }
 
-    /// Returns true if there is land or border at the given position.
-    fn is_land_or_border(pos: Vec2, terrain: &Terrain, world_radius: f32) -> bool {
+    /// Returns true if there is land (that a boat of type `data` can't cross) or border at the
+    /// given position.
+    pub(crate) fn is_land_or_border(
+        pos: Vec2,
+        data: &EntityData,
+        terrain: &Terrain,
+        world_radius: f32,
+    ) -> bool {
         if pos.length_squared() > world_radius.powi(2) {
             return true;
         }
 
-        terrain.sample(pos).unwrap_or(Altitude::MIN) >= terrain::SAND_LEVEL
+        terrain.sample(pos).unwrap_or(Altitude::MIN) >= data.impassable_altitude()
+    }
+
+    /// Returns true if any of the friendly boats, given as positions and radii, are within
+    /// `clearance` of the line of fire from `start` to `end`.
+    fn is_occluded(start: Vec2, end: Vec2, clearance: f32, friendlies: &[(Vec2, f32)]) -> bool {
+        let line = end - start;
+        let length_squared = line.length_squared().max(f32::EPSILON);
+        friendlies.iter().any(|&(position, radius)| {
+            let t = ((position - start).dot(line) / length_squared).clamp(0.0, 1.0);
+            position.distance_squared(start + line * t) < (radius + clearance).powi(2)
+        })
+    }
+
+    /// Decides whether to create, join, or leave a team, or, as captain, to accept or reject the
+    /// first player requesting to join.
+    fn team_request(
+        &self,
+        player_id: PlayerId,
+        team: Option<&TeamData<Server>>,
+        nearby_bots: &[PlayerId],
+        players: &PlayerRepo<Server>,
+        teams: &TeamRepo<Server>,
+        rng: &mut ThreadRng,
+    ) -> Option<TeamRequest> {
+        let players_online = players.real_players_live();
+        if let Some(team) = team {
+            if team.is_captain(player_id) {
+                let joiner = team.joiners().next()?;
+                let accept = !team.is_full(players_online)
+                    && (joiner.is_bot() || rng.gen_bool(Self::HUMAN_JOIN_PROBABILITY));
+                Some(if accept {
+                    TeamRequest::Accept(joiner)
+                } else {
+                    TeamRequest::Reject(joiner)
+                })
+            } else {
+                // Occasionally go solo, for variety.
+                rng.gen_bool(0.02).then_some(TeamRequest::Leave)
+            }
+        } else if self.sociable {
+            // Prefer joining a nearby team of bots to creating a new one.
+            let join = nearby_bots
+                .iter()
+                .filter_map(|&id| players.borrow_player(id)?.team_id())
+                .find(|&team_id| {
+                    teams.get(team_id).map_or(false, |team| {
+                        team.members.peek_front().map_or(false, PlayerId::is_bot)
+                            && !team.is_closed()
+                            && !team.is_full(players_online)
+                    })
+                });
+
+            if let Some(team_id) = join {
+                Some(TeamRequest::Join(team_id))
+            } else if rng.gen_bool(0.2) {
+                Self::TEAM_NAMES
+                    .choose(rng)
+                    .map(|name| TeamRequest::Create(TeamName::new_unsanitized(name)))
+            } else {
+                None
+            }
+        } else {
+            None
+        }
+    }
+
+    /// Returns the owner of an escort, or the player itself if it isn't an escort. Escorts are
+    /// friendly to whomever their owner is friendly to.
+    fn principal(player_id: PlayerId, players: &PlayerRepo<Server>) -> PlayerId {
+        if !player_id.is_bot() {
+            return player_id;
+        }
+        players
+            .borrow_player(player_id)
+            .and_then(|p| {
+                p.data
+                    .owner
+                    .as_ref()
+                    .map(|owner| owner.borrow_player().player_id)
+            })
+            .unwrap_or(player_id)
     }
 
     /// update processes a complete update and returns some command (or None to quit).
-    fn update<'a, U: 'a + CompleteTrait<'a>>(
+    /// `is_friendly` returns whether contacts owned by a given player, if any, are friendly.
+    pub(crate) fn update<'a, U: 'a + CompleteTrait<'a>>(
         &mut self,
         mut update: U,
         player_id: PlayerId,
+        players: &PlayerRepo<Server>,
+        teams: &TeamRepo<Server>,
+        is_friendly: impl Fn(Option<PlayerId>) -> bool,
     ) -> BotAction<Command> {
         let mut rng = thread_rng();
 
-        let mut contacts = update.contacts();
+        // The owner's id and aim target, and this bot's formation slot, if escorting.
+        let escort = if let Some(owner_id) = self.escort {
+            let escort = players.borrow_player(owner_id).and_then(|owner| {
+                if owner.data.flags.left_game {
+                    return None;
+                }
+                if let Status::Alive { aim_target, .. } = owner.data.status {
+                    let slot = owner.data.escorts.iter().position(|&id| id == player_id);
+                    Some((owner_id, aim_target, slot.unwrap_or(0)))
+                } else {
+                    None
+                }
+            });
+            if escort.is_none() {
+                // Escorts leave along with their owner.
+                return BotAction::Quit;
+            }
+            escort
+        } else {
+            None
+        };
+
+        let team = players
+            .borrow_player(Self::principal(player_id, players))
+            .and_then(|p| p.team_id())
+            .and_then(|team_id| teams.get(team_id));
+
+        let contacts = update.collect_contacts();
         let terrain = update.terrain();
 
         if let Some(boat) = contacts
-            .next()
+            .first()
             .filter(|c| c.is_boat() && c.player_id() == Some(player_id))
         {
             self.spawned_at_least_once = true;
@@ -120,6 +419,7 @@ impl Bot {
                 let delta_position = angle.to_vec() * data.length;
                 if Self::is_land_or_border(
                     boat.transform().position + delta_position,
+                    data,
                     terrain,
                     update.world_radius(),
                 ) {
@@ -127,10 +427,24 @@ impl Bot {
                 }
             }
 
-            let mut closest_enemy: Option<(U::Contact, f32)> = None;
+            // Potential targets, and their distance squared.
+            let mut enemies: Vec<(&U::Contact, f32)> = Vec::new();
+            // Positions and radii of friendly boats, which shouldn't be fired through.
+            let mut friendlies: Vec<(Vec2, f32)> = Vec::new();
+            // Position, radius, and strength of teammates' boats.
+            let mut teammates: Vec<(Vec2, f32, f32)> = Vec::new();
+            // Sum of positions and strength of hostile boats.
+            let mut hostile_position_sum = Vec2::ZERO;
+            let mut hostile_count = 0u32;
+            let mut hostile_strength = 0.0;
+            // Other bots, which might be on a team worth joining.
+            let mut nearby_bots: Vec<PlayerId> = Vec::new();
+            let strength = |level: u8, damage: f32, max_health: f32| {
+                level as f32 * (1.0 - damage / max_health).max(0.0)
+            };
 
             // Scan sensor contacts to help make decisions.
-            for contact in contacts {
+            for contact in contacts.iter() {
                 if contact.id() == boat.id() {
                     // Skip processing self.
                     continue;
@@ -140,7 +454,29 @@ impl Bot {
                     let delta_position = contact.transform().position - boat.transform().position;
                     let distance_squared = delta_position.length_squared();
 
-                    let friendly = contact.player_id() == Some(player_id);
+                    let friendly = is_friendly(contact.player_id());
+
+                    if contact_data.kind == EntityKind::Boat {
+                        let boat_strength = strength(
+                            contact_data.level,
+                            contact.damage().to_secs(),
+                            contact_data.max_health().to_secs(),
+                        );
+                        if friendly {
+                            teammates.push((
+                                contact.transform().position,
+                                contact_data.radius,
+                                boat_strength,
+                            ));
+                        } else {
+                            hostile_position_sum += contact.transform().position;
+                            hostile_count += 1;
+                            hostile_strength += boat_strength;
+                            if let Some(id) = contact.player_id().filter(|id| id.is_bot()) {
+                                nearby_bots.push(id);
+                            }
+                        }
+                    }
 
                     if contact_data.kind == EntityKind::Collectible {
                         attract(&mut movement, delta_position, distance_squared);
@@ -159,15 +495,18 @@ impl Bot {
                                 delta_position,
                                 data.radius + contact_data.radius,
                             );
+                            friendlies.push((contact.transform().position, contact_data.radius));
                         }
                     } else if match contact_data.kind {
-                        // Don't kill smol/peaceful boats unless they get too close.
+                        // Don't kill smol/peaceful boats unless they get too close, or escorting
+                        // (in which case the owner picks fights).
                         EntityKind::Boat => {
-                            (contact_data.level + 1 >= data.level
-                                && !matches!(
-                                    contact_data.sub_kind,
-                                    EntitySubKind::Dredger | EntitySubKind::Icebreaker
-                                ))
+                            escort.is_some()
+                                || (contact_data.level + 1 >= data.level
+                                    && !matches!(
+                                        contact_data.sub_kind,
+                                        EntitySubKind::Dredger | EntitySubKind::Icebreaker
+                                    ))
                                 || contact.player_id().map(|id| id.is_bot()).unwrap_or(false)
                                 || distance_squared < 1.5 * data.radius.powi(2)
                                 || health_percent < 1.0 / 3.0
@@ -187,22 +526,97 @@ impl Bot {
                         }
                         _ => false,
                     } {
-                        if let Some(existing) = &closest_enemy {
-                            if distance_squared < existing.1 {
-                                closest_enemy = Some((contact, distance_squared));
-                            }
-                        } else {
-                            closest_enemy = Some((contact, distance_squared));
-                        }
+                        enemies.push((contact, distance_squared));
                     }
                 }
             }
 
-            let mut best_firing_solution = None;
+            if escort.is_none() && rng.gen_bool(Self::TEAM_DECISION_PROBABILITY) {
+                if let Some(request) =
+                    self.team_request(player_id, team, &nearby_bots, players, teams, &mut rng)
+                {
+                    return BotAction::Team(request);
+                }
+            }
+
+            let position = boat.transform().position;
+            let own_strength = strength(
+                data.level,
+                boat.damage().to_secs(),
+                data.max_health().to_secs(),
+            );
+
+            // Teammates (which see each other's contacts) coordinate, without communicating, by
+            // targeting the enemy closest to the center of the team.
+            let team_center = teammates
+                .iter()
+                .fold(position, |sum, teammate| sum + teammate.0)
+                / (teammates.len() + 1) as f32;
+
+            // Escorts engage their owner's target, or else defend their owner.
+            let owner_boat = escort.and_then(|(owner_id, _, _)| {
+                contacts
+                    .iter()
+                    .find(|c| c.is_boat() && c.player_id() == Some(owner_id))
+            });
+            let rally = escort
+                .and_then(|(_, aim_target, _)| aim_target)
+                .or_else(|| owner_boat.map(|owner_boat| owner_boat.transform().position))
+                .unwrap_or(team_center);
+
+            let closest_enemy = enemies.into_iter().min_by(|a, b| {
+                let rank = |enemy: &(&U::Contact, f32)| {
+                    enemy.0.transform().position.distance_squared(rally)
+                };
+                rank(a).total_cmp(&rank(b))
+            });
 
-            if let Some((enemy, _)) = closest_enemy {
+            // Retreat together if outmatched (escorts stand by their owner instead).
+            let team_strength = teammates
+                .iter()
+                .fold(own_strength, |sum, teammate| sum + teammate.2);
+            let retreating =
+                escort.is_none() && hostile_count > 0 && hostile_strength > team_strength * 1.5;
+            // Transform of the owner's boat, if keeping station in formation.
+            let mut station_keeping = None;
+            if let (Some((_, _, slot)), Some(owner_boat)) = (escort, owner_boat) {
+                let station =
+                    formation_position(owner_boat.transform(), owner_boat.data().radius, slot);
+                let delta = station - position;
+                let distance = delta.length();
+                if distance < data.length {
+                    station_keeping = Some(*owner_boat.transform());
+                } else {
+                    movement += delta / distance * Self::FORMATION_WEIGHT;
+                }
+            } else if retreating {
+                let hostile_center = hostile_position_sum / hostile_count as f32;
+                movement += ((position - hostile_center).normalize_or_zero()
+                    + (team_center - position).normalize_or_zero() * 0.5)
+                    * Self::TEAM_WEIGHT;
+            } else if let Some(&(weak_position, weak_radius, _)) = teammates
+                .iter()
+                .filter(|teammate| teammate.2 < own_strength * 0.75)
+                .min_by(|a, b| a.2.total_cmp(&b.2))
+            {
+                // Escort the weakest teammate.
+                let delta = weak_position - position;
+                let distance = delta.length();
+                let desired_distance = data.radius + weak_radius + data.length;
+                if distance > desired_distance {
+                    movement += delta / distance
+                        * Self::TEAM_WEIGHT
+                        * ((distance - desired_distance) / desired_distance).min(1.0);
+                }
+            }
+
+            // Armament index, aim target, and score (lower is better).
+            let mut best_firing_solution: Option<(u8, Vec2, f32)> = None;
+
+            if let Some((enemy, distance_squared)) = closest_enemy {
                 let reloads = boat.reloads();
                 let enemy_data = enemy.data();
+                let distance = distance_squared.sqrt();
                 for (i, armament) in data.armaments.iter().enumerate() {
                     if !reloads[i] {
                         // Not yet reloaded.
@@ -275,7 +689,59 @@ impl Bot {
                     }
 
                     let transform = *boat.transform() + data.armament_transform(boat.turrets(), i);
-                    let angle = Angle::from(enemy.transform().position - transform.position);
+
+                    // Where to aim, and how long the armament would take to get there.
+                    let (target, seconds) = if Trajectory::of(armament_entity_data).is_some() {
+                        let solution = match intercept(
+                            armament_entity_data,
+                            &transform,
+                            enemy_data,
+                            *enemy.transform(),
+                            *enemy.guidance(),
+                        ) {
+                            Some(solution) if solution.in_range => solution,
+                            // Don't waste shots on targets that can't be reached.
+                            _ => continue,
+                        };
+
+                        if Self::is_occluded(
+                            transform.position,
+                            solution.position,
+                            armament_entity_data.width,
+                            &friendlies,
+                        ) {
+                            continue;
+                        }
+
+                        let target = enemy
+                            .transform()
+                            .position
+                            .lerp(solution.position, self.tier.lead());
+                        (target, solution.seconds)
+                    } else {
+                        let in_range = match armament_entity_data.sub_kind {
+                            // Dropped in place.
+                            EntitySubKind::DepthCharge | EntitySubKind::Mine => {
+                                distance < data.radius + enemy_data.radius
+                            }
+                            // Aircraft fly to the target.
+                            _ if armament_entity_data.kind == EntityKind::Aircraft => {
+                                distance < armament_entity_data.range.max(data.sensors.max_range())
+                            }
+                            _ => true,
+                        };
+                        if !in_range {
+                            continue;
+                        }
+                        let seconds = time_to_travel(
+                            armament_entity_data,
+                            transform.velocity.to_mps(),
+                            distance,
+                        );
+                        (enemy.transform().position, seconds)
+                    };
+
+                    let angle = Angle::from(target - transform.position);
 
                     let mut angle_diff = (angle - transform.direction).abs();
                     if armament.vertical
@@ -291,14 +757,12 @@ impl Bot {
                         continue;
                     }
 
-                    let firing_solution = (i as u8, enemy.transform().position, angle_diff);
-
-                    if firing_solution.2
-                        < best_firing_solution
-                            .map(|s: (u8, Vec2, Angle)| s.2)
-                            .unwrap_or(Angle::MAX)
-                    {
-                        best_firing_solution = Some(firing_solution);
+                    // Prefer armaments that will hit soon, without having to turn much.
+                    let score = seconds.min(60.0) * (1.0 + angle_diff.to_radians().abs());
+                    if best_firing_solution.map_or(true, |s| score < s.2) {
+                        let error = self.aim_error
+                            * (target.distance(transform.position) * self.tier.inaccuracy());
+                        best_firing_solution = Some((i as u8, target + error, score));
                     }
                 }
             }
@@ -319,19 +783,98 @@ impl Bot {
                 false
             };
 
+            let mut guidance = Guidance {
+                direction_target: Angle::from(movement) + self.steer_bias,
+                velocity_target: data.speed
+                    * if retreating || escort.is_some() {
+                        1.0
+                    } else {
+                        0.8
+                    },
+            };
+            if let Some(owner_transform) = station_keeping {
+                // Match the owner's course and speed.
+                guidance.direction_target = owner_transform.direction;
+                guidance.velocity_target = owner_transform.velocity;
+            }
+            let mut fire = rng.gen_bool(self.aggression as f64);
+
+            if let Some(model) = &self.model {
+                // The model decides how to move and when to fire, but the rules above still pick
+                // the armament and aim.
+                let decision = match self.decision {
+                    Some(decision) if self.decision_delay > 0 => {
+                        self.decision_delay -= 1;
+                        decision
+                    }
+                    _ => {
+                        let observation =
+                            Observation::new(boat, &contacts, terrain, update.world_radius());
+                        let decision = Decision::from_outputs(&model.forward(&observation.0));
+                        self.decision = Some(decision);
+                        self.decision_delay = Self::DECISION_PERIOD - 1;
+                        decision
+                    }
+                };
+                // The model may not have learned to avoid land and the border, so it isn't allowed
+                // to steer towards them.
+                let model_guidance = decision.guidance(boat);
+                let ahead = model_guidance.direction_target.to_vec() * data.length;
+                if !(1..=2).any(|i| {
+                    Self::is_land_or_border(
+                        position + ahead * i as f32,
+                        terrain,
+                        update.world_radius(),
+                    )
+                }) {
+                    guidance = model_guidance;
+                }
+                self.was_submerging = decision.submerge(boat);
+                fire = decision.fire;
+            }
+
+            // Fire torpedoes in a spread, using every tube that is ready.
+            let salvo = best_firing_solution
+                .filter(|_| fire)
+                .and_then(|(best_index, _, _)| {
+                    let armament_type = data.armaments[best_index as usize].entity_type;
+                    let sub_kind = armament_type.data().sub_kind;
+                    if sub_kind != EntitySubKind::Torpedo {
+                        return None;
+                    }
+                    let reloads = boat.reloads();
+                    let armament_indices: Vec<u8> = data
+                        .armaments
+                        .iter()
+                        .enumerate()
+                        .filter(|(i, armament)| {
+                            armament.entity_type == armament_type
+                                && reloads[*i]
+                                && armament.turret.map_or(true, |t| {
+                                    data.turrets[t].within_azimuth(boat.turrets()[t])
+                                })
+                        })
+                        .map(|(i, _)| i as u8)
+                        .take(Salvo::MAX_ARMAMENTS)
+                        .collect();
+                    (armament_indices.len() > 1).then(|| Salvo::new(sub_kind, armament_indices))
+                });
+
+            if fire && best_firing_solution.is_some() {
+                self.aim_error = gen_radius(&mut rng, 1.0);
+            }
+
             let mut ret = Command::Control(Control {
-                guidance: Some(Guidance {
-                    direction_target: Angle::from(movement) + self.steer_bias,
-                    velocity_target: data.speed * 0.8,
-                }),
+                guidance: Some(guidance),
                 submerge: self.was_submerging,
-                aim_target: best_firing_solution.map(|solution| solution.1 + self.aim_bias),
+                aim_target: best_firing_solution.map(|solution| solution.1),
                 active: health_percent >= 0.5,
                 fire: best_firing_solution
-                    .filter(|_| rng.gen_bool(self.aggression as f64))
-                    .map(|sol| Fire {
-                        armament_index: sol.0,
-                    }),
+                    .filter(|_| fire && salvo.is_none())
+                    .map(|sol| Fire::new(sol.0)),
+                salvo,
+                point_defense: PointDefense::default(),
+                hover: false,
                 pay: None,
                 hint: None,
             });
@@ -347,6 +890,9 @@ impl Bot {
             }
 
             BotAction::Some(ret)
+        } else if self.escort.is_some() {
+            // Escorts are spawned by their owner, and don't respawn.
+            BotAction::Quit
         } else if self.spawned_at_least_once && rng.gen_bool(1.0 / 3.0) {
             // Rage quit.
             BotAction::Quit
@@ -363,6 +909,27 @@ impl Bot {
 impl game_server::game_service::Bot<Server> for Bot {
     type Input<'a> = CompleteRef<'a, impl Iterator<Item = ContactRef<'a>>>;
 
+    fn new(server: &Server, _players: &PlayerRepo<Server>) -> Self {
+        let mut bot = Self::default();
+        let mut rng = thread_rng();
+        // Keep new players company with bots of similar levels.
+        if rng.gen_bool(Self::COMPLEMENT_PROBABILITY) {
+            if let Some(level) = server.population.random_real_level(&mut rng) {
+                bot.level_ambition =
+                    (level + rng.gen_range(0..=1)).clamp(1, EntityData::MAX_BOAT_LEVEL);
+            }
+        }
+        bot
+    }
+
+    fn target_count(server: &Server, _players: &PlayerRepo<Server>, factor: f32) -> Option<usize> {
+        let stats = &server.population;
+        let bot_visual_area = stats
+            .bot_visual_area
+            .unwrap_or_else(|| EntityType::FairmileD.data().visual_area());
+        Some((Self::BOT_DENSITY * factor * stats.real_visual_area / bot_visual_area) as usize)
+    }
+
     fn get_input<'a>(
         server: &'a Server,
         player: &'a Arc<PlayerTuple<Server>>,
@@ -375,8 +942,165 @@ impl game_server::game_service::Bot<Server> for Bot {
         &mut self,
         update: Self::Input<'_>,
         player_id: PlayerId,
-        _players: &PlayerRepo<Server>,
+        players: &PlayerRepo<Server>,
+        teams: &TeamRepo<Server>,
     ) -> BotAction<<Server as GameArenaService>::GameRequest> {
-        self.update(update, player_id)
+        let own_principal = Self::principal(player_id, players);
+        let team = players
+            .borrow_player(own_principal)
+            .and_then(|p| p.team_id())
+            .and_then(|team_id| teams.get(team_id));
+
+        self.update(update, player_id, players, teams, |id| {
+            id.map_or(false, |id| {
+                let id = Self::principal(id, players);
+                id == own_principal || team.map_or(false, |team| team.is_member(id))
+            })
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::bot::Bot;
+    use crate::bot_model::{Decision, Mlp};
+    use crate::entity::Entity;
+    use crate::world::World;
+    use crate::world_test::player;
+    use common::angle::Angle;
+    use common::entity::EntityType;
+    use common::protocol::Command;
+    use common::terrain;
+    use common::terrain::Terrain;
+    use game_server::game_service::BotAction;
+    use game_server::player::PlayerRepo;
+    use game_server::team::TeamRepo;
+    use glam::Vec2;
+    use std::sync::Arc;
+
+    #[test]
+    fn is_occluded() {
+        let friendlies = [(Vec2::new(50.0, 5.0), 10.0)];
+        let start = Vec2::ZERO;
+        assert!(Bot::is_occluded(
+            start,
+            Vec2::new(100.0, 0.0),
+            1.0,
+            &friendlies
+        ));
+        assert!(!Bot::is_occluded(
+            start,
+            Vec2::new(0.0, 100.0),
+            1.0,
+            &friendlies
+        ));
+        // Friendly is beyond the target.
+        assert!(!Bot::is_occluded(
+            start,
+            Vec2::new(30.0, 0.0),
+            1.0,
+            &friendlies
+        ));
+    }
+
+    /// Bots must not fire through teammates, even though teammates' boats aren't their own.
+    #[test]
+    fn teammate_blocks_line_of_fire() {
+        let aim_target = |teammate_position: Vec2| {
+            let mut world = World::new(10000.0);
+            world.terrain = Terrain::new();
+
+            let bot_player = player(1);
+            let teammate = player(2);
+            let enemy = player(3);
+            let bot_player_id = bot_player.borrow_player().player_id;
+            let teammate_id = teammate.borrow_player().player_id;
+
+            assert!(world.try_spawn(Entity::new(EntityType::G5, Some(Arc::clone(&bot_player)))));
+
+            let mut teammate_boat = Entity::new(EntityType::G5, Some(teammate));
+            teammate_boat.transform.position = teammate_position;
+            assert!(world.try_spawn(teammate_boat));
+
+            let mut enemy_boat = Entity::new(EntityType::G5, Some(enemy));
+            enemy_boat.transform.position = Vec2::new(250.0, 0.0);
+            assert!(world.try_spawn(enemy_boat));
+
+            // Never upgrade or join a team instead of aiming.
+            let mut bot = Bot {
+                level_ambition: 1,
+                sociable: false,
+                model: None,
+                ..Bot::default()
+            };
+            let action = bot.update(
+                world.get_player_complete(&bot_player),
+                bot_player_id,
+                &PlayerRepo::new(),
+                &TeamRepo::new(),
+                |player_id| player_id == Some(bot_player_id) || player_id == Some(teammate_id),
+            );
+            match action {
+                BotAction::Some(Command::Control(control)) => control.aim_target,
+                _ => panic!("expected control"),
+            }
+        };
+
+        assert!(aim_target(Vec2::new(120.0, 0.0)).is_none());
+        assert!(aim_target(Vec2::new(0.0, 120.0)).is_some());
+    }
+
+    /// The model must not steer bots aground.
+    #[test]
+    fn model_avoids_land() {
+        /// Land starting 150m east of the origin.
+        fn land_generator(x: usize, _: usize) -> u8 {
+            if x >= terrain::SIZE / 2 + 6 {
+                u8::MAX
+            } else {
+                0
+            }
+        }
+
+        let guidance = |terrain: Terrain| {
+            let mut world = World::new(10000.0);
+            world.terrain = terrain;
+
+            let bot_player = player(1);
+            let bot_player_id = bot_player.borrow_player().player_id;
+            world.add(Entity::new(
+                EntityType::Bismarck,
+                Some(Arc::clone(&bot_player)),
+            ));
+
+            // Always steer straight ahead, at full speed.
+            let mut outputs = vec![0.0; Decision::OUTPUTS];
+            outputs[0] = 1.0;
+            outputs[2] = 1.0;
+            let mut bot = Bot {
+                level_ambition: 1,
+                sociable: false,
+                ..Bot::with_model(Some(Arc::new(Mlp::constant(&outputs))))
+            };
+
+            let action = bot.update(
+                world.get_player_complete(&bot_player),
+                bot_player_id,
+                &PlayerRepo::new(),
+                &TeamRepo::new(),
+                |player_id| player_id == Some(bot_player_id),
+            );
+            match action {
+                BotAction::Some(Command::Control(control)) => control.guidance.unwrap(),
+                _ => panic!("expected control"),
+            }
+        };
+
+        // Follows the model in open water.
+        assert_eq!(guidance(Terrain::new()).direction_target, Angle::ZERO);
+
+        // Turns away from land, despite the model.
+        let direction_target = guidance(Terrain::with_generator(land_generator)).direction_target;
+        assert!(direction_target.abs() > Angle::from_degrees(90.0));
     }
 }
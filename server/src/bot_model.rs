@@ -0,0 +1,522 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::bot::Bot;
+use common::angle::Angle;
+use common::contact::ContactTrait;
+use common::entity::{EntityData, EntityKind, EntitySubKind};
+use common::guidance::Guidance;
+use common::protocol::Control;
+use common::terrain::Terrain;
+use core_protocol::id::PlayerId;
+use glam::{Mat2, Vec2};
+use lazy_static::lazy_static;
+use log::{error, info};
+use rand::seq::SliceRandom;
+use rand::Rng;
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+use std::fs::{self, OpenOptions};
+use std::io::{BufWriter, Write};
+use std::path::Path;
+use std::sync::Arc;
+use std::time::{Duration, Instant};
+
+/// Bots load their model from here at server start, if it exists.
+pub const MODEL_PATH: &str = "bot_model.json";
+/// If this file exists at server start, samples of real players' play are appended to it.
+pub const RECORDING_PATH: &str = "bot_recording.jsonl";
+
+/// Number of nearest hostile contacts included in an observation.
+const OBSERVED_HOSTILES: usize = 4;
+/// Number of directions in which terrain is sampled.
+const TERRAIN_SAMPLES: usize = 8;
+/// Stop buffering samples past this point, in case writing them out fails.
+const MAX_BUFFERED_SAMPLES: usize = 100_000;
+/// Each player is sampled at most this often, since observing is costly and consecutive samples
+/// are nearly identical.
+const SAMPLE_PERIOD: Duration = Duration::from_millis(500);
+
+lazy_static! {
+    /// The model loaded from `MODEL_PATH`, if any.
+    pub static ref MODEL: Option<Arc<Mlp>> = if Path::new(MODEL_PATH).exists() {
+        match Mlp::load(MODEL_PATH) {
+            Ok(model) if model.is_compatible() => {
+                info!("loaded bot model from {}", MODEL_PATH);
+                Some(Arc::new(model))
+            }
+            Ok(_) => {
+                error!("bot model in {} has the wrong shape", MODEL_PATH);
+                None
+            }
+            Err(e) => {
+                error!("error loading bot model: {}", e);
+                None
+            }
+        }
+    } else {
+        None
+    };
+}
+
+/// What a boat senses, as model inputs, relative to the boat's position and direction.
+#[derive(Clone, Debug, PartialEq)]
+pub struct Observation(pub Vec<f32>);
+
+impl Observation {
+    /// Health, speed, submerged, and ready armaments, then terrain, then hostiles, then the
+    /// nearest collectible.
+    pub const FEATURES: usize = 4 + TERRAIN_SAMPLES + OBSERVED_HOSTILES * 6 + 2;
+
+    /// Observes from the perspective of `boat`. `contacts` may include `boat` itself.
+    pub fn new<C: ContactTrait>(
+        boat: &C,
+        contacts: &[C],
+        terrain: &Terrain,
+        world_radius: f32,
+    ) -> Self {
+        let data = boat.data();
+        let transform = boat.transform();
+        let range = data.sensors.max_range().max(1.0);
+        let to_local = Mat2::from_angle(-transform.direction.to_radians());
+        let velocity = |contact: &C| {
+            contact.transform().direction.to_vec() * contact.transform().velocity.to_mps()
+        };
+
+        let mut features = Vec::with_capacity(Self::FEATURES);
+        features.push(1.0 - boat.damage().to_secs() / data.max_health().to_secs());
+        features.push(transform.velocity.to_mps() / data.speed.to_mps().max(1.0));
+        features.push(boat.altitude().is_submerged() as u8 as f32);
+        features.push(if data.armaments.is_empty() {
+            0.0
+        } else {
+            boat.reloads().count_ones() as f32 / data.armaments.len() as f32
+        });
+
+        for i in 0..TERRAIN_SAMPLES {
+            let angle = transform.direction
+                + Angle::from_radians(i as f32 * std::f32::consts::TAU / TERRAIN_SAMPLES as f32);
+            let position = transform.position + angle.to_vec() * data.length * 2.0;
+            features
+                .push(Bot::is_land_or_border(position, data, terrain, world_radius) as u8 as f32);
+        }
+
+        let mut hostiles: Vec<(&C, &EntityData, f32)> = contacts
+            .iter()
+            .filter(|c| c.id() != boat.id() && c.player_id() != boat.player_id())
+            .filter_map(|c| {
+                let contact_data = c.entity_type()?.data();
+                matches!(
+                    contact_data.kind,
+                    EntityKind::Boat | EntityKind::Weapon | EntityKind::Aircraft
+                )
+                .then(|| {
+                    let distance_squared =
+                        c.transform().position.distance_squared(transform.position);
+                    (c, contact_data, distance_squared)
+                })
+            })
+            .collect();
+        hostiles.sort_by(|a, b| a.2.total_cmp(&b.2));
+
+        for i in 0..OBSERVED_HOSTILES {
+            if let Some(&(hostile, hostile_data, _)) = hostiles.get(i) {
+                let delta = hostile.transform().position - transform.position;
+                let local = to_local * delta / range;
+                let closing = (velocity(boat) - velocity(hostile)).dot(delta.normalize_or_zero());
+                features.push(local.x.clamp(-2.0, 2.0));
+                features.push(local.y.clamp(-2.0, 2.0));
+                features.push((closing / 20.0).clamp(-2.0, 2.0));
+                features.push((hostile_data.kind == EntityKind::Weapon) as u8 as f32);
+                features.push((hostile_data.kind == EntityKind::Aircraft) as u8 as f32);
+                features.push(1.0);
+            } else {
+                features.extend_from_slice(&[0.0; 6]);
+            }
+        }
+
+        let collectible = contacts
+            .iter()
+            .filter(|c| {
+                c.entity_type()
+                    .map_or(false, |t| t.data().kind == EntityKind::Collectible)
+            })
+            .map(|c| c.transform().position - transform.position)
+            .min_by(|a, b| a.length_squared().total_cmp(&b.length_squared()));
+        if let Some(delta) = collectible {
+            let local = to_local * delta / range;
+            features.push(local.x.clamp(-2.0, 2.0));
+            features.push(local.y.clamp(-2.0, 2.0));
+        } else {
+            features.extend_from_slice(&[0.0; 2]);
+        }
+
+        debug_assert_eq!(features.len(), Self::FEATURES);
+        Self(features)
+    }
+}
+
+/// What a boat does, as model outputs, relative to the boat's direction.
+#[derive(Copy, Clone, Debug, PartialEq)]
+pub struct Decision {
+    /// Direction target relative to the current direction.
+    pub steer: Angle,
+    /// Velocity target as a fraction of maximum speed.
+    pub speed: f32,
+    pub fire: bool,
+    pub submerge: bool,
+}
+
+impl Decision {
+    /// Steering (as sine and cosine), speed, fire, and submerge.
+    pub const OUTPUTS: usize = 5;
+
+    /// Infers the decision a player made by sending `control`.
+    pub fn from_control<C: ContactTrait>(boat: &C, control: &Control) -> Self {
+        let guidance = control.guidance.unwrap_or(*boat.guidance());
+        Self {
+            steer: guidance.direction_target - boat.transform().direction,
+            speed: guidance.velocity_target.to_mps() / boat.data().speed.to_mps().max(1.0),
+            fire: control.fire.is_some() || control.salvo.is_some(),
+            submerge: control.submerge,
+        }
+    }
+
+    pub fn from_outputs(outputs: &[f32]) -> Self {
+        Self {
+            steer: Angle::from(Vec2::new(outputs[0], outputs[1])),
+            speed: outputs[2].clamp(-1.0 / 3.0, 1.0),
+            fire: outputs[3] > 0.5,
+            submerge: outputs[4] > 0.5,
+        }
+    }
+
+    pub fn to_outputs(&self) -> Vec<f32> {
+        let steer = self.steer.to_vec();
+        vec![
+            steer.x,
+            steer.y,
+            self.speed,
+            self.fire as u8 as f32,
+            self.submerge as u8 as f32,
+        ]
+    }
+
+    /// Guidance that would carry out the decision.
+    pub fn guidance<C: ContactTrait>(&self, boat: &C) -> Guidance {
+        let data = boat.data();
+        Guidance {
+            direction_target: boat.transform().direction + self.steer,
+            velocity_target: data.speed * self.speed,
+        }
+    }
+
+    /// Whether the decision to submerge applies to `boat`.
+    pub fn submerge<C: ContactTrait>(&self, boat: &C) -> bool {
+        self.submerge && boat.data().sub_kind == EntitySubKind::Submarine
+    }
+}
+
+/// A recorded observation, and the decision a real player made in response.
+#[derive(Clone, Debug, Serialize, Deserialize)]
+pub struct Sample {
+    pub observation: Vec<f32>,
+    pub decision: Vec<f32>,
+}
+
+/// Buffers samples of real players' play, to be appended to `RECORDING_PATH`.
+pub struct Recorder {
+    enabled: bool,
+    samples: Vec<Sample>,
+    /// When each player was last sampled.
+    sampled: HashMap<PlayerId, Instant>,
+}
+
+impl Recorder {
+    /// Recording is enabled if `RECORDING_PATH` exists, so it is opt-in per server.
+    pub fn new() -> Self {
+        let enabled = Path::new(RECORDING_PATH).exists();
+        if enabled {
+            info!("recording samples for bot training to {}", RECORDING_PATH);
+        }
+        Self {
+            enabled,
+            samples: Vec::new(),
+            sampled: HashMap::new(),
+        }
+    }
+
+    /// Returns true if `player_id` is due to be sampled, in which case it is considered sampled.
+    pub fn should_sample(&mut self, player_id: PlayerId) -> bool {
+        if !self.enabled || self.samples.len() >= MAX_BUFFERED_SAMPLES {
+            return false;
+        }
+        let now = Instant::now();
+        match self.sampled.get(&player_id) {
+            Some(sampled) if now.duration_since(*sampled) < SAMPLE_PERIOD => false,
+            _ => {
+                self.sampled.insert(player_id, now);
+                true
+            }
+        }
+    }
+
+    pub fn record(&mut self, observation: Observation, decision: Decision) {
+        if self.enabled && self.samples.len() < MAX_BUFFERED_SAMPLES {
+            self.samples.push(Sample {
+                observation: observation.0,
+                decision: decision.to_outputs(),
+            });
+        }
+    }
+
+    /// Appends buffered samples to `RECORDING_PATH`, without blocking.
+    pub fn flush(&mut self) {
+        // Forget players that haven't been sampled lately, such as those who left.
+        self.sampled
+            .retain(|_, sampled| sampled.elapsed() < SAMPLE_PERIOD);
+
+        if self.samples.is_empty() {
+            return;
+        }
+        let samples = std::mem::take(&mut self.samples);
+        tokio::task::spawn_blocking(move || {
+            if let Err(e) = OpenOptions::new()
+                .append(true)
+                .open(RECORDING_PATH)
+                .and_then(|file| {
+                    let mut writer = BufWriter::new(file);
+                    for sample in &samples {
+                        serde_json::to_writer(&mut writer, sample)?;
+                        writer.write_all(b"\n")?;
+                    }
+                    writer.flush()
+                })
+            {
+                error!("error recording bot samples: {:?}", e);
+            }
+        });
+    }
+}
+
+/// Fully-connected layer.
+#[derive(Clone, Debug, Serialize, Deserialize)]
+struct Dense {
+    inputs: usize,
+    outputs: usize,
+    /// Row-major, one row per output.
+    weights: Vec<f32>,
+    biases: Vec<f32>,
+}
+
+impl Dense {
+    fn new(inputs: usize, outputs: usize, rng: &mut impl Rng) -> Self {
+        // Xavier initialization.
+        let limit = (6.0 / (inputs + outputs) as f32).sqrt();
+        Self {
+            inputs,
+            outputs,
+            weights: (0..inputs * outputs)
+                .map(|_| rng.gen_range(-limit..=limit))
+                .collect(),
+            biases: vec![0.0; outputs],
+        }
+    }
+
+    fn forward(&self, input: &[f32]) -> Vec<f32> {
+        self.weights
+            .chunks_exact(self.inputs)
+            .zip(self.biases.iter())
+            .map(|(row, bias)| bias + row.iter().zip(input).map(|(w, x)| w * x).sum::<f32>())
+            .collect()
+    }
+}
+
+/// Tiny multilayer perceptron, with tanh activations on hidden layers, evaluated on the CPU.
+#[derive(Clone, Debug, Serialize, Deserialize)]
+pub struct Mlp {
+    layers: Vec<Dense>,
+}
+
+impl Mlp {
+    /// Creates a randomly initialized model with the given layer sizes, starting with inputs.
+    pub fn new(sizes: &[usize], rng: &mut impl Rng) -> Self {
+        Self {
+            layers: sizes
+                .windows(2)
+                .map(|pair| Dense::new(pair[0], pair[1], rng))
+                .collect(),
+        }
+    }
+
+    /// Creates a model that always outputs `outputs`, regardless of its input.
+    #[cfg(test)]
+    pub fn constant(outputs: &[f32]) -> Self {
+        Self {
+            layers: vec![Dense {
+                inputs: Observation::FEATURES,
+                outputs: outputs.len(),
+                weights: vec![0.0; Observation::FEATURES * outputs.len()],
+                biases: outputs.to_vec(),
+            }],
+        }
+    }
+
+    /// Whether the model maps observations to decisions.
+    pub fn is_compatible(&self) -> bool {
+        self.layers.first().map(|l| l.inputs) == Some(Observation::FEATURES)
+            && self.layers.last().map(|l| l.outputs) == Some(Decision::OUTPUTS)
+            && self.layers.windows(2).all(|p| p[0].outputs == p[1].inputs)
+    }
+
+    pub fn forward(&self, input: &[f32]) -> Vec<f32> {
+        self.activations(input).pop().unwrap_or_default()
+    }
+
+    /// Outputs of each layer, starting with the input.
+    fn activations(&self, input: &[f32]) -> Vec<Vec<f32>> {
+        let mut activations = Vec::with_capacity(self.layers.len() + 1);
+        activations.push(input.to_vec());
+        for (i, layer) in self.layers.iter().enumerate() {
+            let mut output = layer.forward(activations.last().unwrap());
+            if i + 1 < self.layers.len() {
+                output.iter_mut().for_each(|o| *o = o.tanh());
+            }
+            activations.push(output);
+        }
+        activations
+    }
+
+    /// Mean squared error over `samples`.
+    pub fn loss(&self, samples: &[(Vec<f32>, Vec<f32>)]) -> f32 {
+        let sum: f32 = samples
+            .iter()
+            .map(|(input, target)| {
+                let output = self.forward(input);
+                output
+                    .iter()
+                    .zip(target)
+                    .map(|(o, t)| (o - t).powi(2))
+                    .sum::<f32>()
+                    / target.len() as f32
+            })
+            .sum();
+        sum / samples.len().max(1) as f32
+    }
+
+    /// Trains for one epoch of stochastic gradient descent on mean squared error, visiting
+    /// `samples` in a random order.
+    pub fn train(
+        &mut self,
+        samples: &[(Vec<f32>, Vec<f32>)],
+        learning_rate: f32,
+        rng: &mut impl Rng,
+    ) {
+        let mut order: Vec<usize> = (0..samples.len()).collect();
+        order.shuffle(rng);
+        for index in order {
+            let (input, target) = &samples[index];
+            let activations = self.activations(input);
+
+            // Gradient of loss with respect to the (pre-activation) output of the current layer.
+            let output = activations.last().unwrap();
+            let mut delta: Vec<f32> = output
+                .iter()
+                .zip(target)
+                .map(|(o, t)| 2.0 * (o - t) / target.len() as f32)
+                .collect();
+
+            for (i, layer) in self.layers.iter_mut().enumerate().rev() {
+                let input = &activations[i];
+                let previous_delta = (i > 0).then(|| {
+                    (0..layer.inputs)
+                        .map(|j| {
+                            let sum: f32 = (0..layer.outputs)
+                                .map(|k| layer.weights[k * layer.inputs + j] * delta[k])
+                                .sum();
+                            // Derivative of tanh.
+                            sum * (1.0 - input[j].powi(2))
+                        })
+                        .collect::<Vec<f32>>()
+                });
+
+                for (k, d) in delta.iter().enumerate() {
+                    let row = &mut layer.weights[k * layer.inputs..(k + 1) * layer.inputs];
+                    for (w, x) in row.iter_mut().zip(input) {
+                        *w -= learning_rate * d * x;
+                    }
+                    layer.biases[k] -= learning_rate * d;
+                }
+
+                if let Some(previous_delta) = previous_delta {
+                    delta = previous_delta;
+                }
+            }
+        }
+    }
+
+    pub fn load(path: &str) -> Result<Self, String> {
+        let buf = fs::read(path).map_err(|e| e.to_string())?;
+        serde_json::from_slice(&buf).map_err(|e| e.to_string())
+    }
+
+    pub fn save(&self, path: &str) -> Result<(), String> {
+        let buf = serde_json::to_vec(self).map_err(|e| e.to_string())?;
+        fs::write(path, buf).map_err(|e| e.to_string())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::bot_model::{Decision, Mlp, Observation};
+    use common::angle::Angle;
+    use rand::{thread_rng, Rng};
+
+    #[test]
+    fn learns() {
+        let mut rng = thread_rng();
+        // Steer away from a hostile, and fire if it is ahead.
+        let samples: Vec<(Vec<f32>, Vec<f32>)> = (0..500)
+            .map(|_| {
+                let mut observation = vec![0.0; Observation::FEATURES];
+                let x: f32 = rng.gen_range(-1.0..1.0);
+                let y: f32 = rng.gen_range(-1.0..1.0);
+                observation[12] = x;
+                observation[13] = y;
+                let decision = Decision {
+                    steer: Angle::from_degrees(if y > 0.0 { -45.0 } else { 45.0 }),
+                    speed: 1.0,
+                    fire: x > 0.5,
+                    submerge: false,
+                };
+                (observation, decision.to_outputs())
+            })
+            .collect();
+
+        let mut model = Mlp::new(&[Observation::FEATURES, 16, Decision::OUTPUTS], &mut rng);
+        assert!(model.is_compatible());
+        let initial_loss = model.loss(&samples);
+        for _ in 0..50 {
+            model.train(&samples, 0.05, &mut rng);
+        }
+        let loss = model.loss(&samples);
+        assert!(loss < initial_loss * 0.5, "{} {}", initial_loss, loss);
+
+        let decision = Decision::from_outputs(&model.forward(&samples[0].0));
+        assert!(decision.steer.abs() < Angle::from_degrees(90.0));
+    }
+
+    #[test]
+    fn decision_outputs() {
+        let decision = Decision {
+            steer: Angle::from_degrees(30.0),
+            speed: 0.5,
+            fire: true,
+            submerge: false,
+        };
+        let round_trip = Decision::from_outputs(&decision.to_outputs());
+        assert!((round_trip.steer - decision.steer).abs() < Angle::from_degrees(0.1));
+        assert_eq!(round_trip.fire, decision.fire);
+        assert_eq!(round_trip.submerge, decision.submerge);
+    }
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use common::ticks::Ticks;
+
+/// A player's bounty, which grows with their kill streak and time spent leading, and is
+/// collected by whoever sinks them.
+#[derive(Debug, Default)]
+pub struct Bounty {
+    /// Kills since last death.
+    streak: u32,
+    /// Current bounty, in score.
+    value: f32,
+    /// Time since last kill.
+    idle: Ticks,
+    /// Whether the bounty was announced (reset if it decays below the threshold).
+    announced: bool,
+}
+
+impl Bounty {
+    /// Bounty per kill, multiplied by the (capped) streak.
+    const PER_KILL: f32 = 4.0;
+    /// Streak beyond which kills stop being worth more.
+    const MAX_STREAK: u32 = 10;
+    /// Bounty per second spent at the top of the liveboard while fighting.
+    const PER_LEADER_SECOND: f32 = 0.5;
+    /// Bounty starts to decay after this long without a kill.
+    const DECAY_DELAY: Ticks = Ticks::from_whole_secs(120);
+    /// Fraction of bounty lost per second of decay.
+    const DECAY_RATE: f32 = 0.01;
+    /// Bounty is capped so it can't eclipse score.
+    const MAX_VALUE: f32 = 2500.0;
+    /// Minimum bounty that is announced and shown to everyone.
+    pub const THRESHOLD: u32 = 100;
+
+    /// Called when the player sinks another boat.
+    pub fn on_kill(&mut self) {
+        self.streak = self.streak.saturating_add(1);
+        self.value = (self.value + Self::PER_KILL * self.streak.min(Self::MAX_STREAK) as f32)
+            .min(Self::MAX_VALUE);
+        self.idle = Ticks::ZERO;
+    }
+
+    /// Accrues bounty while leading, and decays it while not fighting.
+    pub fn update(&mut self, delta: Ticks, leader: bool) {
+        self.idle = self.idle.saturating_add(delta);
+        if self.idle < Self::DECAY_DELAY {
+            if leader {
+                self.value =
+                    (self.value + Self::PER_LEADER_SECOND * delta.to_secs()).min(Self::MAX_VALUE);
+            }
+        } else {
+            self.value *= (1.0 - Self::DECAY_RATE * delta.to_secs()).max(0.0);
+            if self.value() < Self::THRESHOLD {
+                self.streak = 0;
+            }
+        }
+        if !self.is_wanted() {
+            self.announced = false;
+        }
+    }
+
+    /// Current bounty, in score.
+    pub fn value(&self) -> u32 {
+        self.value as u32
+    }
+
+    /// Whether the bounty is large enough to be shown to everyone.
+    pub fn is_wanted(&self) -> bool {
+        self.value() >= Self::THRESHOLD
+    }
+
+    /// Returns true once each time the bounty becomes large enough to be announced.
+    pub fn take_announcement(&mut self) -> bool {
+        let announce = self.is_wanted() && !self.announced;
+        self.announced |= announce;
+        announce
+    }
+
+    /// Resets the bounty (because the player died), returning the amount to be collected.
+    pub fn collect(&mut self) -> u32 {
+        let value = self.value();
+        *self = Self::default();
+        value
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::bounty::Bounty;
+    use common::ticks::Ticks;
+
+    #[test]
+    fn bounty() {
+        let mut bounty = Bounty::default();
+        for _ in 0..8 {
+            bounty.on_kill();
+        }
+        assert!(bounty.is_wanted());
+        assert!(bounty.take_announcement());
+        assert!(!bounty.take_announcement());
+
+        // Leading while fighting accrues bounty.
+        let before = bounty.value();
+        bounty.update(Ticks::from_whole_secs(60), true);
+        assert!(bounty.value() > before);
+
+        // Not fighting decays it.
+        let before = bounty.value();
+        for _ in 0..600 {
+            bounty.update(Ticks::from_whole_secs(1), true);
+        }
+        assert!(bounty.value() < before / 2);
+        assert!(!bounty.is_wanted());
+
+        bounty.on_kill();
+        let value = bounty.value();
+        assert_eq!(bounty.collect(), value);
+        assert_eq!(bounty.value(), 0);
+    }
+}
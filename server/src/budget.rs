@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use common::entity::{EntityData, EntityId, EntityKind, EntitySubKind};
+use core_protocol::id::{PlayerId, TeamId};
+use core_protocol::metrics::RatioMetric;
+use std::collections::{HashMap, HashSet, VecDeque};
+use std::hash::Hash;
+
+/// Kinds of entities of which each player, and each team, may only have so many at once.
+#[derive(Copy, Clone, Debug, Eq, PartialEq)]
+pub enum BudgetKind {
+    Weapon,
+    Aircraft,
+    Decoy,
+    Mine,
+}
+
+impl BudgetKind {
+    const COUNT: usize = 4;
+    /// Teams may have this many times a player's budget.
+    const TEAM_FACTOR: usize = 3;
+
+    /// Returns the budget kind of entities of type `data`, if any.
+    pub fn of(data: &EntityData) -> Option<Self> {
+        match data.kind {
+            EntityKind::Weapon if data.sub_kind == EntitySubKind::Mine => Some(Self::Mine),
+            EntityKind::Weapon => Some(Self::Weapon),
+            EntityKind::Aircraft => Some(Self::Aircraft),
+            EntityKind::Decoy => Some(Self::Decoy),
+            _ => None,
+        }
+    }
+
+    /// Number of live entities of this kind a player may have, on an idle server.
+    fn player_budget(self) -> usize {
+        match self {
+            Self::Weapon => 48,
+            Self::Aircraft => 12,
+            Self::Decoy => 8,
+            Self::Mine => 16,
+        }
+    }
+}
+
+/// Live entities of each budget kind, oldest first.
+type Ledger = [VecDeque<EntityId>; BudgetKind::COUNT];
+
+/// Tracks live weapons, aircraft, and decoys by player and team, expiring the oldest to make room
+/// for new ones when over budget.
+pub struct Budgets {
+    players: HashMap<PlayerId, Ledger>,
+    teams: HashMap<TeamId, Ledger>,
+    /// Owner, owner's team (at the time of spawning), and budget kind of each live entity.
+    entities: HashMap<EntityId, (PlayerId, Option<TeamId>, BudgetKind)>,
+    /// Entities expired to make room, which physics removes as if their lifespan ended.
+    expiring: HashSet<EntityId>,
+    /// Multiplier of budgets, which shrinks as the world fills up with budgeted entities.
+    scale: f32,
+    /// Whether making room required expiring entities (for metrics).
+    over_budget: RatioMetric,
+}
+
+impl Default for Budgets {
+    fn default() -> Self {
+        Self {
+            players: HashMap::new(),
+            teams: HashMap::new(),
+            entities: HashMap::new(),
+            expiring: HashSet::new(),
+            scale: 1.0,
+            over_budget: RatioMetric::default(),
+        }
+    }
+}
+
+impl Budgets {
+    /// Budgets start shrinking once the world has this many budgeted entities.
+    const CAPACITY: usize = 4000;
+    /// Budgets never shrink below this fraction.
+    const MIN_SCALE: f32 = 0.25;
+    /// Budgets are multiplied by this fraction while capped (shedding load).
+    const CAPPED_SCALE: f32 = 0.5;
+
+    /// Returns true if the entity was expired to make room, and should be removed.
+    pub fn is_expiring(&self, id: EntityId) -> bool {
+        !self.expiring.is_empty() && self.expiring.contains(&id)
+    }
+
+    /// Call when an entity, owned by a player, is added to the world.
+    pub fn add(
+        &mut self,
+        id: EntityId,
+        player_id: PlayerId,
+        team_id: Option<TeamId>,
+        kind: BudgetKind,
+    ) {
+        self.entities.insert(id, (player_id, team_id, kind));
+        self.players.entry(player_id).or_default()[kind as usize].push_back(id);
+        if let Some(team_id) = team_id {
+            self.teams.entry(team_id).or_default()[kind as usize].push_back(id);
+        }
+    }
+
+    /// Call when any entity is removed from the world.
+    pub fn remove(&mut self, id: EntityId) {
+        self.expiring.remove(&id);
+        if let Some((player_id, team_id, kind)) = self.entities.remove(&id) {
+            Self::forget(&mut self.players, player_id, kind, id);
+            if let Some(team_id) = team_id {
+                Self::forget(&mut self.teams, team_id, kind, id);
+            }
+        }
+    }
+
+    /// Removes `id` from a ledger, and the ledger itself if it becomes empty.
+    fn forget<K: Eq + Hash>(
+        ledgers: &mut HashMap<K, Ledger>,
+        key: K,
+        kind: BudgetKind,
+        id: EntityId,
+    ) {
+        if let Some(ledger) = ledgers.get_mut(&key) {
+            let entities = &mut ledger[kind as usize];
+            if let Some(i) = entities.iter().position(|&e| e == id) {
+                entities.remove(i);
+            }
+            if ledger.iter().all(VecDeque::is_empty) {
+                ledgers.remove(&key);
+            }
+        }
+    }
+
+    /// Expires the oldest entities of the player, and their team, as necessary to get back within
+    /// budget. Call right after `add`, which ensures the newly added entity is not expired.
+    pub fn make_room(&mut self, player_id: PlayerId, team_id: Option<TeamId>, kind: BudgetKind) {
+        let budget = ((kind.player_budget() as f32 * self.scale) as usize).max(1);
+
+        let mut excess = Self::excess(self.players.get(&player_id), kind, budget);
+        let mut expired = !excess.is_empty();
+        for id in excess {
+            self.expire(id);
+        }
+
+        if let Some(team_id) = team_id {
+            let team_budget = budget * BudgetKind::TEAM_FACTOR;
+            excess = Self::excess(self.teams.get(&team_id), kind, team_budget);
+            expired |= !excess.is_empty();
+            for id in excess {
+                self.expire(id);
+            }
+        }
+
+        self.over_budget.push(expired);
+    }
+
+    /// Returns the oldest entities of a kind that are over budget.
+    fn excess(ledger: Option<&Ledger>, kind: BudgetKind, budget: usize) -> Vec<EntityId> {
+        ledger.map_or(Vec::new(), |ledger| {
+            let entities = &ledger[kind as usize];
+            let excess = entities.len().saturating_sub(budget);
+            entities.iter().take(excess).copied().collect()
+        })
+    }
+
+    /// Stops counting an entity against its budgets, and marks it for removal.
+    fn expire(&mut self, id: EntityId) {
+        self.remove(id);
+        self.expiring.insert(id);
+    }
+
+    /// Adjusts budgets to the total number of budgeted entities in the world, so busy servers
+    /// don't fall behind, and further caps them if the server is overloaded.
+    pub fn set_load(&mut self, budgeted: usize, capped: bool) {
+        self.scale = (Self::CAPACITY as f32 / budgeted.max(1) as f32).clamp(Self::MIN_SCALE, 1.0);
+        if capped {
+            self.scale *= Self::CAPPED_SCALE;
+        }
+    }
+
+    /// Returns the current multiplier of budgets.
+    pub fn scale(&self) -> f32 {
+        self.scale
+    }
+
+    /// Returns, and resets, the ratio of spawns that required expiring older entities.
+    pub fn take_over_budget(&mut self) -> RatioMetric {
+        std::mem::take(&mut self.over_budget)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::budget::{BudgetKind, Budgets};
+    use common::entity::{EntityId, EntityType};
+    use core_protocol::id::{PlayerId, TeamId};
+    use std::num::NonZeroU32;
+
+    #[test]
+    fn budgets() {
+        let mut budgets = Budgets::default();
+        let player_id = PlayerId::nth_bot(0).unwrap();
+        let team_id = TeamId(NonZeroU32::new(1).unwrap());
+        let kind = BudgetKind::of(EntityType::Mark18.data()).unwrap();
+        let budget = kind.player_budget();
+
+        let ids: Vec<EntityId> = (1..=budget as u32 + 1)
+            .map(|n| EntityId::new(n).unwrap())
+            .collect();
+        for &id in &ids[..budget] {
+            budgets.add(id, player_id, Some(team_id), kind);
+            budgets.make_room(player_id, Some(team_id), kind);
+        }
+        assert!(!budgets.is_expiring(ids[0]));
+
+        // The oldest makes room for the next.
+        budgets.add(ids[budget], player_id, Some(team_id), kind);
+        budgets.make_room(player_id, Some(team_id), kind);
+        assert!(budgets.is_expiring(ids[0]));
+        assert!(!budgets.is_expiring(ids[1]));
+        assert!(!budgets.is_expiring(ids[budget]));
+        budgets.remove(ids[0]);
+        assert!(!budgets.is_expiring(ids[0]));
+
+        let over_budget = budgets.take_over_budget();
+        assert_eq!(
+            (over_budget.count, over_budget.total),
+            (1, budget as u32 + 1)
+        );
+
+        // Busy servers have smaller budgets.
+        budgets.set_load(Budgets::CAPACITY * 2, false);
+        assert_eq!(budgets.scale(), 0.5);
+        budgets.make_room(player_id, None, kind);
+        assert!(budgets.is_expiring(ids[budget / 2]));
+        assert!(!budgets.is_expiring(ids[budget / 2 + 1]));
+
+        // Overloaded servers have even smaller budgets.
+        budgets.set_load(0, true);
+        assert_eq!(budgets.scale(), Budgets::CAPPED_SCALE);
+    }
+}
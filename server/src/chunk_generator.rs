@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::world::World;
+use common::terrain::{Chunk, ChunkId, ChunkSet, Terrain};
+use glam::Vec2;
+use maybe_parallel_iterator::IntoMaybeParallelIterator;
+use std::sync::mpsc::{channel, Receiver, Sender};
+use std::sync::{Arc, Mutex};
+use std::thread;
+
+/// Generates terrain chunks on worker threads, so that exploring new areas doesn't cause long
+/// ticks.
+pub struct ChunkGenerator {
+    jobs: Sender<ChunkId>,
+    results: Receiver<(ChunkId, Box<Chunk>)>,
+    /// Chunks being generated, which shouldn't be requested again.
+    pending: ChunkSet,
+}
+
+impl ChunkGenerator {
+    /// Number of worker threads.
+    const WORKERS: usize = 2;
+
+    /// Starts worker threads, which stop when the generator is dropped.
+    pub fn new(generator: fn(usize, usize) -> u8) -> Self {
+        let (jobs, job_receiver) = channel::<ChunkId>();
+        let (result_sender, results) = channel();
+        let job_receiver = Arc::new(Mutex::new(job_receiver));
+
+        for i in 0..Self::WORKERS {
+            let job_receiver = Arc::clone(&job_receiver);
+            let result_sender = result_sender.clone();
+            thread::Builder::new()
+                .name(format!("chunk_generator_{}", i))
+                .spawn(move || loop {
+                    let chunk_id = match job_receiver.lock().unwrap().recv() {
+                        Ok(chunk_id) => chunk_id,
+                        Err(_) => return,
+                    };
+                    let chunk = Chunk::new(chunk_id, generator);
+                    if result_sender.send((chunk_id, chunk)).is_err() {
+                        return;
+                    }
+                })
+                .unwrap();
+        }
+
+        Self {
+            jobs,
+            results,
+            pending: ChunkSet::new(),
+        }
+    }
+
+    /// Call every tick, before clients receive updates, to swap in generated chunks and generate
+    /// newly requested chunks.
+    pub fn update(&mut self, terrain: &mut Terrain) {
+        for (chunk_id, chunk) in self.results.try_iter() {
+            self.pending.remove(chunk_id);
+            terrain.insert_generated(chunk_id, chunk);
+        }
+
+        for chunk_id in terrain.take_requested().into_iter() {
+            if !self.pending.contains(chunk_id) {
+                self.pending.add(chunk_id);
+                let _ = self.jobs.send(chunk_id);
+            }
+        }
+    }
+
+    /// Blocks until all requested chunks are generated and swapped in.
+    pub fn finish(&mut self, terrain: &mut Terrain) {
+        self.update(terrain);
+        while !self.pending.is_empty() {
+            match self.results.recv() {
+                Ok((chunk_id, chunk)) => {
+                    self.pending.remove(chunk_id);
+                    terrain.insert_generated(chunk_id, chunk);
+                }
+                Err(_) => return,
+            }
+        }
+    }
+}
+
+impl World {
+    /// Terrain is generated this many seconds ahead of moving boats.
+    const PREFETCH_SECONDS: f32 = 10.0;
+
+    /// Requests terrain around, and ahead of, boats, so it is ready by the time it is needed.
+    pub fn prefetch_terrain(&self) {
+        self.entities
+            .par_iter()
+            .into_maybe_parallel_iter()
+            .for_each(|(_, entity)| {
+                if !entity.is_boat() {
+                    return;
+                }
+                let transform = &entity.transform;
+                let ahead = transform.direction.to_vec()
+                    * (transform.velocity.to_mps() * Self::PREFETCH_SECONDS);
+                let range = entity.data().sensors.visual.range;
+                self.terrain
+                    .prefetch(transform.position + ahead, Vec2::splat(range * 2.0));
+            });
+    }
+
+    /// Generates all requested terrain immediately, e.g. before spawning in tests.
+    pub fn generate_terrain(&mut self) {
+        self.chunk_generator.finish(&mut self.terrain);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::chunk_generator::ChunkGenerator;
+    use common::terrain::{Chunk, ChunkId, Terrain};
+    use std::thread;
+    use std::time::Duration;
+
+    #[test]
+    fn chunk_generator() {
+        fn generator(x: usize, y: usize) -> u8 {
+            ((x + y) as u8) << 4
+        }
+
+        let mut chunk_generator = ChunkGenerator::new(generator);
+        let mut terrain = Terrain::with_background_generator(generator);
+        let chunk_id = ChunkId(3, 4);
+        terrain.request(chunk_id);
+
+        for _ in 0..100 {
+            chunk_generator.update(&mut terrain);
+            if terrain.is_ready(chunk_id) {
+                break;
+            }
+            thread::sleep(Duration::from_millis(10));
+        }
+
+        assert!(terrain.is_ready(chunk_id));
+        assert_eq!(
+            terrain.get_chunk(chunk_id).to_bytes(),
+            Chunk::new(chunk_id, generator).to_bytes()
+        );
+    }
+}
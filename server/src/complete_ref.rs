@@ -1,22 +1,26 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::contact_cache::ContactCache;
 use crate::contact_ref::ContactRef;
 use crate::player::Status;
 use crate::server::Server;
 use crate::world::World;
 use atomic_refcell::AtomicRef;
 use common::complete::CompleteTrait;
-use common::contact::ContactTrait;
+use common::contact::{ContactTrait, EncodedContact};
 use common::death_reason::DeathReason;
-use common::protocol::Update;
+use common::entity::EntityId;
+use common::protocol::{Alerts, Bounty, Update};
 use common::terrain;
 use common::terrain::{ChunkSet, Terrain};
 use common::ticks::{Ticks, TicksRepr};
 use common::velocity::Velocity;
 use game_server::player::PlayerData;
 use glam::Vec2;
+use std::collections::HashSet;
 use std::ops::RangeInclusive;
+use std::sync::Arc;
 
 /// A "Complete" server to client update that references world data to avoid additional allocation.
 pub struct CompleteRef<'a, I: Iterator<Item = ContactRef<'a>>> {
@@ -45,7 +49,17 @@ impl<'a, I: Iterator<Item = ContactRef<'a>>> CompleteRef<'a, I> {
         }
     }
 
-    pub fn into_update(self, counter: Ticks, loaded_chunks: &mut ChunkSet) -> Update {
+    /// Collects the ids of hostile contacts (all of them, not just those sent this time) into
+    /// `hostiles`. The caller is responsible for setting `alerts`.
+    pub fn into_update(
+        self,
+        counter: Ticks,
+        stride: Ticks,
+        loaded_chunks: &mut ChunkSet,
+        bounties: &Arc<[Bounty]>,
+        hostiles: &mut HashSet<EntityId>,
+        contact_cache: &ContactCache,
+    ) -> Update<EncodedContact> {
         let death_reason = if let Status::Dead { reason, .. } = &self.player.data.status {
             Some(reason.clone())
         } else {
@@ -58,10 +72,11 @@ impl<'a, I: Iterator<Item = ContactRef<'a>>> CompleteRef<'a, I> {
         // All chunks that are currently visible (on screen).
         // Uses a rect instead of a circle because that is what the client renders,
         // even though it is slightly less realistic.
-        let visible = ChunkSet::new_rect(
+        // Chunks that aren't generated yet are sent once they are.
+        let visible = self.world.terrain.ready(ChunkSet::new_rect(
             self.camera_pos,
             self.camera_dims + Vec2::splat(terrain::SCALE * 2.0),
-        );
+        ));
 
         // Actually load more chunks.
         let loading = visible.and(&new_loaded_chunks.not());
@@ -85,11 +100,22 @@ impl<'a, I: Iterator<Item = ContactRef<'a>>> CompleteRef<'a, I> {
 
         *loaded_chunks = new_loaded_chunks;
 
+        let border_warning = if let Status::Alive { entity_index, .. } = &self.player.data.status {
+            self.world
+                .border_warning(self.world.entities[*entity_index].transform.position)
+        } else {
+            None
+        };
+
         Update {
             contacts: self
                 .contacts
                 .unwrap()
                 .filter_map(|contact| {
+                    if contact.is_hostile() {
+                        hostiles.insert(contact.id());
+                    }
+
                     let modulus = if let Some(entity_type) = contact.entity_type() {
                         let range: RangeInclusive<Ticks> = entity_type.data().kind.keep_alive();
 
@@ -103,16 +129,21 @@ impl<'a, I: Iterator<Item = ContactRef<'a>>> CompleteRef<'a, I> {
                         Ticks::from_repr(5)
                     };
 
+                    // Don't miss sends that fell in between strided updates.
                     let send = counter
                         .wrapping_add(Ticks::from_repr(contact.id().get() as TicksRepr))
                         % (modulus + Ticks::ONE)
-                        == Ticks::ZERO;
-                    send.then(|| contact.into_contact())
+                        < stride;
+                    send.then(|| contact_cache.get(contact))
                 })
                 .collect(),
             death_reason,
             score: self.player.score,
             world_radius: self.world.radius,
+            world_target_radius: self.world.target_radius,
+            border_warning,
+            bounties: Arc::clone(bounties),
+            alerts: Alerts::default(),
             terrain,
         }
     }
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::contact_ref::ContactRef;
+use common::contact::{ContactTrait, EncodedContact};
+use common::entity::EntityId;
+use std::collections::HashMap;
+use std::sync::RwLock;
+
+/// Which parts of an entity a contact reveals, which (along with the entity) determines its
+/// encoding.
+#[derive(Copy, Clone, Debug, Eq, PartialEq, Hash)]
+struct ContactKey {
+    id: EntityId,
+    has_type: bool,
+    reloads_known: bool,
+}
+
+/// Contacts encoded this tick, shared by all clients' updates, so that popular contacts (e.g. a
+/// battleship in a crowded area) are only encoded once per tick.
+#[derive(Default)]
+pub struct ContactCache {
+    contacts: RwLock<HashMap<ContactKey, EncodedContact>>,
+}
+
+impl ContactCache {
+    /// Call every tick, after entities change, but before clients receive updates.
+    pub fn clear(&mut self) {
+        self.contacts.get_mut().unwrap().clear();
+    }
+
+    /// Returns the encoding of a contact, encoding it if no other client has seen the same contact
+    /// this tick.
+    pub fn get(&self, contact: ContactRef) -> EncodedContact {
+        let key = ContactKey {
+            id: contact.id(),
+            has_type: contact.entity_type().is_some(),
+            reloads_known: contact.reloads_known(),
+        };
+
+        if let Some(encoded) = self.contacts.read().unwrap().get(&key) {
+            return encoded.clone();
+        }
+
+        // Encode without holding the lock. If another client encodes the same contact
+        // concurrently, both encodings are identical.
+        let encoded = EncodedContact::new(contact.into_contact());
+        self.contacts
+            .write()
+            .unwrap()
+            .entry(key)
+            .or_insert(encoded)
+            .clone()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::contact_cache::ContactCache;
+    use crate::contact_ref::ContactRef;
+    use crate::entity::Entity;
+    use crate::world::World;
+    use common::contact::{Contact, EncodedContact};
+    use common::entity::EntityType;
+    use common::terrain::Terrain;
+    use core_protocol::id::PlayerId;
+    use game_server::player::{PlayerData, PlayerTuple};
+    use glam::Vec2;
+    use std::sync::Arc;
+    use test::{black_box, Bencher};
+
+    /// Spawns `count` entities, of a few types, each owned by a different player.
+    fn world(count: usize) -> World {
+        let mut world = World::new(3000.0);
+        world.terrain = Terrain::new();
+        for (i, entity_type) in [EntityType::Yamato, EntityType::Mark18, EntityType::Barrel]
+            .iter()
+            .copied()
+            .cycle()
+            .take(count)
+            .enumerate()
+        {
+            let player = Arc::new(PlayerTuple::new(PlayerData::new(
+                PlayerId::nth_bot(i).unwrap(),
+                None,
+            )));
+            let entity = Entity::new(entity_type, Some(player));
+            assert!(world.spawn_here_or_nearby(entity, 2500.0, None));
+        }
+        world
+    }
+
+    fn entities(world: &World) -> Vec<&Entity> {
+        world
+            .entities
+            .iter_radius(Vec2::ZERO, 3000.0)
+            .map(|(_, e)| e)
+            .collect()
+    }
+
+    #[test]
+    fn contact_cache() {
+        let world = world(3);
+        let entities = entities(&world);
+        assert_eq!(entities.len(), 3);
+
+        let mut cache = ContactCache::default();
+        for _ in 0..2 {
+            for &entity in &entities {
+                for visibility in 0..8 {
+                    let contact_ref = || {
+                        ContactRef::new(
+                            entity,
+                            visibility & 1 != 0,
+                            visibility & 2 != 0,
+                            visibility & 4 != 0,
+                            false,
+                        )
+                    };
+                    // Byte for byte equivalent to encoding each client's contacts separately.
+                    assert_eq!(
+                        bincode::serialize(&cache.get(contact_ref())).unwrap(),
+                        bincode::serialize(&contact_ref().into_contact()).unwrap()
+                    );
+                }
+            }
+            cache.clear();
+        }
+    }
+    /// Players per tick, each seeing all of the contacts (e.g. a crowded area).
+    const BENCH_PLAYERS: usize = 50;
+    /// Contacts seen by each of the players.
+    const BENCH_CONTACTS: usize = 100;
+
+    /// One tick of encoding every player's contacts separately.
+    #[bench]
+    fn bench_encode_contacts(b: &mut Bencher) {
+        let world = world(BENCH_CONTACTS);
+        let entities = entities(&world);
+        b.iter(|| {
+            for _ in 0..BENCH_PLAYERS {
+                let contacts: Vec<Contact> = entities
+                    .iter()
+                    .map(|&entity| ContactRef::new(entity, true, false, true, false).into_contact())
+                    .collect();
+                black_box(bincode::serialize(&contacts).unwrap());
+            }
+        });
+    }
+
+    /// One tick of encoding every player's contacts, sharing encodings via the cache, including
+    /// the cost of encoding each contact once and clearing the cache.
+    #[bench]
+    fn bench_encode_cached_contacts(b: &mut Bencher) {
+        let world = world(BENCH_CONTACTS);
+        let entities = entities(&world);
+        let mut cache = ContactCache::default();
+        b.iter(|| {
+            cache.clear();
+            for _ in 0..BENCH_PLAYERS {
+                let contacts: Vec<EncodedContact> = entities
+                    .iter()
+                    .map(|&entity| cache.get(ContactRef::new(entity, true, false, true, false)))
+                    .collect();
+                black_box(bincode::serialize(&contacts).unwrap());
+            }
+        });
+    }
+}
@@ -20,12 +20,19 @@ use std::sync::Arc;
 pub struct ContactRef<'a> {
     entity: &'a Entity,
     has_type: bool,
+    friendly: bool,
     reloads: Option<BitArray<ReloadsStorage>>,
 }
 
 impl<'a> ContactRef<'a> {
     /// Creates a new `ContactRef`, referencing an entity, and having certain visibility parameters.
-    pub fn new(entity: &'a Entity, visible: bool, known: bool, has_type: bool) -> Self {
+    pub fn new(
+        entity: &'a Entity,
+        visible: bool,
+        known: bool,
+        has_type: bool,
+        friendly: bool,
+    ) -> Self {
         let reloads = (has_type && entity.is_boat() && (visible || known)).then(|| {
             let reloads = &*entity.extension().reloads;
             let mut arr = BitArray::ZERO;
@@ -42,10 +49,16 @@ impl<'a> ContactRef<'a> {
         Self {
             entity,
             has_type,
+            friendly,
             reloads,
         }
     }
 
+    /// Returns true if the contact is an identified boat that isn't friendly to the player.
+    pub fn is_hostile(&self) -> bool {
+        self.has_type && !self.friendly && self.entity.is_boat()
+    }
+
     /// Converts into a non-ref `Contact`.
     pub fn into_contact(self) -> Contact {
         Contact::new(
@@ -185,6 +185,7 @@ impl Entity {
         let visual_range = self.data().sensors.visual.range;
 
         let mut player = self.borrow_player_mut();
+        player.data.salvo.clear();
         player.data.status = if player.data.flags.left_game {
             Status::Spawning
         } else {
@@ -321,7 +322,8 @@ impl Entity {
             // Below ice, so only collide with solid land.
             Altitude(2)
         } else {
-            Altitude::ZERO
+            // Some boats can cross low terrain.
+            self.data().impassable_altitude()
         }
         .max(self.altitude);
 
@@ -339,10 +341,12 @@ impl Entity {
             panic!("boat's player was not alive in update_turret_aim()");
         };
 
+        let point_defense_target = self.extension().point_defense_target;
         self.data().update_turret_aim(
             self.transform,
             self.extension_mut().turrets_mut(),
             aim_target,
+            point_defense_target,
             delta_seconds,
         );
     }
@@ -517,11 +521,12 @@ impl Entity {
                 EntitySubKind::Torpedo => target.unwrap_or(-unguided_weapon_altitude),
                 EntitySubKind::DepthCharge => Altitude::MIN, // Sink to bottom.
                 EntitySubKind::Mine => -unguided_weapon_altitude,
-                EntitySubKind::Shell
-                | EntitySubKind::Rocket
-                | EntitySubKind::RocketTorpedo
-                | EntitySubKind::Missile => unguided_weapon_altitude,
-                EntitySubKind::Sam => target.unwrap_or(unguided_weapon_altitude),
+                EntitySubKind::Shell | EntitySubKind::Rocket | EntitySubKind::RocketTorpedo => {
+                    unguided_weapon_altitude
+                }
+                EntitySubKind::Missile | EntitySubKind::Sam => {
+                    target.unwrap_or(unguided_weapon_altitude)
+                }
                 _ => {
                     debug_assert!(false, "{:?}", data.sub_kind);
                     Altitude::ZERO
@@ -566,15 +571,29 @@ impl Entity {
             return true;
         }
 
-        let player = player.borrow_player();
+        let player_data = player.borrow_player();
+        let other_player_data = other_player.borrow_player();
+
+        if player_data.data.owner.is_some() || other_player_data.data.owner.is_some() {
+            // Escorts are exactly as friendly as their owners.
+            let player = player_data.data.owner.as_deref().unwrap_or(player);
+            let other_player = other_player_data
+                .data
+                .owner
+                .as_deref()
+                .unwrap_or(other_player);
+            if ptr::eq(player, other_player) {
+                return true;
+            }
+            let team_id = player.borrow_player().team_id();
+            return team_id.is_some() && team_id == other_player.borrow_player().team_id();
+        }
 
-        if player.team_id().is_none() {
+        if player_data.team_id().is_none() {
             return false;
         }
 
-        let other_player = other_player.borrow_player();
-
-        player.team_id() == other_player.team_id()
+        player_data.team_id() == other_player_data.team_id()
     }
 
     /// Returns true if and only two entities have some, identical players.
@@ -4,9 +4,11 @@
 use common::altitude::Altitude;
 use common::angle::Angle;
 use common::entity::*;
+use common::protocol::PointDefense;
 use common::ticks::Ticks;
 use common::util::make_mut_slice;
 use common_util::alloc::{arc_default_n, box_default_n};
+use glam::Vec2;
 use std::iter::FromIterator;
 use std::sync::Arc;
 
@@ -28,6 +30,16 @@ pub struct EntityExtension {
     /// Ticks of protection ticks remaining, zeroed if showing signs of aggression.
     spawn_protection_remaining: Ticks,
 
+    /// Ticks since the player last sent a control command.
+    idle: Ticks,
+
+    /// What point defense turrets engage.
+    point_defense: PointDefense,
+    /// Ticks until point defense may launch again.
+    point_defense_delay: Ticks,
+    /// Position of the threat that point defense turrets are engaging, if any.
+    pub point_defense_target: Option<Vec2>,
+
     // 1 reload per armament, 0 = reloaded.
     // Not an arc because converted to a bitset with max len of 32.
     pub reloads: Box<[Ticks]>,
@@ -40,6 +52,8 @@ pub struct EntityExtension {
 impl EntityExtension {
     /// How long spawn protection lasts (it linearly fades over this time).
     const SPAWN_PROTECTION_INITIAL: Ticks = Ticks::from_whole_secs(20);
+    /// How long without control commands until a player is considered away from keyboard.
+    const AFK_THRESHOLD: Ticks = Ticks::from_whole_secs(30);
 
     /// How long deactivating sensors is delayed.
     const DEACTIVATE_DELAY: Ticks = Ticks::from_repr(5);
@@ -57,6 +71,8 @@ impl EntityExtension {
         } else {
             Ticks::ZERO
         };
+        self.idle = Ticks::ZERO;
+        self.point_defense_target = None;
         self.reloads = box_default_n(data.armaments.len());
         self.turrets = Arc::from_iter(data.turrets.iter().map(|t| t.angle));
     }
@@ -97,6 +113,44 @@ impl EntityExtension {
             / Self::SPAWN_PROTECTION_INITIAL.to_secs()
     }
 
+    /// Returns whether the boat has any spawn protection remaining.
+    pub fn has_spawn_protection(&self) -> bool {
+        self.spawn_protection_remaining > Ticks::ZERO
+    }
+
+    /// Returns what point defense turrets engage.
+    pub fn point_defense(&self) -> PointDefense {
+        self.point_defense
+    }
+
+    /// Sets what point defense turrets engage.
+    pub fn set_point_defense(&mut self, point_defense: PointDefense) {
+        self.point_defense = point_defense;
+        if point_defense == PointDefense::Off {
+            self.point_defense_target = None;
+        }
+    }
+
+    /// Returns whether point defense may launch.
+    pub fn is_point_defense_ready(&self) -> bool {
+        self.point_defense_delay == Ticks::ZERO
+    }
+
+    /// Call when point defense launches.
+    pub fn point_defense_launched(&mut self, delay: Ticks) {
+        self.point_defense_delay = delay;
+    }
+
+    /// Returns whether the player hasn't controlled the boat in a while.
+    pub fn is_afk(&self) -> bool {
+        self.idle > Self::AFK_THRESHOLD
+    }
+
+    /// Call when the player controls the boat.
+    pub fn reset_idle(&mut self) {
+        self.idle = Ticks::ZERO;
+    }
+
     /// Clears any remaining spawn protection (useful if showing signs of aggression, and thus
     /// no longer deserving of spawn protection).
     pub fn clear_spawn_protection(&mut self) {
@@ -107,10 +161,14 @@ impl EntityExtension {
     /// submerge
     /// deactivate_delay
     /// spawn_protection_remaining
+    /// point_defense_delay
+    /// And adds to idle.
     pub fn update_tickers(&mut self, delta: Ticks) {
         self.submerge_delay = self.submerge_delay.saturating_sub(delta);
+        self.point_defense_delay = self.point_defense_delay.saturating_sub(delta);
         self.deactivate_delay = self.deactivate_delay.saturating_sub(delta);
         self.spawn_protection_remaining = self.spawn_protection_remaining.saturating_sub(delta);
+        self.idle = self.idle.saturating_add(delta);
     }
 
     /// reloads_mut returns a mutable reference to the reloads component of the extension.
@@ -134,6 +192,10 @@ impl Default for EntityExtension {
             active: true,
             deactivate_delay: Ticks::ZERO,
             spawn_protection_remaining: Self::SPAWN_PROTECTION_INITIAL,
+            idle: Ticks::ZERO,
+            point_defense: PointDefense::default(),
+            point_defense_delay: Ticks::ZERO,
+            point_defense_target: None,
             reloads: box_default_n(0),
             turrets: arc_default_n(0),
         }
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::bot::Bot;
+use crate::entity::Entity;
+use crate::player::Status;
+use crate::server::Server;
+use common::entity::EntityType;
+use common::transform::Transform;
+use game_server::context::Context;
+use game_server::player::PlayerTuple;
+use glam::Vec2;
+use std::sync::Arc;
+
+/// Distance, in addition to the owner's radius, between escorts and their owner.
+const FORMATION_SPACING: f32 = 60.0;
+
+/// Returns where the escort in `slot` keeps station, given its owner's boat.
+pub fn formation_position(owner: &Transform, owner_radius: f32, slot: usize) -> Vec2 {
+    // Alternate between the port and starboard quarters, further astern for later slots.
+    let side = if slot % 2 == 0 { 1.0 } else { -1.0 };
+    let rank = (slot / 2 + 1) as f32;
+    let offset = Vec2::new(-rank, side) * (owner_radius + FORMATION_SPACING);
+    (*owner + Transform::from_position(offset)).position
+}
+
+impl Server {
+    /// Spawns escorts that players have paid for, refunding them if that fails.
+    pub(crate) fn spawn_escorts(&mut self, context: &mut Context<Self>) {
+        // Collect first, since hiring inserts into the player repo.
+        let hires: Vec<(Arc<PlayerTuple<Self>>, EntityType, u32)> = context
+            .players
+            .iter()
+            .flat_map(|owner| {
+                let hiring = std::mem::take(&mut owner.borrow_player_mut().data.hiring);
+                hiring
+                    .into_iter()
+                    .map(move |(entity_type, cost)| (Arc::clone(owner), entity_type, cost))
+            })
+            .collect();
+
+        for (owner, entity_type, cost) in hires {
+            let (owner_id, slot, boat) = {
+                let player = owner.borrow_player();
+                let boat = match player.data.status {
+                    Status::Alive { entity_index, .. } if !player.data.flags.left_game => {
+                        let boat = &self.world.entities[entity_index];
+                        Some((boat.transform, boat.data().radius))
+                    }
+                    _ => None,
+                };
+                (player.player_id, player.data.escorts.len(), boat)
+            };
+
+            let escort_id = boat.and_then(|(transform, radius)| {
+                let escort =
+                    context
+                        .bots
+                        .hire(Bot::escort(owner_id), self, &mut context.players)?;
+
+                let escort_id = {
+                    let mut player = escort.borrow_player_mut();
+                    // Escorts are paid for by their owner, so start with nothing.
+                    player.score = 0;
+                    player.data.owner = Some(Arc::clone(&owner));
+                    player.player_id
+                };
+
+                let mut entity = Entity::new(entity_type, Some(escort));
+                entity.transform = transform;
+                entity.transform.position = formation_position(&transform, radius, slot);
+                // Otherwise, the escort quits for lack of a boat.
+                self.world
+                    .spawn_here_or_nearby(entity, FORMATION_SPACING, None)
+                    .then_some(escort_id)
+            });
+
+            let mut player = owner.borrow_player_mut();
+            if let Some(escort_id) = escort_id {
+                player.data.escorts.push(escort_id);
+            } else {
+                player.score += cost;
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::escort::formation_position;
+    use common::angle::Angle;
+    use common::transform::Transform;
+    use glam::Vec2;
+
+    #[test]
+    fn formation() {
+        let owner = Transform {
+            position: Vec2::new(100.0, 0.0),
+            direction: Angle::from_degrees(90.0),
+            ..Transform::default()
+        };
+        let first = formation_position(&owner, 40.0, 0);
+        let second = formation_position(&owner, 40.0, 1);
+        let third = formation_position(&owner, 40.0, 2);
+
+        // Astern (south, since the owner heads north), on either side.
+        assert!(first.y < 0.0 && second.y < 0.0, "{} {}", first, second);
+        assert!(first.x < owner.position.x && second.x > owner.position.x);
+        assert!((first.y - second.y).abs() < 0.01);
+        // Later slots are further astern.
+        assert!(third.y < first.y);
+    }
+}
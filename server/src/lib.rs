@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+#![feature(drain_filter)]
+#![feature(new_uninit)]
+#![feature(get_mut_unchecked)]
+#![feature(async_closure)]
+#![feature(hash_drain_filter)]
+#![feature(type_alias_impl_trait)]
+#![feature(generic_associated_types)]
+#![feature(test)]
+
+//! The game server has authority over all game logic. Clients are served the client, which connects
+//! via websocket.
+
+// Actually required see https://doc.rust-lang.org/beta/unstable-book/library-features/test.html
+#[cfg(test)]
+extern crate test;
+
+pub use crate::server::Server;
+
+mod arena;
+pub mod bot;
+pub mod bot_model;
+mod bounty;
+mod budget;
+mod chunk_generator;
+mod collision;
+mod complete_ref;
+mod contact_cache;
+mod contact_ref;
+mod entities;
+mod entity;
+mod entity_extension;
+mod escort;
+pub mod noise;
+mod player;
+mod protocol;
+mod route;
+pub mod server;
+pub mod world;
+mod world_dipping_sonar;
+mod world_inbound;
+mod world_mutation;
+mod world_outbound;
+mod world_physics;
+mod world_physics_radius;
+mod world_point_defense;
+mod world_spawn;
+#[cfg(test)]
+mod world_test;
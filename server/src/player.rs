@@ -1,11 +1,19 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::bounty::Bounty;
 use crate::entities::*;
+use crate::server::Server;
+use common::angle::Angle;
 use common::death_reason::DeathReason;
+use common::entity::EntityType;
 use common::protocol::Hint;
+use common::ticks::Ticks;
+use core_protocol::id::PlayerId;
+use game_server::player::PlayerTuple;
 use glam::Vec2;
 use std::fmt::Debug;
+use std::sync::Arc;
 use std::time::Instant;
 
 /// A player's view into the world.
@@ -36,6 +44,8 @@ pub enum Status {
         entity_index: EntityIndex,
         /// Where the player is aiming. Used by turrets and aircraft.
         aim_target: Option<Vec2>,
+        /// Whether the player ordered their helicopters to hover in place.
+        hover: bool,
     },
     /// Player had a boat.
     Dead {
@@ -57,6 +67,7 @@ impl Status {
         Self::Alive {
             entity_index,
             aim_target: None,
+            hover: false,
         }
     }
 
@@ -88,6 +99,17 @@ impl Status {
     }
 }
 
+/// A weapon that will be fired, as part of a salvo, after a delay.
+#[derive(Debug)]
+pub struct PendingShot {
+    /// The index of the weapon, relative to `EntityData.armaments`.
+    pub armament_index: u8,
+    /// Offset of the weapon's aim.
+    pub offset: Angle,
+    /// Remaining delay.
+    pub delay: Ticks,
+}
+
 /// Player is the owner of a boat, either a real person or a bot.
 #[derive(Debug)]
 pub struct Player {
@@ -99,6 +121,16 @@ pub struct Player {
     pub hint: Hint,
     /// Current status e.g. Alive, Dead, or Spawning.
     pub status: Status,
+    /// Bounty on this player's boat.
+    pub bounty: Bounty,
+    /// Weapons waiting to be fired as part of a salvo.
+    pub salvo: Vec<PendingShot>,
+    /// The player this player escorts, if it is a hired escort.
+    pub owner: Option<Arc<PlayerTuple<Server>>>,
+    /// Ids of this player's escorts, in order of formation slot.
+    pub escorts: Vec<PlayerId>,
+    /// Escorts paid for but not yet spawned, and what they cost.
+    pub hiring: Vec<(EntityType, u32)>,
 }
 
 impl Default for Player {
@@ -108,6 +140,11 @@ impl Default for Player {
             flags: Flags::default(),
             hint: Hint::default(),
             status: Status::Spawning,
+            bounty: Bounty::default(),
+            salvo: Vec::new(),
+            owner: None,
+            escorts: Vec::new(),
+            hiring: Vec::new(),
         }
     }
 }
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use common::altitude::Altitude;
+use common::protocol::FlightProfile;
+use glam::Vec2;
+
+/// A missile's launch-time flight profile and waypoints, which its guidance follows until it
+/// reaches the last waypoint and starts homing.
+#[derive(Debug)]
+pub struct Route {
+    pub profile: FlightProfile,
+    /// Waypoints, followed by the aim target at launch.
+    points: Vec<Vec2>,
+    /// Index into `points` of the point being flown towards.
+    next: usize,
+}
+
+impl Route {
+    /// A waypoint is reached when the missile comes within this distance of it.
+    const WAYPOINT_RADIUS: f32 = 60.0;
+    /// High altitude missiles dive this many seconds from their target.
+    const DIVE_SECONDS: f32 = 4.0;
+    /// Cruising altitude of sea-skimming missiles.
+    pub const SEA_SKIMMING_ALTITUDE: Altitude = Altitude::from_whole_meters(6);
+
+    /// Creates a route through `waypoints` to `target`.
+    pub fn new(profile: FlightProfile, waypoints: &[Vec2], target: Vec2) -> Self {
+        let mut points = waypoints.to_vec();
+        points.push(target);
+        Self {
+            profile,
+            points,
+            next: 0,
+        }
+    }
+
+    /// Returns the length of the route, starting from `position`, up to the last waypoint.
+    pub fn waypoints_length(&self, position: Vec2) -> f32 {
+        let waypoints = &self.points[..self.points.len() - 1];
+        waypoints
+            .iter()
+            .scan(position, |previous, &waypoint| {
+                let length = previous.distance(waypoint);
+                *previous = waypoint;
+                Some(length)
+            })
+            .sum()
+    }
+
+    /// Returns the waypoint to steer towards, if any remain. Missiles don't home while en route.
+    pub fn waypoint(&self) -> Option<Vec2> {
+        (self.next < self.points.len() - 1).then(|| self.points[self.next])
+    }
+
+    /// Returns true if a missile at `position` has reached its current waypoint.
+    pub fn reached(&self, position: Vec2) -> bool {
+        self.waypoint().map_or(false, |waypoint| {
+            position.distance_squared(waypoint) < Self::WAYPOINT_RADIUS.powi(2)
+        })
+    }
+
+    /// Moves on to the next waypoint.
+    pub fn advance(&mut self) {
+        self.next = (self.next + 1).min(self.points.len() - 1);
+    }
+
+    /// Returns the altitude that a missile at `position`, flying at `speed` (in meters per
+    /// second), should fly at, or `None` for the usual altitude.
+    pub fn altitude_target(&self, position: Vec2, speed: f32) -> Option<Altitude> {
+        match self.profile {
+            FlightProfile::Direct => None,
+            FlightProfile::SeaSkimming => Some(Self::SEA_SKIMMING_ALTITUDE),
+            FlightProfile::HighAltitude => {
+                let target = *self.points.last().unwrap();
+                let dive_distance = speed * Self::DIVE_SECONDS;
+                (self.waypoint().is_some()
+                    || position.distance_squared(target) > dive_distance.powi(2))
+                .then_some(Altitude::MAX)
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::route::Route;
+    use common::altitude::Altitude;
+    use common::protocol::FlightProfile;
+    use glam::Vec2;
+
+    #[test]
+    fn route() {
+        let waypoint = Vec2::new(1000.0, 0.0);
+        let target = Vec2::new(1000.0, 1000.0);
+        let mut route = Route::new(FlightProfile::HighAltitude, &[waypoint], target);
+
+        assert_eq!(route.waypoints_length(Vec2::ZERO), 1000.0);
+        assert_eq!(route.waypoint(), Some(waypoint));
+        assert!(!route.reached(Vec2::ZERO));
+        assert_eq!(
+            route.altitude_target(Vec2::new(990.0, 0.0), 100.0),
+            Some(Altitude::MAX)
+        );
+        assert!(route.reached(Vec2::new(990.0, 0.0)));
+
+        route.advance();
+        assert_eq!(route.waypoint(), None);
+        assert_eq!(
+            route.altitude_target(Vec2::new(1000.0, 500.0), 100.0),
+            Some(Altitude::MAX)
+        );
+        // Dive onto target.
+        assert_eq!(route.altitude_target(Vec2::new(1000.0, 900.0), 100.0), None);
+
+        // Advancing past the target does nothing.
+        route.advance();
+        assert_eq!(route.waypoint(), None);
+    }
+}
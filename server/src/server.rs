@@ -2,21 +2,31 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::bot::*;
+use crate::bot_model::{Decision, Observation, Recorder, MODEL};
+use crate::contact_cache::ContactCache;
 use crate::entity_extension::EntityExtension;
 use crate::player::*;
 use crate::protocol::*;
 use crate::world::World;
-use common::entity::EntityType;
-use common::protocol::{Command, Update};
+use crate::world_inbound::fire_pending_shots;
+use common::angle::Angle;
+use common::complete::CompleteTrait;
+use common::contact::{ContactTrait, EncodedContact};
+use common::entity::{EntityId, EntityType};
+use common::protocol::{Alerts, Bounty, Command, Update};
 use common::terrain::ChunkSet;
 use common::ticks::Ticks;
 use common::util::level_to_score;
 use core_protocol::id::*;
+use core_protocol::metrics::RatioMetric;
 use game_server::context::Context;
 use game_server::game_service::GameArenaService;
-use game_server::player::{PlayerRepo, PlayerTuple};
+use game_server::load_shedding::LoadShedding;
+use game_server::player::{PlayerData, PlayerRepo, PlayerTuple};
 use log::{error, warn};
+use rand::{thread_rng, Rng};
 use std::cell::UnsafeCell;
+use std::collections::HashSet;
 use std::sync::Arc;
 use std::time::Duration;
 
@@ -24,6 +34,14 @@ use std::time::Duration;
 pub struct Server {
     pub world: World,
     pub counter: Ticks,
+    /// Records real players' play, for training bots.
+    pub recorder: Recorder,
+    /// Used to tune the bot population.
+    pub population: PopulationStats,
+    /// Approximate positions of players with bounties.
+    pub bounties: Arc<[Bounty]>,
+    /// Contacts encoded this tick, shared by all clients' updates.
+    pub contact_cache: ContactCache,
 }
 
 /// Stores a player, and metadata related to it. Data stored here may only be accessed when processing,
@@ -31,6 +49,129 @@ pub struct Server {
 #[derive(Default, Debug)]
 pub struct ClientData {
     pub loaded_chunks: ChunkSet,
+    /// The player's boat, and its damage, as of the previous update.
+    pub damage: Option<(EntityId, Ticks)>,
+    /// Hostile boats among the player's contacts, as of the previous update.
+    pub hostiles: HashSet<EntityId>,
+}
+
+impl Server {
+    /// Idle or distant clients are updated this often while shedding load.
+    const THROTTLED_STRIDE: Ticks = Ticks::from_repr(2);
+    /// How often the approximate positions of bounty holders are refreshed.
+    const BOUNTY_REFRESH: Ticks = Ticks::from_whole_secs(10);
+    /// Radius of the ring containing a bounty holder.
+    const BOUNTY_RADIUS: f32 = 1000.0;
+
+    /// Returns true if the player is dead, away from keyboard, or has no other boats in sight, so
+    /// won't notice less frequent updates.
+    fn is_idle_or_distant(&self, player: &PlayerData<Self>) -> bool {
+        if let Status::Alive { entity_index, .. } = player.data.status {
+            let entity = &self.world.entities[entity_index];
+            entity.extension().is_afk()
+                || !self
+                    .world
+                    .entities
+                    .iter_radius(
+                        entity.transform.position,
+                        entity.data().sensors.visual.range,
+                    )
+                    .any(|(_, other)| other.is_boat() && other.id != entity.id)
+        } else {
+            true
+        }
+    }
+
+    /// Returns events, since the previous update, that the player may want to be alerted of.
+    /// `hostiles` are the hostile contacts in the player's current update.
+    fn alerts(
+        &self,
+        player: &PlayerData<Self>,
+        client_data: &mut ClientData,
+        hostiles: HashSet<EntityId>,
+    ) -> Alerts {
+        let mut alerts = Alerts::default();
+        if let Status::Alive { entity_index, .. } = player.data.status {
+            let entity = &self.world.entities[entity_index];
+
+            // The first update of each boat (including after respawning) is only a baseline.
+            let previous_damage = client_data
+                .damage
+                .filter(|&(id, _)| id == entity.id)
+                .map(|(_, damage)| damage);
+            client_data.damage = Some((entity.id, entity.ticks));
+
+            // Repairs lower the damage, which isn't alerted of.
+            alerts.damaged = previous_damage.map_or(false, |damage| entity.ticks > damage);
+            alerts.hostile = previous_damage.is_some()
+                && hostiles.iter().any(|id| !client_data.hostiles.contains(id));
+            client_data.hostiles = hostiles;
+        } else {
+            client_data.damage = None;
+            client_data.hostiles.clear();
+        }
+        alerts
+    }
+
+    /// Called once per second to update bounties, announce new bounty holders, and
+    /// periodically refresh their approximate positions.
+    fn update_bounties(&mut self, context: &mut Context<Self>) {
+        let leader = context.liveboard().first().map(|dto| dto.player_id);
+        let refresh = self.counter.every(Self::BOUNTY_REFRESH);
+        let mut rng = thread_rng();
+        let mut announcements = Vec::new();
+        let mut bounties = Vec::new();
+
+        for mut player in context.players.iter_borrow_mut() {
+            let player_id = player.player_id;
+            let entity_index = if let Status::Alive { entity_index, .. } = player.data.status {
+                entity_index
+            } else {
+                continue;
+            };
+
+            let bounty = &mut player.data.bounty;
+            bounty.update(Ticks::from_whole_secs(1), leader == Some(player_id));
+            if !bounty.is_wanted() {
+                continue;
+            }
+            let value = bounty.value();
+            if bounty.take_announcement() {
+                announcements.push(format!(
+                    "{} is wanted! Sink them to collect a bounty of {}.",
+                    player.alias(),
+                    value
+                ));
+            }
+
+            // Keep the previous ring, unless it is time to refresh it.
+            let previous = self
+                .bounties
+                .iter()
+                .find(|b| b.player_id == player_id)
+                .filter(|_| !refresh)
+                .map(|b| b.position);
+            let position = previous.unwrap_or_else(|| {
+                // Offset the ring so that it doesn't reveal the exact position.
+                let offset =
+                    rng.gen::<Angle>().to_vec() * (rng.gen::<f32>() * Self::BOUNTY_RADIUS * 0.75);
+                self.world.entities[entity_index].transform.position + offset
+            });
+
+            bounties.push(Bounty {
+                player_id,
+                value,
+                position,
+                radius: Self::BOUNTY_RADIUS,
+            });
+        }
+
+        self.bounties = bounties.into();
+
+        for text in announcements {
+            context.announce(text);
+        }
+    }
 }
 
 #[derive(Default)]
@@ -52,18 +193,23 @@ impl GameArenaService for Server {
 
     type Bot = Bot;
     type ClientData = ClientData;
-    type GameUpdate = Update;
+    type GameUpdate = Update<EncodedContact>;
     type GameRequest = Command;
     type PlayerData = Player;
     type PlayerExtension = PlayerExtension;
 
     /// new returns a game server with the specified parameters.
     fn new(min_players: usize) -> Self {
+        lazy_static::initialize(&MODEL);
         Self {
             world: World::new(World::target_radius(
                 min_players as f32 * EntityType::FairmileD.data().visual_area(),
             )),
             counter: Ticks::ZERO,
+            recorder: Recorder::new(),
+            population: PopulationStats::default(),
+            bounties: Vec::new().into(),
+            contact_cache: ContactCache::default(),
         }
     }
 
@@ -103,7 +249,28 @@ impl GameArenaService for Server {
         update: Self::GameRequest,
         player: &Arc<PlayerTuple<Self>>,
         _players: &PlayerRepo<Server>,
-    ) -> Option<Update> {
+    ) -> Option<Self::GameUpdate> {
+        if let Command::Control(control) = &update {
+            let player_id = player.borrow_player().player_id;
+            if !player_id.is_bot() && self.recorder.should_sample(player_id) {
+                let mut complete = self.world.get_player_complete(player);
+                let contacts = complete.collect_contacts();
+                if let Some(boat) = contacts
+                    .first()
+                    .filter(|c| c.is_boat() && c.player_id() == Some(player_id))
+                {
+                    let observation = Observation::new(
+                        boat,
+                        &contacts,
+                        complete.terrain(),
+                        complete.world_radius(),
+                    );
+                    self.recorder
+                        .record(observation, Decision::from_control(boat, control));
+                }
+            }
+        }
+
         if let Err(e) = update.as_command().apply(&mut self.world, player) {
             warn!("Command resulted in {}", e);
         }
@@ -145,6 +312,17 @@ impl GameArenaService for Server {
 
         // Delete all player's entities (efficiently, in the next update cycle).
         player.data.flags.left_game = true;
+
+        // Free up the escort's formation slot.
+        if let Some(owner) = player.data.owner.clone() {
+            let player_id = player.player_id;
+            drop(player);
+            owner
+                .borrow_player_mut()
+                .data
+                .escorts
+                .retain(|&id| id != player_id);
+        }
     }
 
     fn get_game_update(
@@ -153,11 +331,38 @@ impl GameArenaService for Server {
         client_data: &mut Self::ClientData,
         _players: &PlayerRepo<Server>,
     ) -> Option<Self::GameUpdate> {
-        Some(
-            self.world
-                .get_player_complete(player)
-                .into_update(self.counter, &mut client_data.loaded_chunks),
-        )
+        let stride = if self.world.load_shedding >= LoadShedding::ThrottleClients
+            && self.is_idle_or_distant(&player.borrow_player())
+        {
+            Self::THROTTLED_STRIDE
+        } else {
+            Ticks::ONE
+        };
+
+        if !self.counter.every(stride) {
+            // Chunks updated in the meantime must be sent in full next time.
+            client_data.loaded_chunks = client_data
+                .loaded_chunks
+                .and(&self.world.terrain.updated.not());
+            return None;
+        }
+
+        let mut hostiles = HashSet::new();
+        let mut update = self.world.get_player_complete(player).into_update(
+            self.counter,
+            stride,
+            &mut client_data.loaded_chunks,
+            &self.bounties,
+            &mut hostiles,
+            &self.contact_cache,
+        );
+        update.alerts = self.alerts(&player.borrow_player(), client_data, hostiles);
+        Some(update)
+    }
+
+    /// Each update contains all visible contacts, so only terrain and alerts need to arrive.
+    fn is_update_lossy(update: &Self::GameUpdate) -> bool {
+        update.terrain.is_empty() && update.alerts == Alerts::default()
     }
 
     fn is_alive(&self, player_tuple: &Arc<PlayerTuple<Self>>) -> bool {
@@ -168,11 +373,27 @@ impl GameArenaService for Server {
     /// update runs server ticks.
     fn tick(&mut self, context: &mut Context<Self>) {
         self.counter = self.counter.next();
+        self.world.load_shedding = context.load_shedding;
+
+        for player_tuple in context.players.iter() {
+            fire_pending_shots(&mut self.world, player_tuple, Ticks::ONE);
+        }
 
         self.world.update(Ticks::ONE);
+        self.spawn_escorts(context);
 
         // Needs to be called before clients receive updates, but after World::update.
         self.world.terrain.pre_update();
+        self.contact_cache.clear();
+
+        if self.counter.every(Ticks::from_whole_secs(1)) {
+            self.population = PopulationStats::new(&self.world, &context.players);
+            self.update_bounties(context);
+        }
+
+        if self.counter.every(Ticks::from_whole_secs(10)) {
+            self.recorder.flush();
+        }
 
         if self.counter.every(Ticks::from_whole_secs(60)) {
             use std::collections::{BTreeMap, HashMap};
@@ -248,4 +469,111 @@ impl GameArenaService for Server {
         // Needs to be after clients receive updates.
         self.world.terrain.post_update();
     }
+
+    fn take_over_budget(&mut self) -> RatioMetric {
+        self.world.budgets.take_over_budget()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::entity::Entity;
+    use crate::player::Status;
+    use crate::server::{ClientData, Server};
+    use crate::world::World;
+    use common::death_reason::DeathReason;
+    use common::entity::{EntityId, EntityType};
+    use common::protocol::Alerts;
+    use common::terrain::Terrain;
+    use common::ticks::Ticks;
+    use core_protocol::id::PlayerId;
+    use game_server::game_service::GameArenaService;
+    use game_server::player::{PlayerData, PlayerTuple};
+    use std::num::NonZeroU32;
+    use std::sync::Arc;
+
+    /// Sets the damage of the player's boat.
+    fn set_damage(server: &mut Server, player: &PlayerTuple<Server>, seconds: u32) {
+        if let Status::Alive { entity_index, .. } = player.borrow_player().data.status {
+            server.world.entities[entity_index].ticks = Ticks::from_whole_secs(seconds);
+        } else {
+            panic!("not alive");
+        }
+    }
+
+    fn spawn(server: &mut Server, player: &Arc<PlayerTuple<Server>>) -> EntityId {
+        let boat = Entity::new(EntityType::Osa, Some(Arc::clone(player)));
+        server.world.try_spawn_with_id(boat).unwrap()
+    }
+
+    fn sink(server: &mut Server, player: &PlayerTuple<Server>) {
+        let entity_index = match player.borrow_player().data.status {
+            Status::Alive { entity_index, .. } => entity_index,
+            _ => panic!("not alive"),
+        };
+        server.world.remove(entity_index, DeathReason::Unknown);
+    }
+
+    #[test]
+    fn alerts() {
+        let mut server = Server::new(0);
+        server.world = World::new(10000.0);
+        server.world.terrain = Terrain::new();
+        let player = Arc::new(PlayerTuple::new(PlayerData::new(
+            PlayerId(NonZeroU32::new(1).unwrap()),
+            None,
+        )));
+        let mut client_data = ClientData::default();
+        let alerts = |server: &Server, client_data: &mut ClientData, hostiles: &[u32]| {
+            let hostiles = hostiles
+                .iter()
+                .map(|&id| EntityId::new(id).unwrap())
+                .collect();
+            server.alerts(&player.borrow_player(), client_data, hostiles)
+        };
+        let damaged = Alerts {
+            damaged: true,
+            hostile: false,
+        };
+        let hostile = Alerts {
+            damaged: false,
+            hostile: true,
+        };
+
+        // The first update is a baseline, despite damage and hostiles.
+        let first = spawn(&mut server, &player);
+        set_damage(&mut server, &player, 1);
+        assert_eq!(alerts(&server, &mut client_data, &[100]), Alerts::default());
+
+        set_damage(&mut server, &player, 2);
+        assert_eq!(alerts(&server, &mut client_data, &[100]), damaged);
+
+        // Repairing isn't damage.
+        set_damage(&mut server, &player, 1);
+        assert_eq!(alerts(&server, &mut client_data, &[100]), Alerts::default());
+
+        assert_eq!(alerts(&server, &mut client_data, &[100, 101]), hostile);
+        assert_eq!(alerts(&server, &mut client_data, &[101]), Alerts::default());
+        assert_eq!(alerts(&server, &mut client_data, &[100, 101]), hostile);
+
+        // Respawn, without an update in between, into a new boat with more damage.
+        sink(&mut server, &player);
+        let second = spawn(&mut server, &player);
+        assert_ne!(first, second);
+        set_damage(&mut server, &player, 3);
+        assert_eq!(
+            alerts(&server, &mut client_data, &[100, 101, 102]),
+            Alerts::default()
+        );
+
+        assert_eq!(alerts(&server, &mut client_data, &[100, 101, 103]), hostile);
+
+        // Respawn, with an update in between.
+        sink(&mut server, &player);
+        assert_eq!(alerts(&server, &mut client_data, &[]), Alerts::default());
+        spawn(&mut server, &player);
+        assert_eq!(alerts(&server, &mut client_data, &[100]), Alerts::default());
+        set_damage(&mut server, &player, 1);
+        assert_eq!(alerts(&server, &mut client_data, &[100]), damaged);
+    }
 }
@@ -2,14 +2,24 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::arena::Arena;
+use crate::budget::{BudgetKind, Budgets};
+use crate::chunk_generator::ChunkGenerator;
 use crate::entities::{Entities, EntityIndex};
 use crate::entity::Entity;
 use crate::noise::noise_generator;
+use crate::route::Route;
+use crate::server::Server;
+use crate::world_dipping_sonar::Hover;
 use crate::world_mutation::Mutation;
 use common::death_reason::DeathReason;
-use common::entity::{EntityKind, EntityType};
+use common::entity::{EntityId, EntityKind, EntityType};
 use common::terrain::Terrain;
 use common::ticks::Ticks;
+use game_server::load_shedding::LoadShedding;
+use game_server::player::PlayerTuple;
+use glam::Vec2;
+use std::collections::HashMap;
+use std::sync::Arc;
 
 /// A game world of variable radius, consisting of entities and a terrain.
 pub struct World {
@@ -17,26 +27,66 @@ pub struct World {
     pub entities: Entities,
     pub terrain: Terrain,
     pub radius: f32,
+    /// Radius that `radius` is growing or shrinking towards.
+    pub target_radius: f32,
+    /// Routes of missiles launched with a flight profile or waypoints.
+    pub routes: HashMap<EntityId, Route>,
+    /// Helicopters hovering in place (see `World::is_dipping`).
+    pub hovering: HashMap<EntityId, Hover>,
+    /// Live weapons, aircraft, and decoys of each player and team.
+    pub budgets: Budgets,
+    /// Degradations to apply, because the server is overloaded.
+    pub load_shedding: LoadShedding,
+    /// Generates `terrain` in the background.
+    pub chunk_generator: ChunkGenerator,
 }
 
 impl World {
+    /// Minimum speed, in meters per second, at which the world grows.
+    const GROWTH_SPEED: f32 = 2.0;
+    /// Growth speeds up to close any gap in roughly this many seconds.
+    const GROWTH_SECONDS: f32 = 30.0;
+    /// Speed, in meters per second, at which the world shrinks.
+    const SHRINK_SPEED: f32 = 1.0;
+    /// Players are warned this many seconds before the shrinking border reaches them.
+    const BORDER_WARNING_SECONDS: f32 = 60.0;
+
     /// Creates a new World with the given parameters.
     pub fn new(initial_radius: f32) -> Self {
+        let terrain = Terrain::with_background_generator(noise_generator);
+        terrain.prefetch(Vec2::ZERO, Vec2::splat(initial_radius * 2.0));
+
         Self {
             arena: Arena::new(),
             entities: Entities::new(),
-            terrain: Terrain::with_generator(noise_generator),
+            terrain,
             radius: initial_radius,
+            target_radius: initial_radius,
+            routes: HashMap::new(),
+            hovering: HashMap::new(),
+            budgets: Budgets::default(),
+            load_shedding: LoadShedding::None,
+            chunk_generator: ChunkGenerator::new(noise_generator),
         }
     }
 
     /// Updates the internals of the world, spawning and updating existing entities.
     pub fn update(&mut self, delta: Ticks) {
+        self.prefetch_terrain();
+        self.chunk_generator.update(&mut self.terrain);
         self.spawn_statics(delta);
         self.physics(delta);
         self.physics_radius(delta);
+        self.point_defense();
+        self.dipping_sonar();
         self.arena.recycle();
 
+        let budgeted = self.arena.count_kind(EntityKind::Weapon)
+            + self.arena.count_kind(EntityKind::Aircraft)
+            + self.arena.count_kind(EntityKind::Decoy);
+        self.budgets
+            .set_load(budgeted, self.load_shedding >= LoadShedding::CapEntities);
+
         let total_visual_area = EntityType::iter()
             .map(|t| {
                 let data = t.data();
@@ -48,27 +98,65 @@ impl World {
             })
             .sum::<f32>();
 
-        let target_radius = Self::target_radius(total_visual_area);
-        let s = delta.to_secs();
+        self.target_radius = Self::target_radius(total_visual_area);
+        self.radius = Self::next_radius(self.radius, self.target_radius, delta.to_secs());
+    }
 
-        // Takes effect during testing with large bot counts.
-        if target_radius.powi(2) > self.radius.powi(2) + 1000f32.powi(2) {
-            self.radius = target_radius;
+    /// Returns the radius after `seconds` of growing or shrinking towards `target_radius`. The
+    /// world shrinks at a constant speed, so players can predict when the border reaches them, and
+    /// grows at least as fast, speeding up to close large gaps (e.g. when many players spawn)
+    /// without jumping.
+    fn next_radius(radius: f32, target_radius: f32, seconds: f32) -> f32 {
+        if target_radius > radius {
+            let speed = Self::GROWTH_SPEED.max((target_radius - radius) / Self::GROWTH_SECONDS);
+            (radius + speed * seconds).min(target_radius)
         } else {
-            self.radius += (target_radius - self.radius).clamp(-s, 2.0 * s);
+            (radius - Self::SHRINK_SPEED * seconds).max(target_radius)
         }
     }
 
-    /// Adds an entity to the world (assigning it an id).
-    pub fn add(&mut self, mut entity: Entity) {
-        entity.id = self.arena.new_id(entity.entity_type);
-        self.entities.add_internal(entity)
+    /// Returns the seconds until the shrinking border reaches `position`, if it will within
+    /// `BORDER_WARNING_SECONDS`.
+    pub fn border_warning(&self, position: Vec2) -> Option<f32> {
+        let distance = position.length();
+        if self.target_radius >= self.radius || distance <= self.target_radius {
+            return None;
+        }
+        let seconds = (self.radius - distance).max(0.0) / Self::SHRINK_SPEED;
+        (seconds < Self::BORDER_WARNING_SECONDS).then_some(seconds)
+    }
+
+    /// Adds an entity to the world (assigning it an id), returning its id.
+    pub fn add(&mut self, mut entity: Entity) -> EntityId {
+        let id = self.arena.new_id(entity.entity_type);
+        entity.id = id;
+        if let Some(kind) = BudgetKind::of(entity.data()) {
+            if let Some(player) = entity.player.as_ref() {
+                let player = player.borrow_player();
+                let (player_id, team_id) = (player.player_id, player.team_id());
+                self.budgets.add(id, player_id, team_id, kind);
+                // Spamming weapons (by any means) expires older ones instead of lagging the server.
+                self.budgets.make_room(player_id, team_id, kind);
+            }
+        }
+        self.entities.add_internal(entity);
+        id
     }
 
     /// Removes an entity from the world with a given index and death reason.
     /// Calls Mutation::on_world_remove.
     pub fn remove(&mut self, index: EntityIndex, reason: DeathReason) {
-        Mutation::on_world_remove(self, index, &reason);
+        self.remove_with_killer(index, reason, None);
+    }
+
+    /// Like `remove`, but the killer collects any bounty on the entity's player.
+    pub fn remove_with_killer(
+        &mut self,
+        index: EntityIndex,
+        reason: DeathReason,
+        killer: Option<&Arc<PlayerTuple<Server>>>,
+    ) {
+        Mutation::on_world_remove(self, index, &reason, killer);
         let entity = self.entities.remove_internal(index, reason);
         self.arena.drop_entity(entity);
     }
@@ -95,3 +183,28 @@ impl World {
         Entities::max_world_radius().min(Terrain::max_world_radius())
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use crate::world::World;
+
+    #[test]
+    fn next_radius() {
+        // Shrinks at a constant speed.
+        let radius = World::next_radius(1000.0, 500.0, 10.0);
+        assert_eq!(radius, 1000.0 - World::SHRINK_SPEED * 10.0);
+
+        // Never overshoots.
+        assert_eq!(World::next_radius(1000.0, 999.0, 10.0), 999.0);
+        assert_eq!(World::next_radius(1000.0, 1001.0, 10.0), 1001.0);
+
+        // Closes most of a large gap smoothly, within a few GROWTH_SECONDS.
+        let mut radius = 1000.0;
+        for _ in 0..(World::GROWTH_SECONDS * 3.0 / 0.1) as usize {
+            let next = World::next_radius(radius, 10000.0, 0.1);
+            assert!(next - radius < 50.0, "{} {}", radius, next);
+            radius = next;
+        }
+        assert!(radius > 9000.0, "{}", radius);
+    }
+}
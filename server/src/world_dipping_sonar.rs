@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::entities::EntityIndex;
+use crate::entity::Entity;
+use crate::server::Server;
+use crate::world::World;
+use crate::world_mutation::Mutation;
+use common::angle::Angle;
+use common::entity::*;
+use common::ticks::Ticks;
+use game_server::player::PlayerTuple;
+use glam::Vec2;
+use maybe_parallel_iterator::IntoMaybeParallelIterator;
+use std::sync::Arc;
+
+/// A helicopter hovering in place.
+pub struct Hover {
+    /// How long the helicopter has been hovering.
+    pub duration: Ticks,
+    /// Where the helicopter is hovering.
+    pub position: Vec2,
+    /// Owner of the helicopter, who gets the dipping sonar's contacts.
+    pub player: Arc<PlayerTuple<Server>>,
+}
+
+impl Hover {
+    /// Returns true if the helicopter has lowered its dipping sonar.
+    pub fn is_dipping(&self) -> bool {
+        self.duration >= World::DIPPING_SONAR_DELAY
+    }
+}
+
+impl World {
+    /// Helicopters within this distance of their target may hover.
+    pub const HOVER_RADIUS: f32 = 80.0;
+    /// Helicopters slower than this, in meters per second, are hovering.
+    pub const HOVER_SPEED: f32 = 2.0;
+    /// Anti-aircraft is this many times as likely to shoot down a hovering helicopter.
+    pub const HOVER_VULNERABILITY: f32 = 3.0;
+    /// Time spent hovering before the dipping sonar is lowered.
+    const DIPPING_SONAR_DELAY: Ticks = Ticks::from_whole_secs(3);
+    /// Range of a lowered dipping sonar (acts as active sonar for the helicopter's owner).
+    pub const DIPPING_SONAR_RANGE: f32 = 500.0;
+
+    /// Returns true if `entity` is a helicopter that has lowered its dipping sonar.
+    pub fn is_dipping(&self, entity: &Entity) -> bool {
+        self.hovering
+            .get(&entity.id)
+            .map_or(false, Hover::is_dipping)
+    }
+
+    /// Helicopters drop torpedoes on hostile submarines detected by their dipping sonar.
+    pub fn dipping_sonar(&mut self) {
+        let drops: Vec<(EntityIndex, Angle)> = self
+            .entities
+            .par_iter()
+            .into_maybe_parallel_iter()
+            .filter_map(|(index, heli)| {
+                if !self.is_dipping(heli) {
+                    return None;
+                }
+
+                // Uses aircraft lifespan as weapon consumption, like other aircraft drops.
+                let amount = heli
+                    .data()
+                    .armaments
+                    .iter()
+                    .filter(|a| a.entity_type.data().sub_kind == EntitySubKind::Torpedo)
+                    .count();
+                if amount == 0 || heli.ticks <= Ticks::from_secs(3.0 * amount as f32) {
+                    return None;
+                }
+
+                let position = heli.transform.position;
+                self.entities
+                    .iter_radius(position, Self::DIPPING_SONAR_RANGE)
+                    .map(|(_, other)| other)
+                    .filter(|other| {
+                        other.data().sub_kind == EntitySubKind::Submarine
+                            && other.altitude.is_submerged()
+                            && !heli.is_friendly(other)
+                    })
+                    .map(|sub| sub.transform.position)
+                    .min_by(|a, b| {
+                        a.distance_squared(position)
+                            .total_cmp(&b.distance_squared(position))
+                    })
+                    .map(|target| (index, Angle::from(target - position)))
+            })
+            .collect();
+
+        for (index, direction) in drops {
+            // Turn in place to face the submarine before dropping.
+            self.entities[index].transform.direction = direction;
+            Mutation::FireAll(EntitySubKind::Torpedo).apply(self, index, Ticks::ZERO, true);
+        }
+    }
+}
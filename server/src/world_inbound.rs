@@ -2,8 +2,9 @@
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
 use crate::entity::Entity;
-use crate::player::Status;
+use crate::player::{PendingShot, Status};
 use crate::protocol::*;
+use crate::route::Route;
 use crate::server::Server;
 use crate::world::World;
 use common::angle::Angle;
@@ -17,6 +18,7 @@ use common_util::range::map_ranges;
 use game_server::player::PlayerTuple;
 use glam::Vec2;
 use maybe_parallel_iterator::IntoMaybeParallelIterator;
+use rand::seq::IteratorRandom;
 use rand::{thread_rng, Rng};
 use std::ops::Range;
 use std::sync::Arc;
@@ -196,7 +198,7 @@ impl CommandTrait for Control {
         return if let Status::Alive {
             entity_index,
             aim_target,
-            ..
+            hover,
         } = &mut player.data.status
         {
             let entity = &mut world.entities[*entity_index];
@@ -215,9 +217,12 @@ impl CommandTrait for Control {
             } else {
                 None
             };
+            *hover = self.hover;
             let extension = entity.extension_mut();
             extension.set_submerge(self.submerge);
             extension.set_active(self.active);
+            extension.set_point_defense(self.point_defense);
+            extension.reset_idle();
 
             drop(player);
 
@@ -225,6 +230,10 @@ impl CommandTrait for Control {
                 fire.apply(world, player_tuple)?;
             }
 
+            if let Some(salvo) = &self.salvo {
+                salvo.apply(world, player_tuple)?;
+            }
+
             if let Some(pay) = &self.pay {
                 pay.apply(world, player_tuple)?;
             }
@@ -246,120 +255,283 @@ impl CommandTrait for Fire {
         world: &mut World,
         player_tuple: &Arc<PlayerTuple<Server>>,
     ) -> Result<(), &'static str> {
-        let player = player_tuple.borrow_player();
+        if self.waypoints.len() > Self::MAX_WAYPOINTS {
+            return Err("too many waypoints");
+        }
+        if self.waypoints.iter().any(|waypoint| !waypoint.is_finite()) {
+            return Err("invalid waypoint");
+        }
+
+        fire_armament(
+            world,
+            player_tuple,
+            self.armament_index as usize,
+            Angle::ZERO,
+            self.profile,
+            &self.waypoints,
+        )
+    }
+}
+
+impl CommandTrait for Salvo {
+    fn apply(
+        &self,
+        world: &mut World,
+        player_tuple: &Arc<PlayerTuple<Server>>,
+    ) -> Result<(), &'static str> {
+        let count = self.armament_indices.len();
+        if count == 0 || count > Self::MAX_ARMAMENTS {
+            return Err("invalid salvo size");
+        }
+        if self.spread.abs() > Self::max_spread() {
+            return Err("salvo spread too wide");
+        }
+        if self.interval > Self::MAX_INTERVAL {
+            return Err("salvo interval too long");
+        }
+        for (i, index) in self.armament_indices.iter().enumerate() {
+            if self.armament_indices[..i].contains(index) {
+                return Err("duplicate armament in salvo");
+            }
+        }
 
-        return if let Status::Alive {
-            entity_index,
-            aim_target,
-            ..
-        } = player.data.status
         {
-            // Prevents limited armaments from being invalidated since all limited armaments are destroyed on upgrade.
-            if player.data.flags.upgraded {
-                return Err("cannot fire right after upgrading");
+            let pending = &player_tuple.borrow_player().data.salvo;
+            if pending
+                .iter()
+                .any(|shot| self.armament_indices.contains(&shot.armament_index))
+            {
+                return Err("armament already pending in salvo");
+            }
+            // Bounds the queue even if the weapons are invalid, since they are only checked when
+            // fired.
+            if pending.len() + count > Self::MAX_ARMAMENTS {
+                return Err("too many pending shots");
             }
+        }
 
-            let entity = &mut world.entities[entity_index];
+        // Weapons that aren't ready are skipped, so long as at least one is fired (or scheduled).
+        let mut result = Err("salvo empty");
+        for (i, &armament_index) in self.armament_indices.iter().enumerate() {
+            let offset = self.offset(i);
+            let delay = self.interval * i as f32;
+
+            if delay == Ticks::ZERO {
+                let fired = fire_armament(
+                    world,
+                    player_tuple,
+                    armament_index as usize,
+                    offset,
+                    FlightProfile::Direct,
+                    &[],
+                );
+                if result.is_err() {
+                    result = fired;
+                }
+            } else {
+                player_tuple
+                    .borrow_player_mut()
+                    .data
+                    .salvo
+                    .push(PendingShot {
+                        armament_index,
+                        offset,
+                        delay,
+                    });
+                result = Ok(());
+            }
+        }
+        result
+    }
+}
 
-            let data = entity.data();
+/// Fires any of the player's salvo weapons whose delay has elapsed.
+pub fn fire_pending_shots(
+    world: &mut World,
+    player_tuple: &Arc<PlayerTuple<Server>>,
+    delta: Ticks,
+) {
+    let due: Vec<PendingShot> = {
+        let mut player = player_tuple.borrow_player_mut();
+        if player.data.salvo.is_empty() {
+            return;
+        }
+        for shot in &mut player.data.salvo {
+            shot.delay = shot.delay.saturating_sub(delta);
+        }
+        let (due, pending) = std::mem::take(&mut player.data.salvo)
+            .into_iter()
+            .partition(|shot| shot.delay == Ticks::ZERO);
+        player.data.salvo = pending;
+        due
+    };
+
+    for shot in due {
+        // Weapons that aren't ready by the time they are due are skipped.
+        let _ = fire_armament(
+            world,
+            player_tuple,
+            shot.armament_index as usize,
+            shot.offset,
+            FlightProfile::Direct,
+            &[],
+        );
+    }
+}
 
-            let index = self.armament_index as usize;
-            if index >= data.armaments.len() {
-                return Err("armament index out of bounds");
-            }
+/// Fires/uses a single weapon, with its aim offset by `offset`. Missiles fly the given `profile`
+/// through `waypoints`.
+fn fire_armament(
+    world: &mut World,
+    player_tuple: &Arc<PlayerTuple<Server>>,
+    index: usize,
+    offset: Angle,
+    profile: FlightProfile,
+    waypoints: &[Vec2],
+) -> Result<(), &'static str> {
+    let player = player_tuple.borrow_player();
+
+    return if let Status::Alive {
+        entity_index,
+        aim_target,
+        ..
+    } = player.data.status
+    {
+        // Prevents limited armaments from being invalidated since all limited armaments are destroyed on upgrade.
+        if player.data.flags.upgraded {
+            return Err("cannot fire right after upgrading");
+        }
 
-            if entity.extension().reloads[index] != Ticks::ZERO {
-                return Err("armament not yet reloaded");
-            }
+        let entity = &mut world.entities[entity_index];
 
-            let armament = &data.armaments[index];
-            let armament_entity_data = armament.entity_type.data();
-
-            // Can't fire if boat is a submerged former submarine.
-            if entity.altitude.is_submerged()
-                && (data.sub_kind != EntitySubKind::Submarine
-                    || matches!(armament_entity_data.kind, EntityKind::Aircraft)
-                    || matches!(
-                        armament_entity_data.sub_kind,
-                        EntitySubKind::Shell | EntitySubKind::Sam
-                    ))
-            {
-                return Err("cannot fire while surfacing as a boat");
-            }
+        let data = entity.data();
 
-            if let Some(turret_index) = armament.turret {
-                let turret_angle = entity.extension().turrets[turret_index];
-                let turret = &data.turrets[turret_index];
+        if index >= data.armaments.len() {
+            return Err("armament index out of bounds");
+        }
 
-                // The aim may be outside the range but the turret must not be fired if the turret's
-                // current angle is outside the range.
-                if !turret.within_azimuth(turret_angle) {
-                    return Err("invalid turret azimuth");
-                }
-            }
+        if entity.extension().reloads[index] != Ticks::ZERO {
+            return Err("armament not yet reloaded");
+        }
 
-            let armament_transform =
-                entity.transform + data.armament_transform(&entity.extension().turrets, index);
+        let armament = &data.armaments[index];
+        let armament_entity_data = armament.entity_type.data();
 
-            if armament_entity_data.sub_kind == EntitySubKind::Depositor {
-                if let Some(mut target) = aim_target {
-                    // Can't deposit in arctic.
-                    target.y = target.y.min(ARCTIC - 2.0 * common::terrain::SCALE);
+        let routed = profile != FlightProfile::Direct || !waypoints.is_empty();
+        if routed && armament_entity_data.sub_kind != EntitySubKind::Missile {
+            return Err("only missiles can follow a route");
+        }
 
-                    // Clamp target is in valid range from depositor or error if too far.
-                    const DEPOSITOR_RANGE: f32 = 60.0;
-                    let depositor = armament_transform.position;
-                    let pos =
-                        clamp_to_range(depositor, target, DEPOSITOR_RANGE, DEPOSITOR_RANGE * 2.0)?;
+        // Can't fire if boat is a submerged former submarine.
+        if entity.altitude.is_submerged()
+            && (data.sub_kind != EntitySubKind::Submarine
+                || matches!(armament_entity_data.kind, EntityKind::Aircraft)
+                || matches!(
+                    armament_entity_data.sub_kind,
+                    EntitySubKind::Shell | EntitySubKind::Sam
+                ))
+        {
+            return Err("cannot fire while surfacing as a boat");
+        }
 
-                    world.terrain.modify(TerrainMutation::simple(pos, 60.0));
-                } else {
-                    return Err("cannot deposit without aim target");
-                }
-            } else {
-                // Fire weapon.
-                let player_arc = Arc::clone(player_tuple);
+        if let Some(turret_index) = armament.turret {
+            let turret_angle = entity.extension().turrets[turret_index];
+            let turret = &data.turrets[turret_index];
 
-                drop(player);
-                let mut armament_entity = Entity::new(armament.entity_type, Some(player_arc));
+            // The aim may be outside the range but the turret must not be fired if the turret's
+            // current angle is outside the range.
+            if !turret.within_azimuth(turret_angle) {
+                return Err("invalid turret azimuth");
+            }
+        }
 
-                armament_entity.transform = armament_transform;
-                armament_entity.altitude = entity.altitude;
+        let armament_transform =
+            entity.transform + data.armament_transform(&entity.extension().turrets, index);
 
-                let aim_angle = aim_target
-                    .map(|aim| Angle::from(aim - armament_entity.transform.position))
-                    .unwrap_or(entity.transform.direction);
+        if armament_entity_data.sub_kind == EntitySubKind::Depositor {
+            if let Some(mut target) = aim_target {
+                // Can't deposit in arctic.
+                target.y = target.y.min(ARCTIC - 2.0 * common::terrain::SCALE);
 
-                armament_entity.guidance.velocity_target = armament_entity_data.speed;
-                armament_entity.guidance.direction_target = aim_angle;
+                // Clamp target is in valid range from depositor or error if too far.
+                const DEPOSITOR_RANGE: f32 = 60.0;
+                let depositor = armament_transform.position;
+                let pos =
+                    clamp_to_range(depositor, target, DEPOSITOR_RANGE, DEPOSITOR_RANGE * 2.0)?;
 
-                if armament.vertical {
-                    // Vertically-launched armaments can be launched in any horizontal direction.
-                    armament_entity.transform.direction = armament_entity.guidance.direction_target;
+                world.terrain.modify(TerrainMutation::simple(pos, 60.0));
+            } else {
+                return Err("cannot deposit without aim target");
+            }
+        } else {
+            // Fire weapon.
+            let player_arc = Arc::clone(player_tuple);
+
+            drop(player);
+            let mut armament_entity = Entity::new(armament.entity_type, Some(player_arc));
+
+            armament_entity.transform = armament_transform;
+            armament_entity.altitude = entity.altitude;
+
+            let aim_angle = aim_target
+                .map(|aim| Angle::from(aim - armament_entity.transform.position))
+                .unwrap_or(entity.transform.direction);
+
+            let route = if routed {
+                let position = armament_entity.transform.position;
+                let target = aim_target
+                    .unwrap_or_else(|| position + aim_angle.to_vec() * armament_entity_data.range);
+                let route = Route::new(profile, waypoints, target);
+                if route.waypoints_length(position)
+                    > armament_entity_data.range * profile.range_factor()
+                {
+                    return Err("waypoints out of range");
                 }
 
-                // Some weapons experience random deviation on launch
-                let deviation = match armament_entity_data.sub_kind {
-                    EntitySubKind::Rocket | EntitySubKind::RocketTorpedo => 0.05,
-                    EntitySubKind::Shell => 0.01,
-                    _ => 0.03,
-                };
-                armament_entity.transform.direction += thread_rng().gen::<Angle>() * deviation;
+                // Some profiles burn fuel faster.
+                armament_entity.ticks = armament_entity_data.lifespan * profile.fuel_cost();
+                Some(route)
+            } else {
+                None
+            };
 
-                if !world.spawn_here_or_nearby(armament_entity, 0.0, None) {
-                    return Err("failed to fire from current location");
-                }
+            armament_entity.guidance.velocity_target = armament_entity_data.speed;
+            armament_entity.guidance.direction_target = route
+                .as_ref()
+                .and_then(Route::waypoint)
+                .map(|waypoint| Angle::from(waypoint - armament_entity.transform.position))
+                .unwrap_or(aim_angle)
+                + offset;
+
+            if armament.vertical {
+                // Vertically-launched armaments can be launched in any horizontal direction.
+                armament_entity.transform.direction = armament_entity.guidance.direction_target;
             }
 
-            let entity = &mut world.entities[entity_index];
-            entity.consume_armament(index);
-            entity.extension_mut().clear_spawn_protection();
+            // Some weapons experience random deviation on launch
+            let deviation = match armament_entity_data.sub_kind {
+                EntitySubKind::Rocket | EntitySubKind::RocketTorpedo => 0.05,
+                EntitySubKind::Shell => 0.01,
+                _ => 0.03,
+            };
+            armament_entity.transform.direction += thread_rng().gen::<Angle>() * deviation;
 
-            Ok(())
-        } else {
-            Err("cannot fire while not alive")
-        };
-    }
+            let id = world
+                .try_spawn_with_id(armament_entity)
+                .ok_or("failed to fire from current location")?;
+            if let Some(route) = route {
+                world.routes.insert(id, route);
+            }
+        }
+
+        let entity = &mut world.entities[entity_index];
+        entity.consume_armament(index);
+        entity.extension_mut().clear_spawn_protection();
+
+        Ok(())
+    } else {
+        Err("cannot fire while not alive")
+    };
 }
 
 impl CommandTrait for Pay {
@@ -447,6 +619,8 @@ impl CommandTrait for Upgrade {
             }
 
             player.data.flags.upgraded = true;
+            // Armament indices of pending shots refer to the old boat.
+            player.data.salvo.clear();
 
             let below_full_potential = self.entity_type.data().level < score_to_level(player.score);
 
@@ -461,6 +635,45 @@ impl CommandTrait for Upgrade {
     }
 }
 
+impl CommandTrait for Hire {
+    fn apply(
+        &self,
+        world: &mut World,
+        player_tuple: &Arc<PlayerTuple<Server>>,
+    ) -> Result<(), &'static str> {
+        let mut player = player_tuple.borrow_player_mut();
+
+        if let Status::Alive { entity_index, .. } = player.data.status {
+            let entity_type = world.entities[entity_index].entity_type;
+            let data = entity_type.data();
+
+            if player.data.owner.is_some()
+                || player.data.escorts.len() + player.data.hiring.len() >= data.max_escorts()
+            {
+                return Err("cannot hire more escorts");
+            }
+
+            // Like paying, hiring can't cost levels.
+            let cost = data.escort_cost();
+            if player.score < level_to_score(data.level) + cost {
+                return Err("insufficient funds");
+            }
+
+            let escort_type = entity_type
+                .escort_options()
+                .choose(&mut thread_rng())
+                .ok_or("no escorts available")?;
+
+            // Spawned by the server, which refunds the cost if that fails.
+            player.score -= cost;
+            player.data.hiring.push((escort_type, cost));
+            Ok(())
+        } else {
+            Err("cannot hire while not alive")
+        }
+    }
+}
+
 /// Returns an error if the float isn't finite. Otherwise, clamps it to the provided range.
 fn sanitize_float(float: f32, valid: Range<f32>) -> Result<f32, &'static str> {
     if float.is_finite() {
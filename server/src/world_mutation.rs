@@ -1,6 +1,7 @@
 // SPDX-FileCopyrightText: 2021 Softbear, Inc.
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
+use crate::budget::BudgetKind;
 use crate::entities::EntityIndex;
 use crate::entity::Entity;
 use crate::player::Status;
@@ -127,12 +128,17 @@ impl Mutation {
                         let e_score = e.borrow_player().score;
                         let mut other_player = other_player.borrow_player_mut();
                         other_player.score += kill_score(e_score, other_player.score);
+                        other_player.data.bounty.on_kill();
                         let alias = other_player.alias();
                         drop(other_player);
                         alias
                     };
 
-                    world.remove(index, DeathReason::Weapon(killer_alias, weapon_type));
+                    world.remove_with_killer(
+                        index,
+                        DeathReason::Weapon(killer_alias, weapon_type),
+                        Some(&other_player),
+                    );
                     return true;
                 }
             }
@@ -148,18 +154,20 @@ impl Mutation {
                     let killer_alias = {
                         let mut other_player = other_player.borrow_player_mut();
                         other_player.score += ram_score(entity.borrow_player().score, e_score);
+                        other_player.data.bounty.on_kill();
                         let alias = other_player.alias();
                         drop(other_player);
                         alias
                     };
 
-                    world.remove(
+                    world.remove_with_killer(
                         index,
                         if ram {
                             DeathReason::Ram(killer_alias)
                         } else {
                             DeathReason::Boat(killer_alias)
                         },
+                        Some(&other_player),
                     );
                     return true;
                 }
@@ -208,7 +216,16 @@ impl Mutation {
                 if is_last_of_type {
                     let entity = &mut entities[index];
                     entity.guidance.direction_target = direction_target;
-                    entity.apply_altitude_target(&world.terrain, Some(altitude_target), 5.0, delta);
+                    let data = entity.data();
+                    // Missiles keep to their route's altitude (e.g. sea-skimming) while homing.
+                    let altitude_target = if data.sub_kind == EntitySubKind::Missile {
+                        world.routes.get(&entity.id).and_then(|route| {
+                            route.altitude_target(entity.transform.position, data.speed.to_mps())
+                        })
+                    } else {
+                        Some(altitude_target)
+                    };
+                    entity.apply_altitude_target(&world.terrain, altitude_target, 5.0, delta);
                 }
             }
             Self::Attraction(delta_pos, velocity, delta_altitude) => {
@@ -274,7 +291,12 @@ impl Mutation {
     }
 
     /// Called by World::remove.
-    pub fn on_world_remove(world: &mut World, index: EntityIndex, reason: &DeathReason) {
+    pub fn on_world_remove(
+        world: &mut World,
+        index: EntityIndex,
+        reason: &DeathReason,
+        killer: Option<&Arc<PlayerTuple<Server>>>,
+    ) {
         let entity_type = world.entities[index].entity_type;
         let data: &EntityData = entity_type.data();
 
@@ -290,8 +312,21 @@ impl Mutation {
                     | DeathReason::Obstacle(_)
             );
 
-            Self::boat_died(world, index, score_to_coins);
+            Self::boat_died(world, index, score_to_coins, killer);
         } else {
+            if BudgetKind::of(data).is_some() {
+                let id = world.entities[index].id;
+                world.budgets.remove(id);
+            }
+
+            if data.sub_kind == EntitySubKind::Missile {
+                let id = world.entities[index].id;
+                world.routes.remove(&id);
+            } else if data.sub_kind == EntitySubKind::Heli {
+                let id = world.entities[index].id;
+                world.hovering.remove(&id);
+            }
+
             if matches!(reason, DeathReason::Terrain) || data.sub_kind == EntitySubKind::DepthCharge
             {
                 Self::maybe_damage_terrain(world, index);
@@ -324,10 +359,16 @@ impl Mutation {
 
     /// Called by on_world_remove when a boat dies.
     /// Applies the effect of a boat dying, such as a reduction in the corresponding player's
-    /// score and the spawning of loot.
-    fn boat_died(world: &mut World, index: EntityIndex, score_to_coins: bool) {
+    /// score and the spawning of loot. The killer, if any, collects the boat's bounty.
+    fn boat_died(
+        world: &mut World,
+        index: EntityIndex,
+        score_to_coins: bool,
+        killer: Option<&Arc<PlayerTuple<Server>>>,
+    ) {
         let entity = &mut world.entities[index];
         let mut player = entity.borrow_player_mut();
+        let bounty = player.data.bounty.collect();
         let mut rng = thread_rng();
         let score = player.score;
         player.score = if player.is_bot() {
@@ -338,6 +379,10 @@ impl Mutation {
         };
         drop(player);
 
+        if let Some(killer) = killer.filter(|k| !Arc::ptr_eq(k, entity.player.as_ref().unwrap())) {
+            killer.borrow_player_mut().score += bounty;
+        }
+
         let data = entity.data();
         debug_assert_eq!(data.kind, EntityKind::Boat);
 
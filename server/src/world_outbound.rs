@@ -5,6 +5,7 @@ use crate::complete_ref::CompleteRef;
 use crate::contact_ref::ContactRef;
 use crate::entity::Entity;
 use crate::player::Status;
+use crate::route::Route;
 use crate::server::Server;
 use crate::world::World;
 use common::entity::{EntityKind, EntitySubKind};
@@ -12,6 +13,7 @@ use common::ticks::Ticks;
 use common_util::range::{map_ranges, map_ranges_fast};
 use game_server::player::PlayerTuple;
 use glam::{vec2, Vec2};
+use std::sync::Arc;
 
 impl World {
     /// get_player_complete gets the complete update for a player, corresponding to everything they
@@ -121,6 +123,22 @@ impl World {
         let camera_pos = camera.position;
         let camera_view = camera.view;
 
+        // Lowered dipping sonars of the player's helicopters are additional sensor origins.
+        let dipping_sonars: Arc<[Vec2]> = if !self.hovering.is_empty()
+            && player_entity.map_or(false, |e| e.data().has_helicopters())
+        {
+            self.hovering
+                .values()
+                .filter(|hover| tuple == &*hover.player && hover.is_dipping())
+                .map(|hover| hover.position)
+                .collect()
+        } else {
+            Arc::new([])
+        };
+        let dipping_sonar_range_squared = Self::DIPPING_SONAR_RANGE.powi(2);
+        let dipping_sonar_range_inv = Self::DIPPING_SONAR_RANGE.powi(-2);
+        let sonar_origins = Arc::clone(&dipping_sonars);
+
         let contacts = player_entity
             .into_iter()
             .chain(
@@ -129,6 +147,20 @@ impl World {
                     .map(|(_, e)| e)
                     .filter(move |e| Some(*e) != player_entity),
             )
+            .chain((0..sonar_origins.len()).flat_map(move |i| {
+                let origins = Arc::clone(&sonar_origins);
+                self.entities
+                    .iter_radius(origins[i], Self::DIPPING_SONAR_RANGE)
+                    .map(|(_, e)| e)
+                    .filter(move |e| {
+                        // Skip entities already iterated.
+                        let position = e.transform.position;
+                        position.distance_squared(camera_pos) > max_range.powi(2)
+                            && !origins[..i].iter().any(|origin| {
+                                origin.distance_squared(position) <= dipping_sonar_range_squared
+                            })
+                    })
+            }))
             .filter_map(move |entity| {
                 // Limit contacts based on visibility.
 
@@ -136,6 +168,8 @@ impl World {
 
                 // Variables related to the relationship between the player and the contact.
                 let distance_squared = camera.position.distance_squared(entity.transform.position);
+                // Beyond the camera's sensors, only dipping sonars can detect the contact.
+                let dipping_only = distance_squared > max_range.powi(2);
                 let same_player =
                     entity.player.is_some() && tuple == &**entity.player.as_ref().unwrap();
                 let friendly = entity.is_friendly_to_player(Some(tuple));
@@ -152,8 +186,15 @@ impl World {
                     uncertainty = 1.0;
                     let entity_abs_vel = entity.transform.velocity.abs().to_mps();
 
-                    if radar_range_inv.is_finite() && !altitude.is_submerged() {
-                        let radar_ratio = default_ratio * radar_range_inv;
+                    if !dipping_only && radar_range_inv.is_finite() && !altitude.is_submerged() {
+                        let sea_skimming = data.sub_kind == EntitySubKind::Missile
+                            && altitude <= Route::SEA_SKIMMING_ALTITUDE;
+
+                        let mut radar_ratio = default_ratio * radar_range_inv;
+                        if sea_skimming {
+                            // Hides below the radar horizon (halving radar range).
+                            radar_ratio *= 4.0;
+                        }
 
                         if camera.active {
                             // Active radar can see moving targets easier.
@@ -177,7 +218,8 @@ impl World {
                             } else {
                                 BASE_FACTOR / (BASE_EMISSION + BOAT_EMISSION)
                             }
-                        } else if data.sub_kind == EntitySubKind::Missile {
+                        } else if data.sub_kind == EntitySubKind::Missile && !sea_skimming {
+                            // Sea-skimming missiles fly too low for passive radar to pick up.
                             const MISSILE_EMISSION: f32 = 30.0;
                             // emission += MISSILE_EMISSION;
                             BASE_FACTOR / (BASE_EMISSION + MISSILE_EMISSION)
@@ -189,7 +231,7 @@ impl World {
                         uncertainty = uncertainty.min(passive_radar_ratio);
                     }
 
-                    if sonar_range_inv.is_finite() && !altitude.is_airborne() {
+                    if !dipping_only && sonar_range_inv.is_finite() && !altitude.is_airborne() {
                         let mut sonar_ratio = default_ratio * sonar_range_inv;
                         if camera.active {
                             // Active sonar.
@@ -198,25 +240,12 @@ impl World {
 
                         // Beyond this point, sonar_ratio means passive sonar ratio.
 
-                        // Always-on passive sonar:
-                        let mut noise = 2f32
-                            .max(entity_abs_vel - data.cavitation_speed(entity.altitude).to_mps());
-
-                        if data.kind == EntityKind::Boat
-                            || data.kind == EntityKind::Weapon
-                            || data.kind == EntityKind::Decoy
-                        {
-                            noise *= 2.0;
-
-                            if data.kind != EntityKind::Boat {
-                                noise += 100.0;
-                            } else if entity.extension().is_active()
-                                && data.sensors.sonar.range > 0.0
-                            {
-                                // Active sonar gives away entity's position.
-                                noise += 20.0;
-                            }
-                        }
+                        // Always-on passive sonar (see EntityData::passive_sonar_range):
+                        let noise = data.sonar_noise(
+                            entity.transform.velocity,
+                            entity.altitude,
+                            data.kind == EntityKind::Boat && entity.extension().is_active(),
+                        );
 
                         sonar_ratio /= noise;
 
@@ -226,7 +255,18 @@ impl World {
                         uncertainty = uncertainty.min(sonar_ratio);
                     }
 
-                    if visual_range_inv.is_finite() {
+                    if !altitude.is_airborne() {
+                        // Dipping sonars are always active.
+                        for origin in dipping_sonars.iter() {
+                            let dipping_sonar_ratio = origin
+                                .distance_squared(entity.transform.position)
+                                * inv_size
+                                * dipping_sonar_range_inv;
+                            uncertainty = uncertainty.min(dipping_sonar_ratio);
+                        }
+                    }
+
+                    if !dipping_only && visual_range_inv.is_finite() {
                         let mut visual_ratio = default_ratio * visual_range_inv;
                         if altitude.is_submerged() {
                             let extra = if data.kind == EntityKind::Boat
@@ -275,7 +315,7 @@ impl World {
                     || uncertainty < 0.5
                     || distance_squared < inner_circle_squared;
 
-                Some(ContactRef::new(entity, visible, known, has_type))
+                Some(ContactRef::new(entity, visible, known, has_type, friendly))
             });
 
         // How much more terrain can be sent.
@@ -292,3 +332,100 @@ impl World {
         CompleteRef::new(contacts, player, self, camera_pos, camera_dims)
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use crate::entity::Entity;
+    use crate::world::World;
+    use crate::world_dipping_sonar::Hover;
+    use crate::world_test::player;
+    use common::complete::CompleteTrait;
+    use common::contact::ContactTrait;
+    use common::entity::EntityType;
+    use common::terrain::Terrain;
+    use common::ticks::Ticks;
+    use glam::Vec2;
+    use std::sync::Arc;
+
+    /// A surface boat beyond the camera's sensors, but within range of a dipping sonar, must be
+    /// close enough to the sonar to be a contact, even if its active radar gives it away.
+    #[test]
+    fn dipping_sonar_only_contact() {
+        let mut world = World::new(10000.0);
+        world.terrain = Terrain::new();
+
+        let owner = player(1);
+        let other_player = player(2);
+
+        let boat = Entity::new(EntityType::Freedom, Some(Arc::clone(&owner)));
+        let max_range = boat.data().sensors.max_range();
+        assert!(world.try_spawn(boat));
+
+        let mut heli = Entity::new(EntityType::Seahawk, Some(Arc::clone(&owner)));
+        heli.transform.position = Vec2::new(max_range - 100.0, 0.0);
+        let heli_id = world.try_spawn_with_id(heli).unwrap();
+        world.hovering.insert(
+            heli_id,
+            Hover {
+                duration: Ticks::from_whole_secs(60),
+                position: Vec2::new(max_range - 100.0, 0.0),
+                player: Arc::clone(&owner),
+            },
+        );
+
+        let mut other = Entity::new(EntityType::Osa, Some(Arc::clone(&other_player)));
+        other.transform.position = Vec2::new(max_range + 350.0, 0.0);
+        other.extension_mut().set_active(true);
+        let other_id = world.try_spawn_with_id(other).unwrap();
+
+        let contacts = world.get_player_complete(&owner).collect_contacts();
+        assert!(contacts.iter().any(|c| c.id() == heli_id));
+        assert!(
+            !contacts.iter().any(|c| c.id() == other_id),
+            "detected beyond dipping sonar range"
+        );
+    }
+
+    /// A helicopter dipping its sonar beyond the range of its owner's sensors still contributes
+    /// contacts.
+    #[test]
+    fn distant_dipping_sonar() {
+        let mut world = World::new(10000.0);
+        world.terrain = Terrain::new();
+
+        let owner = player(1);
+        let other_player = player(2);
+
+        let boat = Entity::new(EntityType::Freedom, Some(Arc::clone(&owner)));
+        let max_range = boat.data().sensors.max_range();
+        assert!(world.try_spawn(boat));
+
+        let heli_position = Vec2::new(max_range + 300.0, 0.0);
+        let mut heli = Entity::new(EntityType::Seahawk, Some(Arc::clone(&owner)));
+        heli.transform.position = heli_position;
+        let heli_id = world.try_spawn_with_id(heli).unwrap();
+
+        let mut other = Entity::new(EntityType::Osa, Some(Arc::clone(&other_player)));
+        other.transform.position = heli_position + Vec2::new(100.0, 0.0);
+        let other_id = world.try_spawn_with_id(other).unwrap();
+
+        let contacts = world.get_player_complete(&owner).collect_contacts();
+        assert!(
+            !contacts.iter().any(|c| c.id() == other_id),
+            "detected before lowering dipping sonar"
+        );
+
+        world.hovering.insert(
+            heli_id,
+            Hover {
+                duration: Ticks::from_whole_secs(60),
+                position: heli_position,
+                player: Arc::clone(&owner),
+            },
+        );
+
+        let contacts = world.get_player_complete(&owner).collect_contacts();
+        assert!(contacts.iter().any(|c| c.id() == heli_id));
+        assert!(contacts.iter().any(|c| c.id() == other_id));
+    }
+}
@@ -4,11 +4,12 @@
 use crate::entities::EntityIndex;
 use crate::player::{Flags, Status};
 use crate::world::World;
+use crate::world_dipping_sonar::Hover;
 use common::altitude::Altitude;
 use common::angle::Angle;
 use common::death_reason::DeathReason;
 use common::entity::*;
-use common::terrain::TerrainMutation;
+use common::terrain::{self, Terrain, TerrainMutation};
 use common::ticks::Ticks;
 use common::transform::Transform;
 use common::velocity::Velocity;
@@ -37,9 +38,13 @@ impl World {
         let border_radius = self.radius; // Avoids double borrow.
         let border_radius_squared = self.radius.powi(2);
         let terrain = &self.terrain;
+        let routes = &self.routes;
+        let budgets = &self.budgets;
 
         // Collected updates (order doesn't matter).
         let terrain_mutations = Mutex::new(Vec::new());
+        let waypoints_reached = Mutex::new(Vec::new());
+        let hovering = Mutex::new(Vec::new());
         let barrel_spawns = Mutex::new(Vec::new());
         let reset_flags = Mutex::new(Vec::new());
 
@@ -50,9 +55,23 @@ impl World {
             .filter_map(|(index, entity)| {
                 let index = index as EntityIndex;
                 let data = entity.data();
+                let route = if data.sub_kind == EntitySubKind::Missile {
+                    routes.get(&entity.id)
+                } else {
+                    None
+                };
+
+                // Expire to make room for newer entities as if lifespan ended.
+                if budgets.is_expiring(entity.id) {
+                    return Some((index, Fate::Remove(DeathReason::Unknown)));
+                }
 
                 if data.lifespan != Ticks::ZERO {
-                    entity.ticks = entity.ticks.saturating_add(delta);
+                    // Some flight profiles burn fuel slower (stochastically, as ticks are whole).
+                    let fuel_rate = route.map_or(1.0, |route| route.profile.fuel_rate());
+                    if fuel_rate >= 1.0 || rand::thread_rng().gen_bool(fuel_rate as f64) {
+                        entity.ticks = entity.ticks.saturating_add(delta);
+                    }
 
                     // Downgrade or die when expired.
                     if entity.ticks > data.lifespan {
@@ -87,15 +106,19 @@ impl World {
 
                 match data.kind {
                     EntityKind::Aircraft => {
-                        let position_diff = if let Status::Alive {
-                            aim_target: Some(aim_target),
-                            ..
-                        } = entity.borrow_player().data.status
-                        {
-                            aim_target - entity.transform.position
-                        } else {
+                        let position_diff = match entity.borrow_player().data.status {
+                            Status::Alive { hover: true, .. }
+                                if data.sub_kind == EntitySubKind::Heli =>
+                            {
+                                // Hover in place, as ordered.
+                                Vec2::ZERO
+                            }
+                            Status::Alive {
+                                aim_target: Some(aim_target),
+                                ..
+                            } => aim_target - entity.transform.position,
                             // Hover when no target or player is dead.
-                            Vec2::ZERO
+                            _ => Vec2::ZERO,
                         };
 
                         entity.guidance.direction_target = Angle::from(position_diff)
@@ -120,6 +143,16 @@ impl World {
                                     // Turn in place.
                                     max_speed = 0.0;
                                 }
+
+                                // Hovering over the target lowers the dipping sonar.
+                                let hover = distance_squared < Self::HOVER_RADIUS.powi(2)
+                                    && entity.transform.velocity.abs().to_mps() < Self::HOVER_SPEED;
+                                hovering.lock().unwrap().push((
+                                    entity.id,
+                                    entity.player.as_ref().filter(|_| hover).map(|player| {
+                                        (entity.transform.position, Arc::clone(player))
+                                    }),
+                                ));
                             }
                             EntitySubKind::Plane => {
                                 if distance_squared < 50.0f32.powi(2)
@@ -134,8 +167,23 @@ impl World {
                         entity.apply_altitude_target(terrain, None, 4.0, delta);
                     }
                     EntityKind::Collectible | EntityKind::Weapon | EntityKind::Decoy => {
+                        let mut altitude_target = None;
+                        if let Some(route) = route {
+                            altitude_target =
+                                route.altitude_target(entity.transform.position, max_speed);
+
+                            // Steer towards waypoint (homing is disabled until it is reached).
+                            if let Some(waypoint) = route.waypoint() {
+                                entity.guidance.direction_target =
+                                    Angle::from(waypoint - entity.transform.position);
+                                if route.reached(entity.transform.position) {
+                                    waypoints_reached.lock().unwrap().push(entity.id);
+                                }
+                            }
+                        }
+
                         let altitude_change =
-                            entity.apply_altitude_target(terrain, None, 3.0, delta);
+                            entity.apply_altitude_target(terrain, altitude_target, 3.0, delta);
                         if entity.altitude.is_submerged() {
                             match data.sub_kind {
                                 // Wait until risen to surface.
@@ -273,6 +321,19 @@ impl World {
                             return Some((index, Fate::Remove(DeathReason::Terrain)));
                         }
                     }
+                } else if data.kind == EntityKind::Boat
+                    && data.sub_kind == EntitySubKind::Hovercraft
+                {
+                    // Hovercraft ride over the shallows and low land, slowing down as it rises.
+                    let terrain_altitude = terrain
+                        .sample(entity.transform.position)
+                        .unwrap_or(Altitude::MIN);
+                    let speed_factor = data.terrain_speed_factor(terrain_altitude);
+
+                    entity.transform.velocity = entity
+                        .transform
+                        .velocity
+                        .clamp_magnitude(Velocity::from_mps(max_speed * speed_factor));
                 } else if data.kind == EntityKind::Boat && !arctic {
                     let below_keel = entity.altitude
                         - terrain
@@ -298,6 +359,22 @@ impl World {
                     }
                 }
 
+                if data.kind == EntityKind::Boat
+                    && entity.transform.position.length_squared() > border_radius_squared
+                    && (entity.extension().is_afk() || entity.extension().has_spawn_protection())
+                {
+                    // Spare players who aren't (yet) responsible for their position.
+                    if let Some(position) = Self::relocation(
+                        entity.transform.position,
+                        border_radius,
+                        data.length,
+                        terrain,
+                    ) {
+                        entity.transform.position = position;
+                        entity.transform.velocity = Velocity::ZERO;
+                    }
+                }
+
                 let outside_border =
                     entity.transform.position.length_squared() > border_radius_squared;
                 let outside_area =
@@ -373,6 +450,26 @@ impl World {
             }
         }
 
+        for (id, hover) in hovering.into_inner().unwrap() {
+            if let Some((position, player)) = hover {
+                let hover = self.hovering.entry(id).or_insert(Hover {
+                    duration: Ticks::ZERO,
+                    position,
+                    player,
+                });
+                hover.duration = hover.duration.saturating_add(delta);
+                hover.position = position;
+            } else {
+                self.hovering.remove(&id);
+            }
+        }
+
+        for id in waypoints_reached.into_inner().unwrap() {
+            if let Some(route) = self.routes.get_mut(&id) {
+                route.advance();
+            }
+        }
+
         // Spawn barrels around oil platforms.
         let mut rng = rand::thread_rng();
         for mut position in barrel_spawns.into_inner().unwrap() {
@@ -424,6 +521,27 @@ impl World {
     }
 }
 
+impl World {
+    /// Returns a position in water, inside the border, near `position`, or `None` if there
+    /// isn't one nearby.
+    fn relocation(
+        position: Vec2,
+        border_radius: f32,
+        length: f32,
+        terrain: &Terrain,
+    ) -> Option<Vec2> {
+        let normal = position.normalize_or_zero();
+        (1..=8).find_map(|i| {
+            let candidate = normal * (border_radius - length * i as f32);
+            (candidate.dot(normal) > 0.0
+                && terrain
+                    .sample(candidate)
+                    .map_or(false, |a| a < terrain::SAND_LEVEL))
+            .then_some(candidate)
+        })
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use crate::entity::Entity;
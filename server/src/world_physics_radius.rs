@@ -20,6 +20,8 @@ use std::sync::Arc;
 use std::sync::Mutex;
 
 pub const MINE_SPEED: f32 = 8.0;
+/// Probability that a SAM destroys the missile or aircraft it hits.
+const SAM_PROBABILITY_OF_KILL: f64 = 0.7;
 
 impl World {
     /// minimum_scan_radius returns the radius must be scanned to properly resolve all entity vs.
@@ -198,7 +200,8 @@ impl World {
                                                 target_data.kind == EntityKind::Boat || target_data.kind == EntityKind::Decoy
                                             },
                                             EntitySubKind::Missile => {
-                                                target_data.kind == EntityKind::Boat && weapon.altitude_overlapping(target)
+                                                // Missiles en route to a waypoint don't home.
+                                                target_data.kind == EntityKind::Boat && weapon.altitude_overlapping(target) && !self.routes.get(&weapon.id).map_or(false, |route| route.waypoint().is_some())
                                             }
                                             _ => {
                                                 target_data.kind == EntityKind::Boat
@@ -327,7 +330,11 @@ impl World {
 
                                     // In range of aa.
                                     if d2 <= r2 {
-                                        let chance = (1.0 - d2/r2) * target_data.anti_aircraft * delta.to_secs();
+                                        let mut chance = (1.0 - d2/r2) * target_data.anti_aircraft * delta.to_secs();
+                                        if self.hovering.contains_key(&weapon.id) {
+                                            // Hovering helicopters are sitting ducks.
+                                            chance *= Self::HOVER_VULNERABILITY;
+                                        }
                                         if thread_rng().gen_bool((chance as f64).clamp(0.0, 1.0)) {
                                             debug_remove!(weapon, "shot down");
                                         }
@@ -531,6 +538,13 @@ impl World {
                         // No-op; don't allow coins (possibly placed by players) to interfere
                         // with enemy weapons.
                         // Also all non-torpedo weapons won't hit crates.
+                    } else if weapons.len() == 2 && !friendly && weapons.iter().any(|w| w.data().sub_kind == EntitySubKind::Sam) {
+                        // SAMs are expended, but don't always destroy what they hit.
+                        for weapon in weapons {
+                            if weapon.data().sub_kind == EntitySubKind::Sam || thread_rng().gen_bool(SAM_PROBABILITY_OF_KILL) {
+                                debug_remove!(weapon, "intercepted");
+                            }
+                        }
                     } else if !friendly {
                         // Aside from some edge cases, just remove both entities.
                         for e in [entity, other_entity] {
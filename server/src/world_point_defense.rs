@@ -0,0 +1,329 @@
+// SPDX-FileCopyrightText: 2021 Softbear, Inc.
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+use crate::entities::EntityIndex;
+use crate::entity::Entity;
+use crate::world::World;
+use common::angle::Angle;
+use common::death_reason::DeathReason;
+use common::entity::*;
+use common::protocol::PointDefense;
+use common::ticks::Ticks;
+use glam::Vec2;
+use maybe_parallel_iterator::IntoMaybeParallelIterator;
+use rand::{thread_rng, Rng};
+use std::sync::Arc;
+
+impl World {
+    /// Range at which point defense engages threats.
+    const POINT_DEFENSE_RANGE: f32 = 800.0;
+    /// Minimum time between point defense launches, per boat.
+    const POINT_DEFENSE_INTERVAL: Ticks = Ticks::from_whole_millis(500);
+    /// Point defense launchers must be aimed within this angle of the threat.
+    const POINT_DEFENSE_ARC: f32 = 20.0;
+    /// Range at which CIWS guns engage threats, which is much shorter than that of SAMs.
+    const CIWS_RANGE: f32 = 400.0;
+    /// Probability that a CIWS burst destroys the missile or aircraft it is aimed at.
+    const CIWS_PROBABILITY_OF_KILL: f64 = 0.3;
+
+    /// Point defense turrets engage the most threatening incoming missile or aircraft.
+    pub fn point_defense(&mut self) {
+        let engagements: Vec<(EntityIndex, Option<(EntityId, Vec2)>, Option<usize>)> = self
+            .entities
+            .par_iter()
+            .into_maybe_parallel_iter()
+            .filter_map(|(index, boat)| {
+                let data = boat.data();
+                if data.kind != EntityKind::Boat || !data.has_point_defense() {
+                    return None;
+                }
+
+                let target = self.point_defense_threat(boat);
+                let armament = target
+                    .filter(|_| boat.extension().is_point_defense_ready())
+                    .and_then(|(_, target)| Self::point_defense_armament(boat, target));
+                Some((index, target, armament))
+            })
+            .collect();
+
+        // Removed afterwards, since removing entities invalidates indices.
+        let mut shot_down = Vec::new();
+
+        for (index, target, armament) in engagements {
+            self.entities[index].extension_mut().point_defense_target =
+                target.map(|(_, position)| position);
+
+            if let (Some((target_id, target)), Some(armament_index)) = (target, armament) {
+                let boat = &self.entities[index];
+                let armament = &boat.data().armaments[armament_index];
+                if armament.entity_type.data().sub_kind == EntitySubKind::Sam {
+                    self.launch_interceptor(index, armament_index, target);
+                } else if self.fire_ciws(index, armament_index) {
+                    shot_down.push((target_id, target));
+                }
+            }
+        }
+
+        for (id, position) in shot_down {
+            // May have already been shot down by another boat.
+            if let Some(index) = self
+                .entities
+                .iter_radius(position, 1.0)
+                .find(|(_, entity)| entity.id == id)
+                .map(|(index, _)| index)
+            {
+                self.remove(index, DeathReason::Unknown);
+            }
+        }
+    }
+
+    /// Returns the id and position of the most threatening missile or aircraft, if any.
+    fn point_defense_threat(&self, boat: &Entity) -> Option<(EntityId, Vec2)> {
+        let mode = boat.extension().point_defense();
+        if mode == PointDefense::Off || boat.altitude.is_submerged() {
+            return None;
+        }
+
+        // Positions and radii of boats being defended.
+        let mut defended = vec![(boat.transform.position, boat.data().radius)];
+        let mut threats = Vec::new();
+
+        for (_, other) in self
+            .entities
+            .iter_radius(boat.transform.position, Self::POINT_DEFENSE_RANGE)
+        {
+            let other_data = other.data();
+            match other_data.kind {
+                EntityKind::Boat => {
+                    if mode == PointDefense::AreaDefense && other != boat && boat.is_friendly(other)
+                    {
+                        defended.push((other.transform.position, other_data.radius));
+                    }
+                }
+                EntityKind::Aircraft => {
+                    if !boat.is_friendly(other) {
+                        threats.push(other);
+                    }
+                }
+                EntityKind::Weapon => {
+                    if matches!(
+                        other_data.sub_kind,
+                        EntitySubKind::Missile
+                            | EntitySubKind::Rocket
+                            | EntitySubKind::RocketTorpedo
+                    ) && !boat.is_friendly(other)
+                    {
+                        threats.push(other);
+                    }
+                }
+                _ => {}
+            }
+        }
+
+        threats
+            .into_iter()
+            .filter_map(|threat| {
+                defended
+                    .iter()
+                    .filter_map(|&(position, radius)| {
+                        Self::time_to_impact(threat, position, radius)
+                    })
+                    .min_by(|a, b| a.total_cmp(b))
+                    .map(|seconds| (threat, seconds))
+            })
+            .min_by(|a, b| a.1.total_cmp(&b.1))
+            .map(|(threat, _)| (threat.id, threat.transform.position))
+    }
+
+    /// Returns seconds until `threat` reaches a boat at `position`, if it is headed for it.
+    fn time_to_impact(threat: &Entity, position: Vec2, radius: f32) -> Option<f32> {
+        let delta = position - threat.transform.position;
+        let speed = threat.transform.velocity.to_mps().max(1.0);
+
+        if threat.data().kind == EntityKind::Aircraft {
+            // Aircraft maneuver, so are a threat regardless of their current heading.
+            return Some(delta.length() / speed);
+        }
+
+        let velocity = threat.transform.direction.to_vec() * speed;
+        let seconds = delta.dot(velocity) / speed.powi(2);
+        if seconds < 0.0 {
+            // Moving away.
+            return None;
+        }
+
+        let miss_distance = (threat.transform.position + velocity * seconds).distance(position);
+        // Guided weapons may still turn towards the boat.
+        let tolerance = if threat.data().sensors.any() {
+            3.0
+        } else {
+            1.5
+        };
+        (miss_distance < radius * tolerance).then_some(seconds)
+    }
+
+    /// Returns the index of a ready point defense armament that can engage `target`.
+    fn point_defense_armament(boat: &Entity, target: Vec2) -> Option<usize> {
+        let data = boat.data();
+        let extension = boat.extension();
+
+        data.armaments.iter().enumerate().position(|(i, armament)| {
+            let turret_index = if let Some(turret_index) = armament.turret {
+                turret_index
+            } else {
+                return false;
+            };
+            let turret = &data.turrets[turret_index];
+            let turret_angle = extension.turrets[turret_index];
+
+            if !turret.is_point_defense()
+                || extension.reloads[i] != Ticks::ZERO
+                || !turret.within_azimuth(turret_angle)
+            {
+                return false;
+            }
+
+            let transform = boat.transform + data.armament_transform(&extension.turrets, i);
+
+            match armament.entity_type.data().sub_kind {
+                EntitySubKind::Sam => {
+                    if armament.vertical {
+                        return true;
+                    }
+                }
+                EntitySubKind::Shell => {
+                    if target.distance_squared(transform.position) > Self::CIWS_RANGE.powi(2) {
+                        return false;
+                    }
+                }
+                _ => return false,
+            }
+
+            let bearing = Angle::from(target - transform.position);
+            (bearing - transform.direction).abs() < Angle::from_degrees(Self::POINT_DEFENSE_ARC)
+        })
+    }
+
+    /// Launches a point defense armament at `target`.
+    fn launch_interceptor(&mut self, boat_index: EntityIndex, armament_index: usize, target: Vec2) {
+        let boat = &self.entities[boat_index];
+        let data = boat.data();
+        let armament = &data.armaments[armament_index];
+
+        let mut interceptor = Entity::new(
+            armament.entity_type,
+            Some(Arc::clone(boat.player.as_ref().unwrap())),
+        );
+        interceptor.transform =
+            boat.transform + data.armament_transform(&boat.extension().turrets, armament_index);
+        interceptor.altitude = boat.altitude;
+        interceptor.guidance.velocity_target = armament.entity_type.data().speed;
+        interceptor.guidance.direction_target =
+            Angle::from(target - interceptor.transform.position);
+        if armament.vertical {
+            interceptor.transform.direction = interceptor.guidance.direction_target;
+        }
+
+        if self.spawn_here_or_nearby(interceptor, 0.0, None) {
+            let boat = &mut self.entities[boat_index];
+            boat.consume_armament(armament_index);
+            boat.extension_mut()
+                .point_defense_launched(Self::POINT_DEFENSE_INTERVAL);
+        }
+    }
+
+    /// Fires a CIWS burst, returning true if it destroys its target.
+    fn fire_ciws(&mut self, boat_index: EntityIndex, armament_index: usize) -> bool {
+        let boat = &mut self.entities[boat_index];
+        boat.consume_armament(armament_index);
+        boat.extension_mut()
+            .point_defense_launched(Self::POINT_DEFENSE_INTERVAL);
+        thread_rng().gen_bool(Self::CIWS_PROBABILITY_OF_KILL)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::entity::Entity;
+    use crate::world::World;
+    use crate::world_test::player;
+    use crate::Server;
+    use common::angle::Angle;
+    use common::entity::{EntityKind, EntityType};
+    use common::terrain::Terrain;
+    use common::ticks::Ticks;
+    use game_server::player::PlayerTuple;
+    use glam::Vec2;
+    use std::sync::Arc;
+
+    /// Spawns an Osa, whose forward CIWS faces east, and a missile owned by `shooter` inbound
+    /// from the east, within CIWS range.
+    fn engagement(
+        defender: &Arc<PlayerTuple<Server>>,
+        shooter: &Arc<PlayerTuple<Server>>,
+    ) -> World {
+        let mut world = World::new(10000.0);
+        world.terrain = Terrain::new();
+
+        let boat = Entity::new(EntityType::Osa, Some(Arc::clone(defender)));
+        assert!(world.spawn_here_or_nearby(boat, 0.0, None));
+
+        let mut missile = Entity::new(EntityType::Exocet, Some(Arc::clone(shooter)));
+        missile.transform.position = Vec2::new(300.0, 0.0);
+        missile.transform.direction = Angle::from_degrees(180.0);
+        missile.transform.velocity = missile.data().speed;
+        assert!(world.spawn_here_or_nearby(missile, 0.0, None));
+
+        world
+    }
+
+    fn missiles(world: &World) -> usize {
+        world
+            .entities
+            .iter_radius(Vec2::ZERO, 1000.0)
+            .filter(|(_, e)| e.data().kind == EntityKind::Weapon)
+            .count()
+    }
+
+    fn rounds_fired(world: &World, defender: &Arc<PlayerTuple<Server>>) -> usize {
+        let index = defender
+            .borrow_player()
+            .data
+            .status
+            .get_entity_index()
+            .unwrap();
+        world.entities[index]
+            .extension()
+            .reloads
+            .iter()
+            .filter(|&&reload| reload != Ticks::ZERO)
+            .count()
+    }
+
+    #[test]
+    fn ciws_shoots_down_missile() {
+        let mut shot_down = 0;
+        for _ in 0..100 {
+            // Fresh players, since a player may only be alive in one world.
+            let defender = player(1);
+            let shooter = player(2);
+            let mut world = engagement(&defender, &shooter);
+            world.point_defense();
+            assert_eq!(rounds_fired(&world, &defender), 1);
+            shot_down += 1 - missiles(&world);
+        }
+
+        assert!(shot_down > 0, "missile was never shot down");
+        assert!(shot_down < 100, "missile was always shot down");
+    }
+
+    #[test]
+    fn ciws_ignores_friendly_missile() {
+        let defender = player(1);
+
+        let mut world = engagement(&defender, &defender);
+        world.point_defense();
+        assert_eq!(rounds_fired(&world, &defender), 0);
+        assert_eq!(missiles(&world), 1);
+    }
+}
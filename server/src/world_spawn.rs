@@ -12,6 +12,7 @@ use common::transform::Transform;
 use common::velocity::Velocity;
 use common::world::distance_to_soft_area_border;
 use common_util::range::gen_radius;
+use game_server::load_shedding::LoadShedding;
 use glam::Vec2;
 use log::{info, warn};
 use rand::{thread_rng, Rng};
@@ -24,6 +25,8 @@ impl World {
     const CRATE_DENSITY: f32 = 1.0 / 30000.0;
     /// Target density of obstacles (per square meter).
     const OBSTACLE_DENSITY: f32 = 1.0 / 1000000.0;
+    /// Fraction of static densities while shedding load.
+    const SPARSE_DENSITY: f32 = 0.5;
 
     /// spawn_here_or_nearby spawns an entity, adjusting it's position and/or rotation until
     /// it can spawn without colliding with world objects.
@@ -138,12 +141,13 @@ impl World {
 
     /// try_spawn attempts to spawn an entity at a position and returns if the entity was spawned.
     pub fn try_spawn(&mut self, entity: Entity) -> bool {
-        if self.can_spawn(&entity, 1.0, self.radius) {
-            self.add(entity);
-            true
-        } else {
-            false
-        }
+        self.try_spawn_with_id(entity).is_some()
+    }
+
+    /// Like `try_spawn`, but returns the id of the spawned entity.
+    pub fn try_spawn_with_id(&mut self, entity: Entity) -> Option<EntityId> {
+        self.can_spawn(&entity, 1.0, self.radius)
+            .then(|| self.add(entity))
     }
 
     /// Threshold ranges from [1,infinity), and makes the spawning more picky.
@@ -256,6 +260,11 @@ impl World {
 
     /// Spawn basic entities (crates, oil platforms) to maintain their densities.
     pub fn spawn_statics(&mut self, ticks: Ticks) {
+        let density = if self.load_shedding >= LoadShedding::SparserStatics {
+            Self::SPARSE_DENSITY
+        } else {
+            1.0
+        };
         let crate_count = self.arena.count(EntityType::Crate);
         let platform_count =
             self.arena.count(EntityType::OilPlatform) + self.arena.count(EntityType::Hq);
@@ -263,7 +272,7 @@ impl World {
         self.spawn_static_amount(
             |_| Some(EntityType::Crate),
             crate_count,
-            self.target_count(Self::CRATE_DENSITY),
+            self.target_count(Self::CRATE_DENSITY * density),
             ticks.0 as usize * 150,
         );
 
@@ -279,7 +288,7 @@ impl World {
                 })
             },
             platform_count,
-            self.target_count(Self::OBSTACLE_DENSITY),
+            self.target_count(Self::OBSTACLE_DENSITY * density),
             ticks.0 as usize * 2,
         );
     }
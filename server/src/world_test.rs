@@ -1,15 +1,27 @@
 use crate::entities::EntityIndex;
 use crate::entity::Entity;
+use crate::server::Server;
 use crate::world::World;
 use common::altitude::Altitude;
 use common::entity::EntityKind;
 use common::world::ARCTIC;
+use core_protocol::id::PlayerId;
+use game_server::player::{PlayerData, PlayerTuple};
 use glam::Vec2;
 use image::{Rgba, RgbaImage};
 use imageproc::drawing::{draw_polygon_mut, Blend};
 use imageproc::point::Point;
 use maybe_parallel_iterator::IntoMaybeParallelIterator;
-use std::sync::Mutex;
+use std::num::NonZeroU32;
+use std::sync::{Arc, Mutex};
+
+/// Creates a player, not in any arena, for testing purposes.
+pub fn player(id: u32) -> Arc<PlayerTuple<Server>> {
+    Arc::new(PlayerTuple::new(PlayerData::new(
+        PlayerId(NonZeroU32::new(id).unwrap()),
+        None,
+    )))
+}
 
 impl World {
     /// Creates a false-color CPU rendering of the world for testing purposes.
@@ -124,6 +136,7 @@ mod tests {
         println!("rad: {}", world_radius);
 
         let mut world = World::new(world_radius);
+        world.generate_terrain();
         let mut rng = thread_rng();
 
         let players: Vec<Arc<PlayerTuple<Server>>> = (0..player_count)